@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+const purgeBatchSize = 100
+
+type DeleteAccountReq struct {
+	Password string `json:"password"`
+	// Token of a login link, confirms the deletion for accounts without a
+	// password, like the ones created through an external provider
+	MagicToken string `json:"magic_token,omitempty"`
+}
+
+type DeleteAccountResponse struct {
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+// deleteAccountHandler schedules the caller's account for deletion. The
+// account is hidden right away and purged once the grace period is over,
+// logging in again before that restores it.
+func deleteAccountHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		var body DeleteAccountReq
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil || (body.Password == "" && body.MagicToken == "") {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, password or magic_token field expected")
+			return
+		}
+
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting user")
+			return
+		}
+
+		if !cfg.confirmAccountDeletion(res, req, user, body) {
+			return
+		}
+
+		user, err = cfg.dbQueries.SoftDeleteUser(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error deleting user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error deleting user")
+			return
+		}
+
+		err = cfg.dbQueries.RevokeUserRefreshTokens(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error revoking refresh tokens: %v\n", err)
+		}
+		cfg.disconnectUser(req.Context(), userID, "account deleted")
+		cfg.clearSessionCookies(res)
+
+		respondWithJSON(res, http.StatusAccepted, DeleteAccountResponse{PurgeAt: user.DeletedAt.Time.Add(cfg.accountDeletionGrace)})
+	}
+}
+
+// confirmAccountDeletion re-authenticates the user with their password or
+// a login link sent to their email, it responds itself when it fails.
+func (cfg *apiConfig) confirmAccountDeletion(res http.ResponseWriter, req *http.Request, user database.User, body DeleteAccountReq) bool {
+	if body.MagicToken != "" {
+		magicLink, err := cfg.dbQueries.ConsumeMagicLinkToken(req.Context(), auth.HashToken(body.MagicToken))
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Error consuming magic link token: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error verifying login link")
+			return false
+		}
+		if err == sql.ErrNoRows || magicLink.UserID != user.ID {
+			respondWithError(res, http.StatusUnauthorized, "Invalid or expired login link")
+			return false
+		}
+		return true
+	}
+
+	if user.HashedPassword == unusablePasswordHash {
+		respondWithError(res, http.StatusUnauthorized, "Your account has no password, confirm with a login link instead")
+		return false
+	}
+	err := cfg.passwordHasher.Check(body.Password, user.HashedPassword)
+	if err != nil {
+		respondWithError(res, http.StatusUnauthorized, "Incorrect password")
+		return false
+	}
+	return true
+}
+
+// restoreDeletedUser cancels a pending deletion when the user logs in
+// during the grace period.
+func (cfg *apiConfig) restoreDeletedUser(ctx context.Context, user database.User) error {
+	if !user.DeletedAt.Valid {
+		return nil
+	}
+	return cfg.dbQueries.RestoreUser(ctx, user.ID)
+}
+
+// purgeDeletedUsers hard deletes the accounts whose grace period is over.
+// Chirps, tokens and exports go away through ON DELETE CASCADE, only an anonymous
+// record of the deletion is kept for accounting of Chirpy Red upgrades.
+func (cfg *apiConfig) purgeDeletedUsers(ctx context.Context) error {
+	deletedBefore := time.Now().Add(-cfg.accountDeletionGrace)
+	users, err := cfg.dbQueries.GetUsersToPurge(ctx, database.GetUsersToPurgeParams{
+		Limit:         purgeBatchSize,
+		DeletedBefore: deletedBefore,
+	})
+	if err != nil {
+		return fmt.Errorf("error getting users to purge: %w", err)
+	}
+
+	for _, user := range users {
+		err := cfg.purgeUser(ctx, user.ID, deletedBefore)
+		if err != nil {
+			fmt.Printf("Error purging user %s: %v\n", user.ID, err)
+		}
+	}
+	return nil
+}
+
+// purgeUser hard deletes a user if the deletion is still pending and older than
+// deletedBefore. The user row is locked first so a login restoring the account
+// between the select and the purge wins.
+func (cfg *apiConfig) purgeUser(ctx context.Context, userID uuid.UUID, deletedBefore time.Time) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	user, err := queries.LockUser(ctx, userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.DeletedAt.Valid || !user.DeletedAt.Time.Before(deletedBefore) {
+		return nil
+	}
+
+	err = queries.CreateAccountDeletion(ctx, database.CreateAccountDeletionParams{
+		RequestedAt:  user.DeletedAt.Time,
+		WasChirpyRed: user.IsChirpyRed,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Login throttles and rate limits are keyed by email and not linked to the user row
+	err = queries.ClearLoginThrottle(ctx, accountThrottleKey(user.Email))
+	if err != nil {
+		return err
+	}
+	err = queries.ClearRateLimit(ctx, magicLinkThrottlePrefix+accountThrottleKey(user.Email))
+	if err != nil {
+		return err
+	}
+
+	// Groups would be left without an owner by ON DELETE SET NULL
+	groups, err := queries.GetOwnedGroupsForUpdate(ctx, uuid.NullUUID{UUID: user.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		_, err = handOverGroup(ctx, queries, group, user.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	purged, err := queries.PurgeUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	if purged == 0 {
+		return fmt.Errorf("user %s was not purged", user.ID)
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+var magicLinkColumns = []string{"token_hash", "created_at", "updated_at", "user_id", "expires_at", "used_at"}
+
+func magicLinkRows(tokenHash string, userID uuid.UUID) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(magicLinkColumns).AddRow(tokenHash, now, now, userID.String(), now.Add(time.Minute), now)
+}
+
+func TestDeleteAccount(t *testing.T) {
+	newDeleteRequest := func(userID uuid.UUID, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodDelete, "/api/users/me", strings.NewReader(body))
+		return req.WithContext(withUser(req.Context(), userID))
+	}
+	expectDeletion := func(mock sqlmock.Sqlmock, user database.User) {
+		deleted := user
+		deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		expectQuery(mock, "SoftDeleteUser").WithArgs(user.ID).WillReturnRows(userRows(deleted))
+		expectExec(mock, "RevokeUserRefreshTokens").WithArgs(user.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectExec(mock, "NotifyRealtime").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	t.Run("OIDC only user confirms with a login link", func(t *testing.T) {
+		cfg, mock := newTestConfig(t)
+		user := newTestUser("walt@example.com", unusablePasswordHash)
+		tokenHash := auth.HashToken("magic-token")
+
+		expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+		expectQuery(mock, "ConsumeMagicLinkToken").WithArgs(tokenHash).WillReturnRows(magicLinkRows(tokenHash, user.ID))
+		expectDeletion(mock, user)
+
+		rec := httptest.NewRecorder()
+		deleteAccountHandler(cfg)(rec, newDeleteRequest(user.ID, `{"magic_token": "magic-token"}`))
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusAccepted, rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("OIDC only user can't confirm with a password", func(t *testing.T) {
+		cfg, mock := newTestConfig(t)
+		user := newTestUser("walt@example.com", unusablePasswordHash)
+
+		expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+
+		rec := httptest.NewRecorder()
+		deleteAccountHandler(cfg)(rec, newDeleteRequest(user.ID, `{"password": "unset"}`))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusUnauthorized, rec.Code, rec.Body)
+		}
+		if !strings.Contains(rec.Body.String(), "login link") {
+			t.Errorf("Expected the error to point to login links, got %s", rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Login link of another user", func(t *testing.T) {
+		cfg, mock := newTestConfig(t)
+		user := newTestUser("walt@example.com", unusablePasswordHash)
+		tokenHash := auth.HashToken("magic-token")
+
+		expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+		expectQuery(mock, "ConsumeMagicLinkToken").WithArgs(tokenHash).WillReturnRows(magicLinkRows(tokenHash, uuid.New()))
+
+		rec := httptest.NewRecorder()
+		deleteAccountHandler(cfg)(rec, newDeleteRequest(user.ID, `{"magic_token": "magic-token"}`))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusUnauthorized, rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Password user confirms with the password", func(t *testing.T) {
+		cfg, mock := newTestConfig(t)
+		hash, err := cfg.passwordHasher.Hash("hunter22")
+		if err != nil {
+			t.Fatalf("Error hashing password: %v", err)
+		}
+		user := newTestUser("walt@example.com", hash)
+
+		expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+		expectDeletion(mock, user)
+
+		rec := httptest.NewRecorder()
+		deleteAccountHandler(cfg)(rec, newDeleteRequest(user.ID, `{"password": "hunter22"}`))
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusAccepted, rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("No confirmation", func(t *testing.T) {
+		cfg, mock := newTestConfig(t)
+
+		rec := httptest.NewRecorder()
+		deleteAccountHandler(cfg)(rec, newDeleteRequest(uuid.New(), `{}`))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusBadRequest, rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+}
+
+func TestPurgeUser(t *testing.T) {
+	deletedBefore := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		deletedAt sql.NullTime
+	}{
+		{name: "Restored between select and purge"},
+		{name: "Deleted again within the grace period", deletedAt: sql.NullTime{Time: time.Now(), Valid: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			user := newTestUser("walt@example.com", unusablePasswordHash)
+			user.DeletedAt = tt.deletedAt
+
+			mock.ExpectBegin()
+			expectQuery(mock, "LockUser").WithArgs(user.ID).WillReturnRows(userRows(user))
+			mock.ExpectRollback()
+
+			err := cfg.purgeUser(context.Background(), user.ID, deletedBefore)
+			if err != nil {
+				t.Fatalf("purgeUser() error = %v", err)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+
+	t.Run("No row purged", func(t *testing.T) {
+		cfg, mock := newTestConfig(t)
+		user := newTestUser("walt@example.com", unusablePasswordHash)
+		user.DeletedAt = sql.NullTime{Time: deletedBefore.Add(-time.Hour), Valid: true}
+
+		mock.ExpectBegin()
+		expectQuery(mock, "LockUser").WithArgs(user.ID).WillReturnRows(userRows(user))
+		expectExec(mock, "CreateAccountDeletion").WillReturnResult(sqlmock.NewResult(0, 1))
+		expectExec(mock, "ClearLoginThrottle").WillReturnResult(sqlmock.NewResult(0, 1))
+		expectExec(mock, "ClearRateLimit").WillReturnResult(sqlmock.NewResult(0, 1))
+		expectQuery(mock, "GetOwnedGroupsForUpdate").WillReturnRows(sqlmock.NewRows(conversationColumns))
+		expectExec(mock, "PurgeUser").WithArgs(user.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := cfg.purgeUser(context.Background(), user.ID, deletedBefore)
+		if err == nil {
+			t.Fatal("Expected an error when no row was purged")
+		}
+		expectMockDone(t, mock)
+	})
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/ivportilla/chirpy/internal/auth"
+)
+
+func (cfg *apiConfig) withAdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		apiKey, err := auth.GetAPIKey(req.Header)
+		if err != nil || cfg.adminKey == "" {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.adminKey)) != 1 {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		next.ServeHTTP(res, req)
+	})
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+// RelatedUser is an entry in the caller's lists of blocked or muted users
+type RelatedUser struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// parseTargetUser returns the user in the userID path value of endpoints
+// acting on another user, responding with an error if it isn't one.
+func (cfg *apiConfig) parseTargetUser(res http.ResponseWriter, req *http.Request, userID uuid.UUID) (uuid.UUID, bool) {
+	targetID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+		return uuid.UUID{}, false
+	}
+	if targetID == userID {
+		respondWithError(res, http.StatusBadRequest, "Invalid user, it can't be yourself")
+		return uuid.UUID{}, false
+	}
+
+	target, err := cfg.dbQueries.GetUserByID(req.Context(), targetID)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Printf("Error getting user: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting user")
+		return uuid.UUID{}, false
+	}
+	if err == sql.ErrNoRows || target.DeletedAt.Valid {
+		respondWithError(res, http.StatusNotFound, "User not found")
+		return uuid.UUID{}, false
+	}
+
+	return targetID, true
+}
+
+// blockUserHandler blocks a user. Blocked users can't see the blocker's
+// chirps, follow or mention them, and any follow between both is removed.
+func blockUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		blockedID, ok := cfg.parseTargetUser(res, req, userID)
+		if !ok {
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error blocking user")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		err = queries.BlockUser(req.Context(), database.BlockUserParams{BlockerID: userID, BlockedID: blockedID})
+		if err != nil {
+			fmt.Printf("Error blocking user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error blocking user")
+			return
+		}
+
+		err = queries.DeleteFollowsBetween(req.Context(), database.DeleteFollowsBetweenParams{UserID: userID, OtherUserID: blockedID})
+		if err != nil {
+			fmt.Printf("Error removing follows: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error blocking user")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing block: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error blocking user")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func unblockUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		blockedID, err := uuid.Parse(req.PathValue("userID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+			return
+		}
+
+		err = cfg.dbQueries.UnblockUser(req.Context(), database.UnblockUserParams{BlockerID: userID, BlockedID: blockedID})
+		if err != nil {
+			fmt.Printf("Error unblocking user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error unblocking user")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func getBlockedUsersHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		blocks, err := cfg.dbQueries.GetBlockedUsers(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting blocked users: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting blocked users")
+			return
+		}
+
+		response := make([]RelatedUser, len(blocks))
+		for i, block := range blocks {
+			response[i] = RelatedUser{UserID: block.BlockedID, CreatedAt: block.CreatedAt}
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+// muteUserHandler hides the chirps of a user from the caller's listings,
+// the muted user isn't told and can still interact with the caller.
+func muteUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		mutedID, ok := cfg.parseTargetUser(res, req, userID)
+		if !ok {
+			return
+		}
+
+		err := cfg.dbQueries.MuteUser(req.Context(), database.MuteUserParams{MuterID: userID, MutedID: mutedID})
+		if err != nil {
+			fmt.Printf("Error muting user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error muting user")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func unmuteUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		mutedID, err := uuid.Parse(req.PathValue("userID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+			return
+		}
+
+		err = cfg.dbQueries.UnmuteUser(req.Context(), database.UnmuteUserParams{MuterID: userID, MutedID: mutedID})
+		if err != nil {
+			fmt.Printf("Error unmuting user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error unmuting user")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func getMutedUsersHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		mutes, err := cfg.dbQueries.GetMutedUsers(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting muted users: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting muted users")
+			return
+		}
+
+		response := make([]RelatedUser, len(mutes))
+		for i, mute := range mutes {
+			response[i] = RelatedUser{UserID: mute.MutedID, CreatedAt: mute.CreatedAt}
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
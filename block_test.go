@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func newUserActionRequest(method, path string, userID, targetID uuid.UUID) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.SetPathValue("userID", targetID.String())
+	return req.WithContext(withUser(req.Context(), userID))
+}
+
+func TestBlockUser(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	userID := uuid.New()
+	blocked := newTestUser("jesse@example.com", unusablePasswordHash)
+
+	expectQuery(mock, "GetUserByID").WithArgs(blocked.ID).WillReturnRows(userRows(blocked))
+	mock.ExpectBegin()
+	expectExec(mock, "BlockUser").WithArgs(userID, blocked.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	// Follows are removed in both directions by a single statement
+	expectExec(mock, "DeleteFollowsBetween").WithArgs(userID, blocked.ID).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	blockUserHandler(cfg)(rec, newUserActionRequest(http.MethodPost, "/api/users/"+blocked.ID.String()+"/block", userID, blocked.ID))
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d %s", http.StatusNoContent, rec.Code, rec.Body)
+	}
+	expectMockDone(t, mock)
+}
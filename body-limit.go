@@ -0,0 +1,16 @@
+package main
+
+import "net/http"
+
+// maxRequestBodyBytes caps every request body, the largest JSON bodies
+// are chirps and messages, bounded by chirptext.MaxBytes.
+const maxRequestBodyBytes = 64 * 1024
+
+// withMaxBodyMiddleware makes reading more than maxRequestBodyBytes of a
+// body fail, handlers then answer as for any undecodable body.
+func withMaxBodyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		req.Body = http.MaxBytesReader(res, req.Body, maxRequestBodyBytes)
+		next.ServeHTTP(res, req)
+	})
+}
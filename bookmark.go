@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/pagination"
+)
+
+const maxBookmarkFolderNameLength = 50
+
+type Bookmark struct {
+	Chirp        Chirp      `json:"chirp"`
+	FolderID     *uuid.UUID `json:"folder_id"`
+	BookmarkedAt time.Time  `json:"bookmarked_at"`
+}
+
+type BookmarksPage struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type BookmarkFolder struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+}
+
+type BookmarkRequest struct {
+	// Optional, only Chirpy Red users have folders
+	FolderID *uuid.UUID `json:"folder_id,omitempty"`
+}
+
+type BookmarkFolderRequest struct {
+	Name string `json:"name"`
+}
+
+func toBookmarkFolder(target database.BookmarkFolder) BookmarkFolder {
+	return BookmarkFolder{
+		ID:        target.ID,
+		CreatedAt: target.CreatedAt,
+		Name:      target.Name,
+	}
+}
+
+func nullUUIDToPtr(target uuid.NullUUID) *uuid.UUID {
+	if !target.Valid {
+		return nil
+	}
+	return &target.UUID
+}
+
+// setBookmarkedFlags marks which of the chirps the user has bookmarked.
+func (cfg *apiConfig) setBookmarkedFlags(ctx context.Context, userID uuid.UUID, chirps []Chirp) error {
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	bookmarkedIDs, err := cfg.dbQueries.GetBookmarkedChirpIDs(ctx, database.GetBookmarkedChirpIDsParams{UserID: userID, ChirpIds: chirpIDs})
+	if err != nil {
+		return fmt.Errorf("error getting bookmarks: %w", err)
+	}
+	bookmarked := make(map[uuid.UUID]bool, len(bookmarkedIDs))
+	for _, chirpID := range bookmarkedIDs {
+		bookmarked[chirpID] = true
+	}
+
+	for i := range chirps {
+		isBookmarked := bookmarked[chirps[i].ID]
+		chirps[i].Bookmarked = &isBookmarked
+	}
+	return nil
+}
+
+func bookmarkChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		// The body is optional, an empty one bookmarks without a folder
+		var reqBody BookmarkRequest
+		err = json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil && err != io.EOF {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body")
+			return
+		}
+
+		_, err = cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID(req)})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			fmt.Printf("Error getting chirp from DB: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirp information")
+			return
+		}
+
+		folderID := uuid.NullUUID{}
+		if reqBody.FolderID != nil {
+			if !cfg.requireChirpyRed(res, req, userID, "Bookmark folders are only available for Chirpy Red users") {
+				return
+			}
+			_, err = cfg.dbQueries.GetBookmarkFolder(req.Context(), database.GetBookmarkFolderParams{ID: *reqBody.FolderID, UserID: userID})
+			if err != nil {
+				if err == sql.ErrNoRows {
+					respondWithError(res, http.StatusNotFound, "Folder not found")
+					return
+				}
+				fmt.Printf("Error getting bookmark folder: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error bookmarking chirp")
+				return
+			}
+			folderID = uuid.NullUUID{UUID: *reqBody.FolderID, Valid: true}
+		}
+
+		err = cfg.dbQueries.CreateBookmark(req.Context(), database.CreateBookmarkParams{UserID: userID, ChirpID: chirpID, FolderID: folderID})
+		if err != nil {
+			fmt.Printf("Error bookmarking chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error bookmarking chirp")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func deleteBookmarkHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		err = cfg.dbQueries.DeleteBookmark(req.Context(), database.DeleteBookmarkParams{UserID: userID, ChirpID: chirpID})
+		if err != nil {
+			fmt.Printf("Error deleting bookmark: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error deleting bookmark")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+// getBookmarksHandler lists the caller's bookmarks, last bookmarked first,
+// one page at a time. next_cursor is omitted on the last page.
+func getBookmarksHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		query := req.URL.Query()
+
+		limit, err := pagination.ParseLimit(query.Get("limit"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid limit, it must be a positive number")
+			return
+		}
+
+		params := database.GetBookmarksParams{UserID: userID, PageLimit: int32(limit + 1)}
+		if rawCursor := query.Get("cursor"); rawCursor != "" {
+			cursor, err := pagination.DecodeCursor(rawCursor)
+			if err != nil {
+				respondWithError(res, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+			params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+		if rawFolderID := query.Get("folder_id"); rawFolderID != "" {
+			folderID, err := uuid.Parse(rawFolderID)
+			if err != nil {
+				respondWithError(res, http.StatusBadRequest, "Invalid folder_id, it must be a UUID")
+				return
+			}
+			params.FolderID = uuid.NullUUID{UUID: folderID, Valid: true}
+		}
+
+		rows, err := cfg.dbQueries.GetBookmarks(req.Context(), params)
+		if err != nil {
+			fmt.Printf("Error getting bookmarks: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting bookmarks")
+			return
+		}
+
+		page := BookmarksPage{Bookmarks: []Bookmark{}}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			last := rows[len(rows)-1]
+			page.NextCursor = pagination.Cursor{Time: last.BookmarkedAt, ID: last.Chirp.ID}.Encode()
+		}
+
+		chirps := make([]database.Chirp, len(rows))
+		for i, row := range rows {
+			chirps[i] = row.Chirp
+		}
+		responseChirps, err := cfg.toChirpsForViewer(req, chirps)
+		if err != nil {
+			fmt.Printf("Error getting bookmarks viewer data: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting bookmarks")
+			return
+		}
+		for i, row := range rows {
+			page.Bookmarks = append(page.Bookmarks, Bookmark{
+				Chirp:        responseChirps[i],
+				FolderID:     nullUUIDToPtr(row.FolderID),
+				BookmarkedAt: row.BookmarkedAt,
+			})
+		}
+
+		respondWithJSON(res, http.StatusOK, page)
+	}
+}
+
+// requireChirpyRed responds with 403 when the user is not a Chirpy Red user.
+func (cfg *apiConfig) requireChirpyRed(res http.ResponseWriter, req *http.Request, userID uuid.UUID, msg string) bool {
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		fmt.Printf("Error getting user: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting user")
+		return false
+	}
+	if !user.IsChirpyRed {
+		respondWithError(res, http.StatusForbidden, msg)
+		return false
+	}
+	return true
+}
+
+func createBookmarkFolderHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody BookmarkFolderRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, name field expected")
+			return
+		}
+		name := strings.TrimSpace(reqBody.Name)
+		if name == "" || len(name) > maxBookmarkFolderNameLength {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Folder name must have between 1 and %d characters", maxBookmarkFolderNameLength))
+			return
+		}
+
+		if !cfg.requireChirpyRed(res, req, userID, "Bookmark folders are only available for Chirpy Red users") {
+			return
+		}
+
+		folder, err := cfg.dbQueries.CreateBookmarkFolder(req.Context(), database.CreateBookmarkFolderParams{UserID: userID, Name: name})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusConflict, "A folder with this name already exists")
+				return
+			}
+			fmt.Printf("Error creating bookmark folder: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating folder")
+			return
+		}
+
+		respondWithJSON(res, http.StatusCreated, toBookmarkFolder(folder))
+	}
+}
+
+func getBookmarkFoldersHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		folders, err := cfg.dbQueries.GetBookmarkFolders(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting bookmark folders: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting folders")
+			return
+		}
+
+		response := make([]BookmarkFolder, len(folders))
+		for i, folder := range folders {
+			response[i] = toBookmarkFolder(folder)
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+// deleteBookmarkFolderHandler removes a folder, its bookmarks are kept
+// without a folder.
+func deleteBookmarkFolderHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		folderID, err := uuid.Parse(req.PathValue("folderID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid folder ID, it must be a UUID")
+			return
+		}
+
+		deleted, err := cfg.dbQueries.DeleteBookmarkFolder(req.Context(), database.DeleteBookmarkFolderParams{ID: folderID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error deleting bookmark folder: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error deleting folder")
+			return
+		}
+		if deleted == 0 {
+			respondWithError(res, http.StatusNotFound, "Folder not found")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
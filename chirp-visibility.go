@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+// Who can read a chirp is decided by the chirp_visible_to SQL function,
+// every chirp read query filters with it.
+const (
+	chirpVisibilityPublic    = "public"
+	chirpVisibilityFollowers = "followers"
+	chirpVisibilityMentioned = "mentioned"
+
+	maxChirpMentions = 10
+)
+
+func validateVisibility(res http.ResponseWriter, visibility string) (string, bool) {
+	switch visibility {
+	case "":
+		return chirpVisibilityPublic, true
+	case chirpVisibilityPublic, chirpVisibilityFollowers, chirpVisibilityMentioned:
+		return visibility, true
+	}
+	respondWithError(res, http.StatusBadRequest, "Invalid visibility, it must be public, followers or mentioned")
+	return "", false
+}
+
+// validateMentions removes duplicated mentions and checks the mentioned
+// users exist and haven't blocked the author.
+func (cfg *apiConfig) validateMentions(res http.ResponseWriter, req *http.Request, authorID uuid.UUID, mentions []uuid.UUID) ([]uuid.UUID, bool) {
+	seen := map[uuid.UUID]bool{}
+	cleaned := []uuid.UUID{}
+	for _, userID := range mentions {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+		cleaned = append(cleaned, userID)
+	}
+	if len(cleaned) > maxChirpMentions {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("A chirp can mention up to %d users", maxChirpMentions))
+		return nil, false
+	}
+
+	for _, userID := range cleaned {
+		user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Error getting mentioned user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
+			return nil, false
+		}
+		if err == sql.ErrNoRows || user.DeletedAt.Valid {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Mentioned user %s not found", userID))
+			return nil, false
+		}
+
+		blocked, err := cfg.dbQueries.IsBlocked(req.Context(), database.IsBlockedParams{BlockerID: userID, BlockedID: authorID})
+		if err != nil {
+			fmt.Printf("Error checking block: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
+			return nil, false
+		}
+		if blocked {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("You can't mention user %s", userID))
+			return nil, false
+		}
+	}
+
+	return cleaned, true
+}
+
+func createMentions(ctx context.Context, queries *database.Queries, chirpID uuid.UUID, mentions []uuid.UUID) error {
+	for _, userID := range mentions {
+		err := queries.CreateChirpMention(ctx, database.CreateChirpMentionParams{ChirpID: chirpID, UserID: userID})
+		if err != nil {
+			return fmt.Errorf("error creating mention: %w", err)
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) setMentions(ctx context.Context, chirps []Chirp) error {
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	mentions, err := cfg.dbQueries.GetChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return fmt.Errorf("error getting mentions: %w", err)
+	}
+	mentionsByChirp := map[uuid.UUID][]uuid.UUID{}
+	for _, mention := range mentions {
+		mentionsByChirp[mention.ChirpID] = append(mentionsByChirp[mention.ChirpID], mention.UserID)
+	}
+
+	for i := range chirps {
+		chirps[i].Mentions = mentionsByChirp[chirps[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestValidateMentions(t *testing.T) {
+	tests := []struct {
+		name       string
+		blocked    bool
+		wantStatus int
+	}{
+		{name: "Mentioned user blocked the author", blocked: true, wantStatus: http.StatusBadRequest},
+		{name: "Mentioned user didn't block the author", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			authorID := uuid.New()
+			mentioned := newTestUser("jesse@example.com", unusablePasswordHash)
+
+			expectQuery(mock, "GetUserByID").WithArgs(mentioned.ID).WillReturnRows(userRows(mentioned))
+			expectQuery(mock, "IsBlocked").WithArgs(mentioned.ID, authorID).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.blocked))
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/chirps", nil)
+			// The same user mentioned twice is checked once
+			got, ok := cfg.validateMentions(rec, req, authorID, []uuid.UUID{mentioned.ID, mentioned.ID})
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if ok && (len(got) != 1 || got[0] != mentioned.ID) {
+				t.Errorf("validateMentions() = %v, want [%s]", got, mentioned.ID)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
@@ -9,11 +9,22 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/chirptext"
 	"github.com/ivportilla/chirpy/internal/database"
 )
 
 type RequestParams struct {
 	Body string `json:"body"`
+	// Optional, chirps with a future publish_at are scheduled instead of published
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	// Optional poll attached to the chirp
+	Poll *PollRequest `json:"poll,omitempty"`
+	// public (default), followers or mentioned
+	Visibility string      `json:"visibility,omitempty"`
+	Mentions   []uuid.UUID `json:"mentions,omitempty"`
+	// Optional spoiler text shown instead of the body until expanded
+	ContentWarning *string `json:"content_warning,omitempty"`
+	Sensitive      bool    `json:"sensitive,omitempty"`
 }
 
 type ValidationResponse struct {
@@ -21,15 +32,64 @@ type ValidationResponse struct {
 }
 
 type Chirp struct {
+	ID             uuid.UUID   `json:"id"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+	Body           string      `json:"body"`
+	UserID         uuid.UUID   `json:"user_id"`
+	Visibility     string      `json:"visibility"`
+	Mentions       []uuid.UUID `json:"mentions,omitempty"`
+	ContentWarning *string     `json:"content_warning,omitempty"`
+	Sensitive      bool        `json:"sensitive"`
+	// Whether clients should collapse the body, it depends on the reader preferences
+	Collapsed bool `json:"collapsed"`
+	// Phrases of the reader's warn filters the chirp matches
+	Filtered  []string   `json:"filtered,omitempty"`
+	Edited    bool       `json:"edited"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	PublishAt *time.Time `json:"publish_at,omitempty"`
+	Poll      *Poll      `json:"poll,omitempty"`
+	// Reaction counts in the configured emoji order, emojis nobody used are left out
+	Reactions []ReactionCount `json:"reactions,omitempty"`
+	// Preview of the first link in the body, once it has been fetched
+	LinkPreview *LinkPreview `json:"link_preview,omitempty"`
+	// Only set for authenticated callers
+	Bookmarked *bool `json:"bookmarked,omitempty"`
+}
+
+type ChirpRevision struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
 	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
 }
 
-func isChirpValid(content string) bool {
-	return len(content) <= 140
+func (cfg *apiConfig) chirpMaxLength(user database.User) int {
+	if user.IsChirpyRed {
+		return cfg.chirpMaxLengthRed
+	}
+	return cfg.chirpMaxLengthFree
+}
+
+// validateChirpBody normalizes the body and checks it fits in the author's
+// length limit, see chirptext.Length for how characters are counted.
+func (cfg *apiConfig) validateChirpBody(res http.ResponseWriter, req *http.Request, userID uuid.UUID, body string) (string, bool) {
+	user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+	if err != nil {
+		fmt.Printf("Error getting chirp author: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting user")
+		return "", false
+	}
+
+	body = chirptext.Normalize(body)
+	if err := chirptext.CheckSize(body); err != nil {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Chirp is too large, %v", err))
+		return "", false
+	}
+	if limit := cfg.chirpMaxLength(user); chirptext.Length(body) > limit {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Chirp is too long, the limit is %d characters", limit))
+		return "", false
+	}
+	return body, true
 }
 
 var forbiddenWords = []string{"sharbert", "kerfuffle", "fornax"}
@@ -59,13 +119,81 @@ func sanitizeChirp(content string) string {
 }
 
 func toChirp(target database.Chirp) Chirp {
-	return Chirp{
-		ID:        target.ID,
-		CreatedAt: target.CreatedAt,
-		UpdatedAt: target.UpdatedAt,
-		Body:      target.Body,
-		UserID:    target.UserID,
+	chirp := Chirp{
+		ID:         target.ID,
+		CreatedAt:  target.CreatedAt,
+		UpdatedAt:  target.UpdatedAt,
+		Body:       target.Body,
+		UserID:     target.UserID,
+		Visibility: target.Visibility,
+		Sensitive:  target.Sensitive,
+		Collapsed:  target.Sensitive || target.ContentWarning.Valid,
+		Edited:     target.EditedAt.Valid,
+		EditedAt:   nullTimeToPtr(target.EditedAt),
+	}
+	if target.ContentWarning.Valid {
+		chirp.ContentWarning = &target.ContentWarning.String
+	}
+	if target.Status == chirpStatusScheduled {
+		chirp.PublishAt = nullTimeToPtr(target.PublishAt)
 	}
+	return chirp
+}
+
+// toChirpsForViewer converts the chirps adding the fields that depend on
+// who is asking, anonymous callers only get the public fields.
+func (cfg *apiConfig) toChirpsForViewer(req *http.Request, chirps []database.Chirp) ([]Chirp, error) {
+	response := make([]Chirp, len(chirps))
+	for i, chirp := range chirps {
+		response[i] = toChirp(chirp)
+	}
+
+	if len(response) == 0 {
+		return response, nil
+	}
+	viewer := viewerID(req)
+
+	err := cfg.setMentions(req.Context(), response)
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.setLinkPreviews(req.Context(), response)
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.setPolls(req.Context(), viewer, response)
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.setReactions(req.Context(), viewer, response)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.Valid {
+		return response, nil
+	}
+
+	err = cfg.setBookmarkedFlags(req.Context(), viewer.UUID, response)
+	if err != nil {
+		return nil, err
+	}
+	err = cfg.applySensitiveContentPreference(req.Context(), viewer.UUID, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (cfg *apiConfig) respondWithChirp(res http.ResponseWriter, req *http.Request, code int, chirp database.Chirp) {
+	response, err := cfg.toChirpsForViewer(req, []database.Chirp{chirp})
+	if err != nil {
+		fmt.Printf("Error getting chirp viewer data: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting chirp")
+		return
+	}
+
+	respondWithJSON(res, code, response[0])
 }
 
 func createChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
@@ -81,11 +209,6 @@ func createChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request)
 			return
 		}
 
-		if !isChirpValid(reqBody.Body) {
-			respondWithError(res, http.StatusBadRequest, "Chirp is too long")
-			return
-		}
-
 		userID, err := uuid.Parse(req.Context().Value("user_id").(string))
 		if err != nil {
 			fmt.Printf("Error converting user_id to uuid: %v\n", err)
@@ -93,15 +216,106 @@ func createChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request)
 			return
 		}
 
-		chirpBody := sanitizeChirp(reqBody.Body)
-		chirp, err := cfg.dbQueries.CreateChirp(req.Context(), database.CreateChirpParams{Body: chirpBody, UserID: userID})
+		body, ok := cfg.validateChirpBody(res, req, userID, reqBody.Body)
+		if !ok {
+			return
+		}
+
+		scheduled := reqBody.PublishAt != nil && reqBody.PublishAt.After(time.Now())
+		publishedAt := time.Now()
+		if scheduled {
+			publishedAt = *reqBody.PublishAt
+		}
+		visibility, ok := validateVisibility(res, reqBody.Visibility)
+		if !ok {
+			return
+		}
+		mentions, ok := cfg.validateMentions(res, req, userID, reqBody.Mentions)
+		if !ok {
+			return
+		}
+		contentWarning, ok := validateContentWarning(res, reqBody.ContentWarning)
+		if !ok {
+			return
+		}
+
+		var pollOptions []string
+		if reqBody.Poll != nil {
+			pollOptions, ok = validatePollRequest(res, *reqBody.Poll, publishedAt)
+			if !ok {
+				return
+			}
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		chirpBody := sanitizeChirp(body)
+		var chirp database.Chirp
+		if scheduled {
+			chirp, err = queries.CreateScheduledChirp(req.Context(), database.CreateScheduledChirpParams{
+				Body:           chirpBody,
+				UserID:         userID,
+				PublishAt:      sql.NullTime{Time: reqBody.PublishAt.Local(), Valid: true},
+				Visibility:     visibility,
+				ContentWarning: contentWarning,
+				Sensitive:      reqBody.Sensitive,
+			})
+		} else {
+			chirp, err = queries.CreateChirp(req.Context(), database.CreateChirpParams{
+				Body:           chirpBody,
+				UserID:         userID,
+				Visibility:     visibility,
+				ContentWarning: contentWarning,
+				Sensitive:      reqBody.Sensitive,
+			})
+		}
 		if err != nil {
 			fmt.Printf("Error creating chirp: %v", err)
 			respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
 			return
 		}
 
-		respondWithJSON(res, http.StatusCreated, toChirp(chirp))
+		err = createMentions(req.Context(), queries, chirp.ID, mentions)
+		if err != nil {
+			fmt.Printf("Error creating chirp mentions: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
+			return
+		}
+
+		err = saveChirpLinks(req.Context(), queries, chirp.ID, chirp.Body)
+		if err != nil {
+			fmt.Printf("Error saving chirp links: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
+			return
+		}
+
+		if reqBody.Poll != nil {
+			err = createPoll(req.Context(), queries, chirp.ID, pollOptions, reqBody.Poll.ClosesAt)
+			if err != nil {
+				fmt.Printf("Error creating chirp poll: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
+				return
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating chirp")
+			return
+		}
+		if !scheduled {
+			cfg.notifyMentions(req.Context(), []database.Chirp{chirp})
+		}
+
+		cfg.respondWithChirp(res, req, http.StatusCreated, chirp)
 	}
 }
 
@@ -115,7 +329,7 @@ func deleteChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request)
 			return
 		}
 
-		chirp, err := cfg.dbQueries.GetChirp(req.Context(), chirpID)
+		chirp, err := cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID(req)})
 		if err != nil {
 			fmt.Printf("Error getting chirp from DB: %v", err)
 			if err == sql.ErrNoRows {
@@ -141,6 +355,145 @@ func deleteChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request)
 	}
 }
 
+func editChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			fmt.Printf("Error converting chirp id to uuid: %v\n", err)
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		var reqBody RequestParams
+		err = json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			fmt.Printf("Error decoding chirp request body: %v\n", err)
+			respondWithError(res, http.StatusBadRequest, "Error decoding request")
+			return
+		}
+
+		body, ok := cfg.validateChirpBody(res, req, userID, reqBody.Body)
+		if !ok {
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error editing chirp")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		// The row stays locked until commit so concurrent edits can't both
+		// save a revision of the same body
+		chirp, err := queries.GetChirpForUpdate(req.Context(), database.GetChirpForUpdateParams{ID: chirpID, ViewerID: viewerID(req)})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			fmt.Printf("Error getting chirp from DB: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirp information")
+			return
+		}
+
+		if chirp.UserID != userID {
+			respondWithError(res, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		if time.Since(chirp.CreatedAt) > cfg.chirpEditWindow {
+			respondWithError(res, http.StatusForbidden, "Chirp can no longer be edited")
+			return
+		}
+
+		chirpBody := sanitizeChirp(body)
+		if chirpBody == chirp.Body {
+			cfg.respondWithChirp(res, req, http.StatusOK, chirp)
+			return
+		}
+
+		// The revision keeps the previous body with the time it was written
+		revisionCreatedAt := chirp.CreatedAt
+		if chirp.EditedAt.Valid {
+			revisionCreatedAt = chirp.EditedAt.Time
+		}
+		_, err = queries.CreateChirpRevision(req.Context(), database.CreateChirpRevisionParams{
+			CreatedAt: revisionCreatedAt,
+			ChirpID:   chirp.ID,
+			Body:      chirp.Body,
+		})
+		if err != nil {
+			fmt.Printf("Error saving chirp revision: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error editing chirp")
+			return
+		}
+
+		chirp, err = queries.UpdateChirpBody(req.Context(), database.UpdateChirpBodyParams{ID: chirp.ID, Body: chirpBody})
+		if err != nil {
+			fmt.Printf("Error updating chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error editing chirp")
+			return
+		}
+
+		err = saveChirpLinks(req.Context(), queries, chirp.ID, chirp.Body)
+		if err != nil {
+			fmt.Printf("Error saving chirp links: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error editing chirp")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing chirp edit: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error editing chirp")
+			return
+		}
+
+		cfg.respondWithChirp(res, req, http.StatusOK, chirp)
+	}
+}
+
+func getChirpRevisionsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			fmt.Printf("Error converting chirp id to uuid: %v\n", err)
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		_, err = cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID(req)})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			fmt.Printf("Error fetching chirp %s: %v\n", chirpID, err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirp")
+			return
+		}
+
+		revisions, err := cfg.dbQueries.GetChirpRevisions(req.Context(), chirpID)
+		if err != nil {
+			fmt.Printf("Error getting chirp revisions: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirp revisions")
+			return
+		}
+
+		response := make([]ChirpRevision, len(revisions))
+		for i, revision := range revisions {
+			response[i] = ChirpRevision{ID: revision.ID, CreatedAt: revision.CreatedAt, Body: revision.Body}
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
 func getSortBy(sortBy string) string {
 	if strings.ToUpper(sortBy) == "DESC" {
 		return "DESC"
@@ -157,7 +510,7 @@ func getChirpsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 		sortBy := getSortBy(req.URL.Query().Get("sort"))
 		rawAuthorID := req.URL.Query().Get("author_id")
 		if rawAuthorID == "" {
-			chirps, err = cfg.dbQueries.GetChirps(req.Context(), sortBy)
+			chirps, err = cfg.dbQueries.GetChirps(req.Context(), database.GetChirpsParams{ViewerID: viewerID(req), OrderBy: sortBy})
 		} else {
 			authorID, err := uuid.Parse(rawAuthorID)
 			if err != nil {
@@ -165,7 +518,7 @@ func getChirpsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 				respondWithError(res, http.StatusBadRequest, "Error parsing author_id, it must be an uuid")
 				return
 			}
-			chirps, err = cfg.dbQueries.GetChirpsByAuthor(req.Context(), database.GetChirpsByAuthorParams{UserID: authorID, OrderBy: sortBy})
+			chirps, err = cfg.dbQueries.GetChirpsByAuthor(req.Context(), database.GetChirpsByAuthorParams{UserID: authorID, ViewerID: viewerID(req), OrderBy: sortBy})
 		}
 
 		if err != nil {
@@ -178,9 +531,19 @@ func getChirpsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
-		response := make([]Chirp, len(chirps))
-		for i, chirp := range chirps {
-			response[i] = toChirp(chirp)
+		response, err := cfg.toChirpsForViewer(req, chirps)
+		if err != nil {
+			fmt.Printf("Error getting chirps viewer data: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirps")
+			return
+		}
+		if viewer := viewerID(req); viewer.Valid {
+			response, err = cfg.applyMuteFilters(req.Context(), viewer.UUID, response)
+			if err != nil {
+				fmt.Printf("Error applying mute filters: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error getting chirps")
+				return
+			}
 		}
 
 		respondWithJSON(res, http.StatusOK, response)
@@ -196,7 +559,7 @@ func getChirp(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
 			return
 		}
-		chirp, err := cfg.dbQueries.GetChirp(req.Context(), parsedId)
+		chirp, err := cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: parsedId, ViewerID: viewerID(req)})
 		if err != nil {
 			if err == sql.ErrNoRows {
 				respondWithError(res, http.StatusNotFound, "Chirp not found")
@@ -208,6 +571,6 @@ func getChirp(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
-		respondWithJSON(res, http.StatusOK, toChirp(chirp))
+		cfg.respondWithChirp(res, req, http.StatusOK, chirp)
 	}
 }
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+var chirpColumns = []string{"id", "created_at", "updated_at", "body", "user_id", "edited_at", "status", "publish_at", "visibility", "content_warning", "sensitive"}
+
+func chirpRow(id, userID uuid.UUID, createdAt time.Time, body string) *sqlmock.Rows {
+	return sqlmock.NewRows(chirpColumns).AddRow(id.String(), createdAt, createdAt, body, userID.String(), nil, "published", nil, "public", nil, false)
+}
+
+// expectChirpViewerData expects the queries filling the response of a
+// single chirp without mentions, links, polls, reactions or bookmarks.
+func expectChirpViewerData(mock sqlmock.Sqlmock, viewer *database.User) {
+	expectQuery(mock, "GetChirpMentions").WillReturnRows(sqlmock.NewRows([]string{"chirp_id", "user_id"}))
+	expectQuery(mock, "GetChirpsLinkPreviews").WillReturnRows(sqlmock.NewRows([]string{"chirp_id"}))
+	expectQuery(mock, "GetPolls").WillReturnRows(sqlmock.NewRows([]string{"chirp_id"}))
+	expectQuery(mock, "GetReactionCounts").WillReturnRows(sqlmock.NewRows([]string{"chirp_id", "emoji", "reactions"}))
+	if viewer == nil {
+		return
+	}
+	expectQuery(mock, "GetViewerReactions").WillReturnRows(sqlmock.NewRows([]string{"chirp_id", "emoji"}))
+	expectQuery(mock, "GetBookmarkedChirpIDs").WillReturnRows(sqlmock.NewRows([]string{"chirp_id"}))
+	expectQuery(mock, "GetUserByID").WithArgs(viewer.ID).WillReturnRows(userRows(*viewer))
+}
+
+func TestEditChirp(t *testing.T) {
+	newEditRequest := func(userID, chirpID uuid.UUID, body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPut, "/api/chirps/"+chirpID.String(), strings.NewReader(body))
+		req.SetPathValue("chirpID", chirpID.String())
+		return req.WithContext(withUser(req.Context(), userID))
+	}
+
+	tests := []struct {
+		name       string
+		author     bool
+		createdAt  time.Time
+		chirpErr   error
+		wantStatus int
+	}{
+		{name: "Chirp of another user", createdAt: time.Now(), wantStatus: http.StatusForbidden},
+		{name: "Edit window is over", author: true, createdAt: time.Now().Add(-time.Hour), wantStatus: http.StatusForbidden},
+		{name: "Chirp not found", chirpErr: sql.ErrNoRows, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			cfg.chirpEditWindow = 30 * time.Minute
+			cfg.chirpMaxLengthFree = 140
+			user := newTestUser("walt@example.com", unusablePasswordHash)
+			chirpID := uuid.New()
+			authorID := uuid.New()
+			if tt.author {
+				authorID = user.ID
+			}
+
+			expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+			// The chirp is read and locked inside the transaction of the edit
+			mock.ExpectBegin()
+			getChirp := expectQuery(mock, "GetChirpForUpdate").WithArgs(chirpID, user.ID)
+			if tt.chirpErr != nil {
+				getChirp.WillReturnError(tt.chirpErr)
+			} else {
+				getChirp.WillReturnRows(chirpRow(chirpID, authorID, tt.createdAt, "old body"))
+			}
+			mock.ExpectRollback()
+
+			rec := httptest.NewRecorder()
+			editChirpHandler(cfg)(rec, newEditRequest(user.ID, chirpID, `{"body": "new body"}`))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
+
+func TestGetChirp(t *testing.T) {
+	tests := []struct {
+		name       string
+		viewer     bool
+		visible    bool
+		wantStatus int
+	}{
+		// Blocks are applied by chirp_visible_to, the query finds no chirp
+		{name: "Viewer blocked by the author", viewer: true, wantStatus: http.StatusNotFound},
+		// So are followers-only chirps for anyone not following the author
+		{name: "Followers only chirp for a non follower", viewer: true, wantStatus: http.StatusNotFound},
+		{name: "Followers only chirp for an anonymous caller", wantStatus: http.StatusNotFound},
+		{name: "Followers only chirp for a follower", viewer: true, visible: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			chirpID := uuid.New()
+			viewer := newTestUser("jesse@example.com", unusablePasswordHash)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirpID.String(), nil)
+			req.SetPathValue("chirpID", chirpID.String())
+			viewerID := uuid.NullUUID{}
+			if tt.viewer {
+				viewerID = uuid.NullUUID{UUID: viewer.ID, Valid: true}
+				req = req.WithContext(withUser(req.Context(), viewer.ID))
+			}
+
+			rows := sqlmock.NewRows(chirpColumns)
+			if tt.visible {
+				now := time.Now()
+				rows.AddRow(chirpID.String(), now, now, "hello", uuid.New().String(), nil, "published", nil, chirpVisibilityFollowers, nil, false)
+			}
+			expectQuery(mock, "GetChirp").WithArgs(chirpID, viewerID).WillReturnRows(rows)
+			if tt.visible {
+				expectChirpViewerData(mock, &viewer)
+			}
+
+			rec := httptest.NewRecorder()
+			getChirp(cfg)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if tt.visible {
+				var got Chirp
+				decodeBody(t, rec, &got)
+				if got.ID != chirpID || got.Visibility != chirpVisibilityFollowers {
+					t.Errorf("Expected followers only chirp %s, got %+v", chirpID, got)
+				}
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/ivportilla/chirpy/internal/chirptext"
+)
+
+type ChirpConfig struct {
+	// Limit of the caller, the free one for anonymous callers
+	MaxLength     int    `json:"max_length"`
+	MaxLengthFree int    `json:"max_length_free"`
+	MaxLengthRed  int    `json:"max_length_red"`
+	Counting      string `json:"counting"`
+	Normalization string `json:"normalization"`
+	URLLength     int    `json:"url_length"`
+	URLPattern    string `json:"url_pattern"`
+}
+
+type ServerConfig struct {
+	Chirp ChirpConfig `json:"chirp"`
+	// Emojis chirps can be reacted with, in display order
+	ReactionEmojis []string `json:"reaction_emojis"`
+}
+
+// getConfigHandler exposes the rules clients need to validate chirps the
+// same way the server does.
+func getConfigHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		chirpConfig := ChirpConfig{
+			MaxLength:     cfg.chirpMaxLengthFree,
+			MaxLengthFree: cfg.chirpMaxLengthFree,
+			MaxLengthRed:  cfg.chirpMaxLengthRed,
+			Counting:      "grapheme_clusters",
+			Normalization: "NFC",
+			URLLength:     chirptext.URLLength,
+			URLPattern:    chirptext.URLPattern,
+		}
+
+		if viewer := viewerID(req); viewer.Valid {
+			user, err := cfg.dbQueries.GetUserByID(req.Context(), viewer.UUID)
+			if err != nil {
+				fmt.Printf("Error getting user: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error getting config")
+				return
+			}
+			chirpConfig.MaxLength = cfg.chirpMaxLength(user)
+		}
+
+		respondWithJSON(res, http.StatusOK, ServerConfig{Chirp: chirpConfig, ReactionEmojis: cfg.reactionEmojis})
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/chirptext"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+// Reader preference for chirps with a content warning or flagged as
+// sensitive. Clients get it per chirp in the collapsed field.
+const (
+	sensitiveContentHide   = "hide"
+	sensitiveContentExpand = "expand"
+
+	maxContentWarningLength = 100
+)
+
+type SensitiveFlagRequest struct {
+	Sensitive bool `json:"sensitive"`
+}
+
+type PreferencesRequest struct {
+	SensitiveContent *string `json:"sensitive_content"`
+	DMPrivacy        *string `json:"dm_privacy"`
+}
+
+func validateContentWarning(res http.ResponseWriter, contentWarning *string) (sql.NullString, bool) {
+	if contentWarning == nil || strings.TrimSpace(*contentWarning) == "" {
+		return sql.NullString{}, true
+	}
+
+	warning := chirptext.Normalize(strings.TrimSpace(*contentWarning))
+	if chirptext.CheckSize(warning) != nil || chirptext.Length(warning) > maxContentWarningLength {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Content warning is too long, the limit is %d characters", maxContentWarningLength))
+		return sql.NullString{}, false
+	}
+	return sql.NullString{String: warning, Valid: true}, true
+}
+
+// applySensitiveContentPreference expands the flagged chirps for readers
+// who asked for it, chirps are collapsed by default.
+func (cfg *apiConfig) applySensitiveContentPreference(ctx context.Context, userID uuid.UUID, chirps []Chirp) error {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user.SensitiveContent != sensitiveContentExpand {
+		return nil
+	}
+
+	for i := range chirps {
+		chirps[i].Collapsed = false
+	}
+	return nil
+}
+
+// setChirpSensitiveHandler lets moderators flag or unflag any chirp they
+// can see as sensitive, no matter how old it is.
+func setChirpSensitiveHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		var reqBody SensitiveFlagRequest
+		err = json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, sensitive field expected")
+			return
+		}
+
+		user, err := cfg.dbQueries.GetUserByID(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting user")
+			return
+		}
+		if !user.IsModerator {
+			respondWithError(res, http.StatusForbidden, "Only moderators can flag chirps")
+			return
+		}
+
+		_, err = cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID(req)})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			fmt.Printf("Error getting chirp from DB: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirp information")
+			return
+		}
+
+		chirp, err := cfg.dbQueries.SetChirpSensitive(req.Context(), database.SetChirpSensitiveParams{ID: chirpID, Sensitive: reqBody.Sensitive})
+		if err != nil {
+			fmt.Printf("Error flagging chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error flagging chirp")
+			return
+		}
+
+		cfg.respondWithChirp(res, req, http.StatusOK, chirp)
+	}
+}
+
+func updatePreferencesHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody PreferencesRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil || (reqBody.SensitiveContent == nil && reqBody.DMPrivacy == nil) {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, sensitive_content or dm_privacy field expected")
+			return
+		}
+
+		params := database.UpdateUserPreferencesParams{ID: userID}
+		if reqBody.SensitiveContent != nil {
+			if *reqBody.SensitiveContent != sensitiveContentHide && *reqBody.SensitiveContent != sensitiveContentExpand {
+				respondWithError(res, http.StatusBadRequest, "Invalid sensitive_content, it must be hide or expand")
+				return
+			}
+			params.SensitiveContent = sql.NullString{String: *reqBody.SensitiveContent, Valid: true}
+		}
+		if reqBody.DMPrivacy != nil {
+			if !isValidDMPrivacy(*reqBody.DMPrivacy) {
+				respondWithError(res, http.StatusBadRequest, "Invalid dm_privacy, it must be everyone, following or nobody")
+				return
+			}
+			params.DmPrivacy = sql.NullString{String: *reqBody.DMPrivacy, Valid: true}
+		}
+
+		user, err := cfg.dbQueries.UpdateUserPreferences(req.Context(), params)
+		if err != nil {
+			fmt.Printf("Error updating preferences: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating preferences")
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, ToResponseUser(user))
+	}
+}
+
+func setModeratorHandler(cfg *apiConfig, isModerator bool) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, err := uuid.Parse(req.PathValue("userID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+			return
+		}
+
+		user, err := cfg.dbQueries.SetUserModerator(req.Context(), database.SetUserModeratorParams{ID: userID, IsModerator: isModerator})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "User not found")
+				return
+			}
+			fmt.Printf("Error updating moderator: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating user")
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, ToResponseUser(user))
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestSetChirpSensitive(t *testing.T) {
+	tests := []struct {
+		name       string
+		moderator  bool
+		wantStatus int
+	}{
+		{name: "Moderator flags a chirp", moderator: true, wantStatus: http.StatusOK},
+		{name: "Regular user can't flag a chirp", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			user := newTestUser("walt@example.com", unusablePasswordHash)
+			user.IsModerator = tt.moderator
+			chirpID := uuid.New()
+			authorID := uuid.New()
+			now := time.Now()
+
+			expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+			if tt.moderator {
+				expectQuery(mock, "GetChirp").WithArgs(chirpID, uuid.NullUUID{UUID: user.ID, Valid: true}).
+					WillReturnRows(chirpRow(chirpID, authorID, now, "hello"))
+				expectQuery(mock, "SetChirpSensitive").WithArgs(chirpID, true).
+					WillReturnRows(sqlmock.NewRows(chirpColumns).AddRow(chirpID.String(), now, now, "hello", authorID.String(), nil, "published", nil, chirpVisibilityPublic, nil, true))
+				expectChirpViewerData(mock, &user)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/api/chirps/"+chirpID.String()+"/sensitive", strings.NewReader(`{"sensitive": true}`))
+			req.SetPathValue("chirpID", chirpID.String())
+			rec := httptest.NewRecorder()
+			setChirpSensitiveHandler(cfg)(rec, req.WithContext(withUser(req.Context(), user.ID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if tt.moderator {
+				var got Chirp
+				decodeBody(t, rec, &got)
+				if !got.Sensitive {
+					t.Errorf("Expected the chirp to be flagged as sensitive, got %+v", got)
+				}
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
@@ -0,0 +1,532 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/chirptext"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/pagination"
+)
+
+const maxMessageLength = 1000
+
+const (
+	conversationKindDirect = "direct"
+	conversationKindGroup  = "group"
+)
+
+// Who can start a direct conversation with a user.
+const (
+	dmPrivacyEveryone  = "everyone"
+	dmPrivacyFollowing = "following"
+	dmPrivacyNobody    = "nobody"
+)
+
+type Conversation struct {
+	ID        uuid.UUID   `json:"id"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	Kind      string      `json:"kind"`
+	Name      *string     `json:"name,omitempty"`
+	OwnerID   *uuid.UUID  `json:"owner_id,omitempty"`
+	Members   []uuid.UUID `json:"members"`
+	// Up to when each member has read the conversation
+	ReadCursors []ReadCursor `json:"read_cursors"`
+	LastMessage *Message     `json:"last_message"`
+	UnreadCount int64        `json:"unread_count"`
+}
+
+type ReadCursor struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	CreatedAt      time.Time `json:"created_at"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	// Empty once the sender's account is purged
+	SenderID *uuid.UUID `json:"sender_id"`
+	Body     string     `json:"body"`
+	// Only set for system messages, user_id is the member the event is about
+	Event  *string    `json:"event,omitempty"`
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+}
+
+type ReadRequest struct {
+	MessageID *uuid.UUID `json:"message_id"`
+}
+
+type MessagesPage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type MessageRequest struct {
+	Body string `json:"body"`
+}
+
+func isValidDMPrivacy(privacy string) bool {
+	return privacy == dmPrivacyEveryone || privacy == dmPrivacyFollowing || privacy == dmPrivacyNobody
+}
+
+// directConversationKey identifies the direct conversation between two
+// users no matter who started it.
+func directConversationKey(userID, otherUserID uuid.UUID) string {
+	ids := []string{userID.String(), otherUserID.String()}
+	if ids[1] < ids[0] {
+		ids[0], ids[1] = ids[1], ids[0]
+	}
+	return strings.Join(ids, ":")
+}
+
+func toMessage(target database.Message) Message {
+	message := Message{
+		ID:             target.ID,
+		CreatedAt:      target.CreatedAt,
+		ConversationID: target.ConversationID,
+		SenderID:       nullUUIDToPtr(target.SenderID),
+		Body:           target.Body,
+		UserID:         nullUUIDToPtr(target.UserID),
+	}
+	if target.Event.Valid {
+		message.Event = &target.Event.String
+	}
+	return message
+}
+
+// canStartConversation checks the recipient's DM privacy setting and that
+// neither user blocked the other.
+func (cfg *apiConfig) canStartConversation(ctx context.Context, senderID uuid.UUID, recipient database.User) (bool, error) {
+	blocked, err := cfg.dbQueries.IsBlockedBetween(ctx, database.IsBlockedBetweenParams{UserID: senderID, OtherUserID: recipient.ID})
+	if err != nil {
+		return false, fmt.Errorf("error checking blocks: %w", err)
+	}
+	if blocked {
+		return false, nil
+	}
+
+	switch recipient.DmPrivacy {
+	case dmPrivacyEveryone:
+		return true, nil
+	case dmPrivacyFollowing:
+		following, err := cfg.dbQueries.IsFollowing(ctx, database.IsFollowingParams{FollowerID: recipient.ID, FolloweeID: senderID})
+		if err != nil {
+			return false, fmt.Errorf("error checking follow: %w", err)
+		}
+		return following, nil
+	default:
+		return false, nil
+	}
+}
+
+// toConversations adds the members, last message and unread count the
+// user has in each conversation.
+func (cfg *apiConfig) toConversations(ctx context.Context, userID uuid.UUID, conversations []database.Conversation) ([]Conversation, error) {
+	response := make([]Conversation, len(conversations))
+	if len(conversations) == 0 {
+		return response, nil
+	}
+
+	ids := make([]uuid.UUID, len(conversations))
+	for i, conversation := range conversations {
+		ids[i] = conversation.ID
+	}
+
+	members, err := cfg.dbQueries.GetConversationMembers(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error getting conversation members: %w", err)
+	}
+	membersByConversation := map[uuid.UUID][]database.ConversationMember{}
+	for _, member := range members {
+		membersByConversation[member.ConversationID] = append(membersByConversation[member.ConversationID], member)
+	}
+
+	lastMessages, err := cfg.dbQueries.GetLastMessages(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error getting last messages: %w", err)
+	}
+	lastMessageByConversation := map[uuid.UUID]Message{}
+	for _, message := range lastMessages {
+		lastMessageByConversation[message.ConversationID] = toMessage(message)
+	}
+
+	unreadCounts, err := cfg.dbQueries.GetUnreadCounts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unread counts: %w", err)
+	}
+	unreadByConversation := map[uuid.UUID]int64{}
+	for _, count := range unreadCounts {
+		unreadByConversation[count.ConversationID] = count.Unread
+	}
+
+	for i, conversation := range conversations {
+		response[i] = Conversation{
+			ID:          conversation.ID,
+			CreatedAt:   conversation.CreatedAt,
+			UpdatedAt:   conversation.UpdatedAt,
+			Kind:        conversation.Kind,
+			OwnerID:     nullUUIDToPtr(conversation.OwnerID),
+			Members:     []uuid.UUID{},
+			ReadCursors: []ReadCursor{},
+			UnreadCount: unreadByConversation[conversation.ID],
+		}
+		if conversation.Name.Valid {
+			response[i].Name = &conversation.Name.String
+		}
+		for _, member := range membersByConversation[conversation.ID] {
+			response[i].Members = append(response[i].Members, member.UserID)
+			response[i].ReadCursors = append(response[i].ReadCursors, ReadCursor{UserID: member.UserID, LastReadAt: nullTimeToPtr(member.LastReadAt)})
+		}
+		if message, ok := lastMessageByConversation[conversation.ID]; ok {
+			response[i].LastMessage = &message
+		}
+	}
+	return response, nil
+}
+
+func (cfg *apiConfig) respondWithConversation(res http.ResponseWriter, req *http.Request, userID uuid.UUID, code int, conversation database.Conversation) {
+	response, err := cfg.toConversations(req.Context(), userID, []database.Conversation{conversation})
+	if err != nil {
+		fmt.Printf("Error getting conversation data: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting conversation")
+		return
+	}
+	respondWithJSON(res, code, response[0])
+}
+
+// getMemberConversation gets the conversation in the path, conversations
+// the user isn't a member of are reported as not found.
+func (cfg *apiConfig) getMemberConversation(res http.ResponseWriter, req *http.Request, userID uuid.UUID) (database.Conversation, bool) {
+	conversationID, err := uuid.Parse(req.PathValue("conversationID"))
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Invalid conversation ID, it must be a UUID")
+		return database.Conversation{}, false
+	}
+
+	conversation, err := cfg.dbQueries.GetConversationForMember(req.Context(), database.GetConversationForMemberParams{ID: conversationID, UserID: userID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(res, http.StatusNotFound, "Conversation not found")
+			return database.Conversation{}, false
+		}
+		fmt.Printf("Error getting conversation: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting conversation")
+		return database.Conversation{}, false
+	}
+	return conversation, true
+}
+
+// startConversationHandler returns the direct conversation with the user
+// in the path, creating it if they never talked before.
+func startConversationHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		recipientID, ok := cfg.parseTargetUser(res, req, userID)
+		if !ok {
+			return
+		}
+		directKey := sql.NullString{String: directConversationKey(userID, recipientID), Valid: true}
+
+		conversation, err := cfg.dbQueries.GetDirectConversation(req.Context(), directKey)
+		if err == nil {
+			cfg.respondWithConversation(res, req, userID, http.StatusOK, conversation)
+			return
+		}
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting conversation: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error starting conversation")
+			return
+		}
+
+		recipient, err := cfg.dbQueries.GetUserByID(req.Context(), recipientID)
+		if err != nil {
+			fmt.Printf("Error getting recipient: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error starting conversation")
+			return
+		}
+		allowed, err := cfg.canStartConversation(req.Context(), userID, recipient)
+		if err != nil {
+			fmt.Printf("Error checking DM permissions: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error starting conversation")
+			return
+		}
+		if !allowed {
+			respondWithError(res, http.StatusForbidden, "You can't message this user")
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error starting conversation")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		conversation, err = queries.CreateDirectConversation(req.Context(), directKey)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusConflict, "The conversation was just started, try again")
+				return
+			}
+			fmt.Printf("Error creating conversation: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error starting conversation")
+			return
+		}
+		for _, memberID := range []uuid.UUID{userID, recipientID} {
+			err = queries.AddConversationMember(req.Context(), database.AddConversationMemberParams{ConversationID: conversation.ID, UserID: memberID})
+			if err != nil {
+				fmt.Printf("Error adding conversation member: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error starting conversation")
+				return
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing conversation: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error starting conversation")
+			return
+		}
+
+		cfg.respondWithConversation(res, req, userID, http.StatusCreated, conversation)
+	}
+}
+
+// getConversationsHandler lists the user's conversations, the most
+// recently active first.
+func getConversationsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		conversations, err := cfg.dbQueries.GetConversationsForUser(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting conversations: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting conversations")
+			return
+		}
+
+		response, err := cfg.toConversations(req.Context(), userID, conversations)
+		if err != nil {
+			fmt.Printf("Error getting conversations data: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting conversations")
+			return
+		}
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+// sendMessageHandler posts a message to a conversation. Direct messages
+// are rejected once either user blocked the other or the recipient's DM
+// privacy setting no longer allows the sender.
+func sendMessageHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody MessageRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, body field expected")
+			return
+		}
+		body := chirptext.Normalize(strings.TrimSpace(reqBody.Body))
+		if err := chirptext.CheckSize(body); err != nil {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Message is too large, %v", err))
+			return
+		}
+		if body == "" || chirptext.Length(body) > maxMessageLength {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Message must have between 1 and %d characters", maxMessageLength))
+			return
+		}
+
+		conversation, ok := cfg.getMemberConversation(res, req, userID)
+		if !ok {
+			return
+		}
+
+		if conversation.Kind == conversationKindDirect {
+			members, err := cfg.dbQueries.GetConversationMembers(req.Context(), []uuid.UUID{conversation.ID})
+			if err != nil {
+				fmt.Printf("Error getting conversation members: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error sending message")
+				return
+			}
+			for _, member := range members {
+				if member.UserID == userID {
+					continue
+				}
+				recipient, err := cfg.dbQueries.GetUserByID(req.Context(), member.UserID)
+				if err != nil {
+					fmt.Printf("Error getting recipient: %v\n", err)
+					respondWithError(res, http.StatusInternalServerError, "Error sending message")
+					return
+				}
+				allowed, err := cfg.canStartConversation(req.Context(), userID, recipient)
+				if err != nil {
+					fmt.Printf("Error checking DM permissions: %v\n", err)
+					respondWithError(res, http.StatusInternalServerError, "Error sending message")
+					return
+				}
+				if !allowed {
+					respondWithError(res, http.StatusForbidden, "You can't message this user")
+					return
+				}
+			}
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error sending message")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		message, err := queries.CreateMessage(req.Context(), database.CreateMessageParams{
+			ConversationID: conversation.ID,
+			SenderID:       uuid.NullUUID{UUID: userID, Valid: true},
+			Body:           body,
+		})
+		if err != nil {
+			fmt.Printf("Error creating message: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error sending message")
+			return
+		}
+		err = queries.TouchConversation(req.Context(), conversation.ID)
+		if err != nil {
+			fmt.Printf("Error updating conversation: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error sending message")
+			return
+		}
+		err = queries.MarkConversationRead(req.Context(), database.MarkConversationReadParams{ConversationID: conversation.ID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error marking conversation read: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error sending message")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing message: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error sending message")
+			return
+		}
+		cfg.publishMessage(req.Context(), message)
+
+		respondWithJSON(res, http.StatusCreated, toMessage(message))
+	}
+}
+
+// getMessagesHandler pages through a conversation history, newest first.
+func getMessagesHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		query := req.URL.Query()
+
+		limit, err := pagination.ParseLimit(query.Get("limit"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid limit, it must be a positive number")
+			return
+		}
+
+		conversation, ok := cfg.getMemberConversation(res, req, userID)
+		if !ok {
+			return
+		}
+
+		params := database.GetMessagesParams{ConversationID: conversation.ID, PageLimit: int32(limit + 1)}
+		if rawCursor := query.Get("cursor"); rawCursor != "" {
+			cursor, err := pagination.DecodeCursor(rawCursor)
+			if err != nil {
+				respondWithError(res, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+			params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+
+		messages, err := cfg.dbQueries.GetMessages(req.Context(), params)
+		if err != nil {
+			fmt.Printf("Error getting messages: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting messages")
+			return
+		}
+
+		page := MessagesPage{Messages: []Message{}}
+		if len(messages) > limit {
+			messages = messages[:limit]
+			last := messages[len(messages)-1]
+			page.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+		}
+		for _, message := range messages {
+			page.Messages = append(page.Messages, toMessage(message))
+		}
+
+		respondWithJSON(res, http.StatusOK, page)
+	}
+}
+
+// markConversationReadHandler moves the user's read cursor to the given
+// message, or marks the whole conversation as read when there's none.
+// Cursors never move backwards.
+func markConversationReadHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody ReadRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil && err != io.EOF {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body")
+			return
+		}
+
+		conversation, ok := cfg.getMemberConversation(res, req, userID)
+		if !ok {
+			return
+		}
+
+		if reqBody.MessageID != nil {
+			message, err := cfg.dbQueries.GetMessage(req.Context(), database.GetMessageParams{ID: *reqBody.MessageID, ConversationID: conversation.ID})
+			if err != nil {
+				if err == sql.ErrNoRows {
+					respondWithError(res, http.StatusNotFound, "Message not found")
+					return
+				}
+				fmt.Printf("Error getting message: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error updating conversation")
+				return
+			}
+
+			err = cfg.dbQueries.MoveReadCursor(req.Context(), database.MoveReadCursorParams{ConversationID: conversation.ID, UserID: userID, ReadAt: message.CreatedAt})
+			if err != nil {
+				fmt.Printf("Error moving read cursor: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error updating conversation")
+				return
+			}
+			respondWithJSON(res, http.StatusNoContent, nil)
+			return
+		}
+
+		err = cfg.dbQueries.MarkConversationRead(req.Context(), database.MarkConversationReadParams{ConversationID: conversation.ID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error marking conversation read: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating conversation")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
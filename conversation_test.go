@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var (
+	conversationColumns       = []string{"id", "created_at", "updated_at", "direct_key", "kind", "name", "owner_id"}
+	conversationMemberColumns = []string{"conversation_id", "user_id", "joined_at", "last_read_at"}
+)
+
+func TestSendDirectMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		dmPrivacy  string
+		following  bool
+		blocked    bool
+		wantStatus int
+	}{
+		{name: "Recipient accepts messages from everyone", dmPrivacy: dmPrivacyEveryone, wantStatus: http.StatusCreated},
+		{name: "Recipient follows the sender", dmPrivacy: dmPrivacyFollowing, following: true, wantStatus: http.StatusCreated},
+		{name: "Recipient no longer accepts messages", dmPrivacy: dmPrivacyNobody, wantStatus: http.StatusForbidden},
+		{name: "Recipient stopped following the sender", dmPrivacy: dmPrivacyFollowing, following: false, wantStatus: http.StatusForbidden},
+		{name: "Block between sender and recipient", dmPrivacy: dmPrivacyEveryone, blocked: true, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			sender := newTestUser("walt@example.com", unusablePasswordHash)
+			recipient := newTestUser("jesse@example.com", unusablePasswordHash)
+			recipient.DmPrivacy = tt.dmPrivacy
+			conversationID := uuid.New()
+			now := time.Now()
+			memberRows := func() *sqlmock.Rows {
+				return sqlmock.NewRows(conversationMemberColumns).
+					AddRow(conversationID.String(), sender.ID.String(), now, nil).
+					AddRow(conversationID.String(), recipient.ID.String(), now, nil)
+			}
+
+			expectQuery(mock, "GetConversationForMember").WithArgs(conversationID, sender.ID).
+				WillReturnRows(sqlmock.NewRows(conversationColumns).AddRow(conversationID.String(), now, now, "key", conversationKindDirect, nil, nil))
+			expectQuery(mock, "GetConversationMembers").WillReturnRows(memberRows())
+			expectQuery(mock, "GetUserByID").WithArgs(recipient.ID).WillReturnRows(userRows(recipient))
+			expectQuery(mock, "IsBlockedBetween").WithArgs(sender.ID, recipient.ID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.blocked))
+			if tt.dmPrivacy == dmPrivacyFollowing && !tt.blocked {
+				expectQuery(mock, "IsFollowing").WithArgs(recipient.ID, sender.ID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.following))
+			}
+			if tt.wantStatus == http.StatusCreated {
+				mock.ExpectBegin()
+				expectQuery(mock, "CreateMessage").WithArgs(conversationID, sender.ID, "hi").
+					WillReturnRows(sqlmock.NewRows(messageColumns).AddRow(uuid.New().String(), now, conversationID.String(), sender.ID.String(), "hi", nil, nil))
+				expectExec(mock, "TouchConversation").WithArgs(conversationID).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectExec(mock, "MarkConversationRead").WithArgs(conversationID, sender.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectQuery(mock, "GetConversationMembers").WillReturnRows(memberRows())
+				expectExec(mock, "NotifyRealtime").WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/conversations/"+conversationID.String()+"/messages", strings.NewReader(`{"body": "hi"}`))
+			req.SetPathValue("conversationID", conversationID.String())
+			rec := httptest.NewRecorder()
+			sendMessageHandler(cfg)(rec, req.WithContext(withUser(req.Context(), sender.ID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
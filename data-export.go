@@ -0,0 +1,423 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/export"
+)
+
+const (
+	exportStatusPending    = "pending"
+	exportStatusProcessing = "processing"
+	exportStatusReady      = "ready"
+	exportStatusFailed     = "failed"
+
+	// Exports stuck in processing longer than this are picked up again
+	exportStaleAfter = 15 * time.Minute
+	exportBatchSize  = 10
+)
+
+type DataExport struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	Status    string     `json:"status"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type ExportSession struct {
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+type ExportIdentity struct {
+	Issuer    string    `json:"issuer"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportList struct {
+	List
+	MemberIDs []uuid.UUID `json:"member_ids"`
+}
+
+type ExportBookmark struct {
+	ChirpID   uuid.UUID  `json:"chirp_id"`
+	FolderID  *uuid.UUID `json:"folder_id"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func toDataExport(target database.DataExport) DataExport {
+	dataExport := DataExport{
+		ID:        target.ID,
+		CreatedAt: target.CreatedAt,
+		Status:    target.Status,
+	}
+	if target.ExpiresAt.Valid {
+		dataExport.ExpiresAt = &target.ExpiresAt.Time
+	}
+	return dataExport
+}
+
+func nullTimeToPtr(target sql.NullTime) *time.Time {
+	if !target.Valid {
+		return nil
+	}
+	return &target.Time
+}
+
+func createDataExportHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		active, err := cfg.dbQueries.GetActiveDataExport(req.Context(), userID)
+		if err == nil {
+			respondWithJSON(res, http.StatusAccepted, toDataExport(active))
+			return
+		}
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting active export: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating export")
+			return
+		}
+
+		dataExport, err := cfg.dbQueries.CreateDataExport(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error creating export: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating export")
+			return
+		}
+
+		respondWithJSON(res, http.StatusAccepted, toDataExport(dataExport))
+	}
+}
+
+func getDataExportHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		exportID, err := uuid.Parse(req.PathValue("exportID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid export ID, it must be a UUID")
+			return
+		}
+
+		dataExport, err := cfg.dbQueries.GetDataExport(req.Context(), exportID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Export not found")
+				return
+			}
+			fmt.Printf("Error getting export: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting export")
+			return
+		}
+		if dataExport.UserID != userID {
+			respondWithError(res, http.StatusNotFound, "Export not found")
+			return
+		}
+
+		switch dataExport.Status {
+		case exportStatusPending, exportStatusProcessing:
+			respondWithJSON(res, http.StatusAccepted, toDataExport(dataExport))
+			return
+		case exportStatusFailed:
+			respondWithError(res, http.StatusGone, "Export failed, please request a new one")
+			return
+		case exportStatusReady:
+		default:
+			fmt.Printf("Unknown status of export %s: %s\n", dataExport.ID, dataExport.Status)
+			respondWithError(res, http.StatusInternalServerError, "Error getting export")
+			return
+		}
+
+		if time.Now().After(dataExport.ExpiresAt.Time) {
+			respondWithError(res, http.StatusGone, "Export expired, please request a new one")
+			return
+		}
+
+		// Archives are stored in the database so any replica can serve them
+		content, err := cfg.dbQueries.GetDataExportArchive(req.Context(), dataExport.ID)
+		if err != nil {
+			fmt.Printf("Error getting export archive: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting export")
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/zip")
+		res.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="chirpy-export-%s.zip"`, dataExport.ID))
+		http.ServeContent(res, req, "", dataExport.UpdatedAt, bytes.NewReader(content))
+	}
+}
+
+// exportEntries collects everything we store about the user.
+func (cfg *apiConfig) exportEntries(ctx context.Context, userID uuid.UUID) ([]export.Entry, error) {
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user: %w", err)
+	}
+
+	chirps, err := cfg.dbQueries.GetChirpsByAuthor(ctx, database.GetChirpsByAuthorParams{UserID: userID, ViewerID: uuid.NullUUID{UUID: userID, Valid: true}, OrderBy: "ASC"})
+	if err != nil {
+		return nil, fmt.Errorf("error getting chirps: %w", err)
+	}
+	exportChirps := make([]Chirp, len(chirps))
+	for i, chirp := range chirps {
+		exportChirps[i] = toChirp(chirp)
+	}
+
+	refreshTokens, err := cfg.dbQueries.GetUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting sessions: %w", err)
+	}
+	sessions := make([]ExportSession, len(refreshTokens))
+	for i, refreshToken := range refreshTokens {
+		sessions[i] = ExportSession{
+			CreatedAt: refreshToken.CreatedAt,
+			ExpiresAt: refreshToken.ExpiresAt,
+			RevokedAt: nullTimeToPtr(refreshToken.RevokedAt),
+		}
+	}
+
+	userIdentities, err := cfg.dbQueries.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting identities: %w", err)
+	}
+	identities := make([]ExportIdentity, len(userIdentities))
+	for i, identity := range userIdentities {
+		identities[i] = ExportIdentity{
+			Issuer:    identity.Issuer,
+			Subject:   identity.Subject,
+			Email:     identity.Email,
+			CreatedAt: identity.CreatedAt,
+		}
+	}
+
+	userDrafts, err := cfg.dbQueries.GetDrafts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting drafts: %w", err)
+	}
+	drafts := make([]Draft, len(userDrafts))
+	for i, draft := range userDrafts {
+		drafts[i] = toDraft(draft)
+	}
+
+	userBookmarks, err := cfg.dbQueries.GetUserBookmarks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bookmarks: %w", err)
+	}
+	bookmarks := make([]ExportBookmark, len(userBookmarks))
+	for i, bookmark := range userBookmarks {
+		bookmarks[i] = ExportBookmark{
+			ChirpID:   bookmark.ChirpID,
+			FolderID:  nullUUIDToPtr(bookmark.FolderID),
+			CreatedAt: bookmark.CreatedAt,
+		}
+	}
+
+	userFolders, err := cfg.dbQueries.GetBookmarkFolders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting bookmark folders: %w", err)
+	}
+	folders := make([]BookmarkFolder, len(userFolders))
+	for i, folder := range userFolders {
+		folders[i] = toBookmarkFolder(folder)
+	}
+
+	userPollVotes, err := cfg.dbQueries.GetAllUserPollVotes(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting poll votes: %w", err)
+	}
+	pollVotes := make([]ExportPollVote, len(userPollVotes))
+	for i, vote := range userPollVotes {
+		pollVotes[i] = ExportPollVote{ChirpID: vote.ChirpID, OptionID: vote.OptionID, CreatedAt: vote.CreatedAt}
+	}
+
+	userFollows, err := cfg.dbQueries.GetFollowing(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting follows: %w", err)
+	}
+	following := make([]ExportFollow, len(userFollows))
+	for i, follow := range userFollows {
+		following[i] = ExportFollow{UserID: follow.FolloweeID, CreatedAt: follow.CreatedAt}
+	}
+
+	userBlocks, err := cfg.dbQueries.GetBlockedUsers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting blocks: %w", err)
+	}
+	blocks := make([]RelatedUser, len(userBlocks))
+	for i, block := range userBlocks {
+		blocks[i] = RelatedUser{UserID: block.BlockedID, CreatedAt: block.CreatedAt}
+	}
+
+	userMutes, err := cfg.dbQueries.GetMutedUsers(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting mutes: %w", err)
+	}
+	mutes := make([]RelatedUser, len(userMutes))
+	for i, mute := range userMutes {
+		mutes[i] = RelatedUser{UserID: mute.MutedID, CreatedAt: mute.CreatedAt}
+	}
+
+	userMessages, err := cfg.dbQueries.GetMessagesBySender(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("error getting messages: %w", err)
+	}
+	messages := make([]Message, len(userMessages))
+	for i, message := range userMessages {
+		messages[i] = toMessage(message)
+	}
+
+	userFilters, err := cfg.dbQueries.GetMuteFilters(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting mute filters: %w", err)
+	}
+	filters := make([]MuteFilter, len(userFilters))
+	for i, muteFilter := range userFilters {
+		filters[i] = toMuteFilter(muteFilter)
+	}
+
+	userReactions, err := cfg.dbQueries.GetUserReactions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting reactions: %w", err)
+	}
+	reactions := make([]Reaction, len(userReactions))
+	for i, userReaction := range userReactions {
+		reactions[i] = toReaction(userReaction)
+	}
+
+	userLists, err := cfg.dbQueries.GetListsByOwner(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting lists: %w", err)
+	}
+	ownedLists, err := cfg.toLists(ctx, userLists)
+	if err != nil {
+		return nil, err
+	}
+	lists := make([]ExportList, len(ownedLists))
+	for i, list := range ownedLists {
+		members, err := cfg.dbQueries.GetListMembers(ctx, list.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting list members: %w", err)
+		}
+		lists[i] = ExportList{List: list, MemberIDs: make([]uuid.UUID, len(members))}
+		for j, member := range members {
+			lists[i].MemberIDs[j] = member.UserID
+		}
+	}
+
+	userSubscriptions, err := cfg.dbQueries.GetSubscribedLists(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting list subscriptions: %w", err)
+	}
+	subscriptions, err := cfg.toLists(ctx, userSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return []export.Entry{
+		{Name: "profile.json", Data: ToResponseUser(user)},
+		{Name: "chirps.json", Data: exportChirps},
+		{Name: "drafts.json", Data: drafts},
+		{Name: "bookmarks.json", Data: bookmarks},
+		{Name: "bookmark_folders.json", Data: folders},
+		{Name: "poll_votes.json", Data: pollVotes},
+		{Name: "reactions.json", Data: reactions},
+		{Name: "following.json", Data: following},
+		{Name: "blocks.json", Data: blocks},
+		{Name: "mutes.json", Data: mutes},
+		{Name: "filters.json", Data: filters},
+		{Name: "messages.json", Data: messages},
+		{Name: "lists.json", Data: lists},
+		{Name: "list_subscriptions.json", Data: subscriptions},
+		{Name: "sessions.json", Data: sessions},
+		{Name: "identities.json", Data: identities},
+	}, nil
+}
+
+// processDataExports builds the archives of pending exports. Exports are
+// claimed with SKIP LOCKED so each one is built by a single replica.
+func (cfg *apiConfig) processDataExports(ctx context.Context) error {
+	for range exportBatchSize {
+		dataExport, err := cfg.dbQueries.ClaimDataExport(ctx, time.Now().Add(-exportStaleAfter))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				break
+			}
+			return fmt.Errorf("error claiming export: %w", err)
+		}
+
+		archive, err := cfg.buildDataExport(ctx, dataExport)
+		if err != nil {
+			fmt.Printf("Error building export %s: %v\n", dataExport.ID, err)
+			err = cfg.dbQueries.FailDataExport(ctx, dataExport.ID)
+			if err != nil {
+				fmt.Printf("Error marking export %s as failed: %v\n", dataExport.ID, err)
+			}
+			continue
+		}
+
+		// The export stays in processing and is claimed again once stale
+		err = cfg.completeDataExport(ctx, dataExport, archive)
+		if err != nil {
+			fmt.Printf("Error completing export %s: %v\n", dataExport.ID, err)
+		}
+	}
+
+	return cfg.deleteExpiredDataExports(ctx)
+}
+
+func (cfg *apiConfig) buildDataExport(ctx context.Context, dataExport database.DataExport) ([]byte, error) {
+	entries, err := cfg.exportEntries(ctx, dataExport.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	err = export.WriteArchive(&archive, entries, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
+
+// completeDataExport stores the archive and marks the export as ready. A
+// worker that reclaimed a stale export may have stored it already, the
+// first archive is kept.
+func (cfg *apiConfig) completeDataExport(ctx context.Context, dataExport database.DataExport, archive []byte) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	err = queries.CreateDataExportArchive(ctx, database.CreateDataExportArchiveParams{ExportID: dataExport.ID, Content: archive})
+	if err != nil {
+		return err
+	}
+	err = queries.CompleteDataExport(ctx, database.CompleteDataExportParams{
+		ID:        dataExport.ID,
+		ExpiresAt: sql.NullTime{Time: time.Now().Add(cfg.exportTTL), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (cfg *apiConfig) deleteExpiredDataExports(ctx context.Context) error {
+	err := cfg.dbQueries.DeleteExpiredDataExports(ctx, time.Now().Add(-cfg.exportTTL))
+	if err != nil {
+		return fmt.Errorf("error deleting expired exports: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var dataExportColumns = []string{"id", "created_at", "updated_at", "user_id", "status", "expires_at"}
+
+func TestGetDataExport(t *testing.T) {
+	newExportRequest := func(userID, exportID uuid.UUID) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me/export/"+exportID.String(), nil)
+		req.SetPathValue("exportID", exportID.String())
+		return req.WithContext(withUser(req.Context(), userID))
+	}
+
+	tests := []struct {
+		name       string
+		status     string
+		expiresAt  any
+		archive    []byte
+		wantStatus int
+	}{
+		{name: "Ready export is served from the database", status: exportStatusReady, expiresAt: time.Now().Add(time.Hour), archive: []byte("PK archive"), wantStatus: http.StatusOK},
+		{name: "Pending export", status: exportStatusPending, wantStatus: http.StatusAccepted},
+		{name: "Failed export", status: exportStatusFailed, wantStatus: http.StatusGone},
+		{name: "Expired export", status: exportStatusReady, expiresAt: time.Now().Add(-time.Hour), wantStatus: http.StatusGone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			exportID := uuid.New()
+			now := time.Now()
+
+			expectQuery(mock, "GetDataExport").WithArgs(exportID).
+				WillReturnRows(sqlmock.NewRows(dataExportColumns).AddRow(exportID.String(), now, now, userID.String(), tt.status, tt.expiresAt))
+			if tt.archive != nil {
+				expectQuery(mock, "GetDataExportArchive").WithArgs(exportID).
+					WillReturnRows(sqlmock.NewRows([]string{"content"}).AddRow(tt.archive))
+			}
+
+			rec := httptest.NewRecorder()
+			getDataExportHandler(cfg)(rec, newExportRequest(userID, exportID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			if tt.archive != nil && rec.Body.String() != string(tt.archive) {
+				t.Errorf("Expected the archive, got %q", rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
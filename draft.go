@@ -0,0 +1,232 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+// Drafts are not validated as chirps, this only keeps them from being
+// used as free storage
+const maxDraftLength = 4096
+
+type Draft struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string    `json:"body"`
+}
+
+type DraftRequest struct {
+	Body string `json:"body"`
+}
+
+func toDraft(target database.Draft) Draft {
+	return Draft{
+		ID:        target.ID,
+		CreatedAt: target.CreatedAt,
+		UpdatedAt: target.UpdatedAt,
+		Body:      target.Body,
+	}
+}
+
+func decodeDraftRequest(res http.ResponseWriter, req *http.Request) (DraftRequest, bool) {
+	defer req.Body.Close()
+	var reqBody DraftRequest
+	err := json.NewDecoder(req.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Error decoding body, body field expected")
+		return reqBody, false
+	}
+	if len(reqBody.Body) > maxDraftLength {
+		respondWithError(res, http.StatusBadRequest, "Draft is too long")
+		return reqBody, false
+	}
+	return reqBody, true
+}
+
+func parseDraftID(res http.ResponseWriter, req *http.Request) (uuid.UUID, bool) {
+	draftID, err := uuid.Parse(req.PathValue("draftID"))
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Invalid draft ID, it must be a UUID")
+		return uuid.UUID{}, false
+	}
+	return draftID, true
+}
+
+func createDraftHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		reqBody, ok := decodeDraftRequest(res, req)
+		if !ok {
+			return
+		}
+
+		draft, err := cfg.dbQueries.CreateDraft(req.Context(), database.CreateDraftParams{UserID: userID, Body: reqBody.Body})
+		if err != nil {
+			fmt.Printf("Error creating draft: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating draft")
+			return
+		}
+
+		respondWithJSON(res, http.StatusCreated, toDraft(draft))
+	}
+}
+
+func getDraftsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		drafts, err := cfg.dbQueries.GetDrafts(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting drafts: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting drafts")
+			return
+		}
+
+		response := make([]Draft, len(drafts))
+		for i, draft := range drafts {
+			response[i] = toDraft(draft)
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+func getDraftHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		draftID, ok := parseDraftID(res, req)
+		if !ok {
+			return
+		}
+
+		draft, err := cfg.dbQueries.GetDraft(req.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Draft not found")
+				return
+			}
+			fmt.Printf("Error getting draft: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting draft")
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, toDraft(draft))
+	}
+}
+
+func updateDraftHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		draftID, ok := parseDraftID(res, req)
+		if !ok {
+			return
+		}
+		reqBody, ok := decodeDraftRequest(res, req)
+		if !ok {
+			return
+		}
+
+		draft, err := cfg.dbQueries.UpdateDraft(req.Context(), database.UpdateDraftParams{ID: draftID, UserID: userID, Body: reqBody.Body})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Draft not found")
+				return
+			}
+			fmt.Printf("Error updating draft: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating draft")
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, toDraft(draft))
+	}
+}
+
+func deleteDraftHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		draftID, ok := parseDraftID(res, req)
+		if !ok {
+			return
+		}
+
+		_, err := cfg.dbQueries.DeleteDraft(req.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Draft not found")
+				return
+			}
+			fmt.Printf("Error deleting draft: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error deleting draft")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+// publishDraftHandler validates the draft as a regular chirp and replaces
+// it with the chirp in a single transaction.
+func publishDraftHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		draftID, ok := parseDraftID(res, req)
+		if !ok {
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error publishing draft")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		draft, err := queries.DeleteDraft(req.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Draft not found")
+				return
+			}
+			fmt.Printf("Error deleting draft: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error publishing draft")
+			return
+		}
+
+		body, ok := cfg.validateChirpBody(res, req, userID, draft.Body)
+		if !ok {
+			return
+		}
+
+		chirp, err := queries.CreateChirp(req.Context(), database.CreateChirpParams{Body: sanitizeChirp(body), UserID: userID, Visibility: chirpVisibilityPublic})
+		if err != nil {
+			fmt.Printf("Error creating chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error publishing draft")
+			return
+		}
+
+		err = saveChirpLinks(req.Context(), queries, chirp.ID, chirp.Body)
+		if err != nil {
+			fmt.Printf("Error saving chirp links: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error publishing draft")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing draft publication: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error publishing draft")
+			return
+		}
+
+		cfg.respondWithChirp(res, req, http.StatusCreated, chirp)
+	}
+}
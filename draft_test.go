@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var draftColumns = []string{"id", "created_at", "updated_at", "user_id", "body"}
+
+func TestCreateDraftLength(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{name: "At the limit", body: strings.Repeat("a", maxDraftLength), wantStatus: http.StatusCreated},
+		{name: "Over the limit", body: strings.Repeat("a", maxDraftLength+1), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			now := time.Now()
+
+			if tt.wantStatus == http.StatusCreated {
+				expectQuery(mock, "CreateDraft").WithArgs(userID, tt.body).
+					WillReturnRows(sqlmock.NewRows(draftColumns).AddRow(uuid.New().String(), now, now, userID.String(), tt.body))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/drafts", strings.NewReader(`{"body": "`+tt.body+`"}`))
+			rec := httptest.NewRecorder()
+			createDraftHandler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
+
+func TestPublishDraft(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		draftErr   error
+		chirpErr   error
+		wantStatus int
+	}{
+		{name: "Published", body: "hello", wantStatus: http.StatusCreated},
+		// The draft is only deleted when the chirp is created
+		{name: "Too long for a chirp", body: strings.Repeat("a", 141), wantStatus: http.StatusBadRequest},
+		{name: "Chirp creation fails", body: "hello", chirpErr: errors.New("connection reset"), wantStatus: http.StatusInternalServerError},
+		{name: "Draft not found", draftErr: sql.ErrNoRows, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			cfg.chirpMaxLengthFree = 140
+			user := newTestUser("walt@example.com", unusablePasswordHash)
+			draftID := uuid.New()
+			chirpID := uuid.New()
+			now := time.Now()
+
+			mock.ExpectBegin()
+			deleteDraft := expectQuery(mock, "DeleteDraft").WithArgs(draftID, user.ID)
+			if tt.draftErr != nil {
+				deleteDraft.WillReturnError(tt.draftErr)
+			} else {
+				deleteDraft.WillReturnRows(sqlmock.NewRows(draftColumns).AddRow(draftID.String(), now, now, user.ID.String(), tt.body))
+				expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+			}
+			if tt.wantStatus == http.StatusCreated || tt.chirpErr != nil {
+				createChirp := expectQuery(mock, "CreateChirp")
+				if tt.chirpErr != nil {
+					createChirp.WillReturnError(tt.chirpErr)
+				} else {
+					createChirp.WillReturnRows(chirpRow(chirpID, user.ID, now, tt.body))
+				}
+			}
+			if tt.wantStatus == http.StatusCreated {
+				expectExec(mock, "DeleteChirpLinks").WithArgs(chirpID).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				expectChirpViewerData(mock, &user)
+			} else {
+				mock.ExpectRollback()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/drafts/"+draftID.String()+"/publish", nil)
+			req.SetPathValue("draftID", draftID.String())
+			rec := httptest.NewRecorder()
+			publishDraftHandler(cfg)(rec, req.WithContext(withUser(req.Context(), user.ID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
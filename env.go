@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/reaction"
+	"github.com/ivportilla/chirpy/internal/unfurl"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func getEnvInt(name string, fallback int) int {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		fmt.Printf("Invalid value for %s, using default %d: %v\n", name, fallback, err)
+		return fallback
+	}
+	return value
+}
+
+func getEnvDuration(name string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		fmt.Printf("Invalid value for %s, using default %s: %v\n", name, fallback, err)
+		return fallback
+	}
+	return value
+}
+
+func getEnvString(name string, fallback string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// passwordHasherFromEnv hashes new passwords with PASSWORD_HASHER (argon2id
+// or bcrypt) and keeps accepting hashes from the other algorithm so they can
+// be upgraded on login. Parameters below the sane minimums are an error.
+func passwordHasherFromEnv() (auth.PasswordHasher, error) {
+	argon2Params := auth.DefaultArgon2idParams
+	memory := getEnvInt("ARGON2_MEMORY_KIB", int(argon2Params.Memory))
+	iterations := getEnvInt("ARGON2_ITERATIONS", int(argon2Params.Iterations))
+	parallelism := getEnvInt("ARGON2_PARALLELISM", int(argon2Params.Parallelism))
+	if memory < 0 || memory > math.MaxUint32 || iterations < 0 || iterations > math.MaxUint32 || parallelism < 0 || parallelism > math.MaxUint8 {
+		return auth.PasswordHasher{}, fmt.Errorf("argon2 parameters out of range")
+	}
+	argon2Params.Memory = uint32(memory)
+	argon2Params.Iterations = uint32(iterations)
+	argon2Params.Parallelism = uint8(parallelism)
+	err := argon2Params.Validate()
+	if err != nil {
+		return auth.PasswordHasher{}, err
+	}
+	argon2Hasher := auth.Argon2idHasher{Params: argon2Params}
+
+	bcryptCost := getEnvInt("BCRYPT_COST", bcrypt.DefaultCost)
+	if bcryptCost < bcrypt.MinCost || bcryptCost > bcrypt.MaxCost {
+		return auth.PasswordHasher{}, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	bcryptHasher := auth.BcryptHasher{Cost: bcryptCost}
+
+	if os.Getenv("PASSWORD_HASHER") == "bcrypt" {
+		return auth.PasswordHasher{Preferred: bcryptHasher, Legacy: []auth.Hasher{argon2Hasher}}, nil
+	}
+	return auth.PasswordHasher{Preferred: argon2Hasher, Legacy: []auth.Hasher{bcryptHasher}}, nil
+}
+
+func linkFetcherFromEnv() *unfurl.Fetcher {
+	fetcherConfig := unfurl.DefaultConfig
+	fetcherConfig.Timeout = getEnvDuration("LINK_PREVIEW_TIMEOUT", fetcherConfig.Timeout)
+	fetcherConfig.MaxBytes = int64(getEnvInt("LINK_PREVIEW_MAX_BYTES", int(fetcherConfig.MaxBytes)))
+	return unfurl.NewFetcher(fetcherConfig)
+}
+
+// reactionEmojisFromEnv reads the comma separated REACTION_EMOJIS, falling
+// back to the default set when it is missing or invalid.
+func reactionEmojisFromEnv() reaction.Set {
+	raw := os.Getenv("REACTION_EMOJIS")
+	if raw == "" {
+		return reaction.DefaultEmojis
+	}
+	set, err := reaction.ParseSet(raw)
+	if err != nil {
+		fmt.Printf("Invalid value for REACTION_EMOJIS, using the default set: %v\n", err)
+		return reaction.DefaultEmojis
+	}
+	return set
+}
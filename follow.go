@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+type ExportFollow struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func followUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		followeeID, ok := cfg.parseTargetUser(res, req, userID)
+		if !ok {
+			return
+		}
+
+		blocked, err := cfg.dbQueries.IsBlocked(req.Context(), database.IsBlockedParams{BlockerID: followeeID, BlockedID: userID})
+		if err != nil {
+			fmt.Printf("Error checking block: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error following user")
+			return
+		}
+		if blocked {
+			respondWithError(res, http.StatusForbidden, "You can't follow this user")
+			return
+		}
+
+		followed, err := cfg.dbQueries.FollowUser(req.Context(), database.FollowUserParams{FollowerID: userID, FolloweeID: followeeID})
+		if err != nil {
+			fmt.Printf("Error following user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error following user")
+			return
+		}
+		if followed > 0 {
+			cfg.publish(req.Context(), []uuid.UUID{followeeID}, eventNotification, Notification{Kind: "follow", UserID: userID})
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func unfollowUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		followeeID, err := uuid.Parse(req.PathValue("userID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+			return
+		}
+
+		err = cfg.dbQueries.UnfollowUser(req.Context(), database.UnfollowUserParams{FollowerID: userID, FolloweeID: followeeID})
+		if err != nil {
+			fmt.Printf("Error unfollowing user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error unfollowing user")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
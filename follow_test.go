@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestFollowUser(t *testing.T) {
+	tests := []struct {
+		name       string
+		blocked    bool
+		wantStatus int
+	}{
+		{name: "Follower blocked by the followee", blocked: true, wantStatus: http.StatusForbidden},
+		{name: "Follower not blocked", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			followee := newTestUser("jesse@example.com", unusablePasswordHash)
+
+			expectQuery(mock, "GetUserByID").WithArgs(followee.ID).WillReturnRows(userRows(followee))
+			expectQuery(mock, "IsBlocked").WithArgs(followee.ID, userID).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tt.blocked))
+			if !tt.blocked {
+				expectExec(mock, "FollowUser").WithArgs(userID, followee.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+				expectExec(mock, "NotifyRealtime").WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			rec := httptest.NewRecorder()
+			followUserHandler(cfg)(rec, newUserActionRequest(http.MethodPost, "/api/users/"+followee.ID.String()+"/follow", userID, followee.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/chirptext"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+const maxConversationNameLength = 50
+
+// System message events, the user_id of the message is the member the
+// event is about.
+const (
+	messageEventCreated       = "created"
+	messageEventRenamed       = "renamed"
+	messageEventMemberAdded   = "member_added"
+	messageEventMemberRemoved = "member_removed"
+	messageEventMemberLeft    = "member_left"
+)
+
+type GroupConversationRequest struct {
+	Name      string      `json:"name"`
+	MemberIDs []uuid.UUID `json:"member_ids"`
+}
+
+type ConversationNameRequest struct {
+	Name string `json:"name"`
+}
+
+type ConversationMemberRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func validateConversationName(res http.ResponseWriter, name string) (string, bool) {
+	name = chirptext.Normalize(strings.TrimSpace(name))
+	if name == "" || chirptext.CheckSize(name) != nil || chirptext.Length(name) > maxConversationNameLength {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Group name must have between 1 and %d characters", maxConversationNameLength))
+		return "", false
+	}
+	return name, true
+}
+
+// validateNewMember checks the user can be added to a group by userID,
+// using the same rules as starting a direct conversation. Blocks between
+// the new member and the rest of the group are checked by the callers.
+func (cfg *apiConfig) validateNewMember(res http.ResponseWriter, req *http.Request, userID, memberID uuid.UUID) bool {
+	member, err := cfg.dbQueries.GetUserByID(req.Context(), memberID)
+	if err != nil && err != sql.ErrNoRows {
+		fmt.Printf("Error getting user: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting user")
+		return false
+	}
+	if err == sql.ErrNoRows || member.DeletedAt.Valid {
+		respondWithError(res, http.StatusNotFound, fmt.Sprintf("User %s not found", memberID))
+		return false
+	}
+
+	allowed, err := cfg.canStartConversation(req.Context(), userID, member)
+	if err != nil {
+		fmt.Printf("Error checking DM permissions: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error adding member")
+		return false
+	}
+	if !allowed {
+		respondWithError(res, http.StatusForbidden, fmt.Sprintf("You can't add user %s to a conversation", memberID))
+		return false
+	}
+	return true
+}
+
+// getOwnedGroup gets the group conversation in the path, only its owner
+// can manage it.
+func (cfg *apiConfig) getOwnedGroup(res http.ResponseWriter, req *http.Request, userID uuid.UUID) (database.Conversation, bool) {
+	conversation, ok := cfg.getMemberConversation(res, req, userID)
+	if !ok {
+		return database.Conversation{}, false
+	}
+	if conversation.Kind != conversationKindGroup {
+		respondWithError(res, http.StatusBadRequest, "Only group conversations can be managed")
+		return database.Conversation{}, false
+	}
+	if !conversation.OwnerID.Valid || conversation.OwnerID.UUID != userID {
+		respondWithError(res, http.StatusForbidden, "Only the group owner can manage it")
+		return database.Conversation{}, false
+	}
+	return conversation, true
+}
+
+// lockOwnedGroup locks the group with GetConversationForUpdate and checks
+// again that userID owns it, ownership may have changed since getOwnedGroup.
+func lockOwnedGroup(res http.ResponseWriter, req *http.Request, queries *database.Queries, conversationID, userID uuid.UUID, errMessage string) (database.Conversation, bool) {
+	conversation, err := queries.GetConversationForUpdate(req.Context(), conversationID)
+	if err == sql.ErrNoRows {
+		respondWithError(res, http.StatusNotFound, "Conversation not found")
+		return database.Conversation{}, false
+	}
+	if err != nil {
+		fmt.Printf("Error locking conversation: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, errMessage)
+		return database.Conversation{}, false
+	}
+	if !conversation.OwnerID.Valid || conversation.OwnerID.UUID != userID {
+		respondWithError(res, http.StatusForbidden, "Only the group owner can manage it")
+		return database.Conversation{}, false
+	}
+	return conversation, true
+}
+
+func addSystemMessage(ctx context.Context, queries *database.Queries, conversationID, actorID uuid.UUID, event string, memberID uuid.NullUUID, body string) (database.Message, error) {
+	message, err := queries.CreateSystemMessage(ctx, database.CreateSystemMessageParams{
+		ConversationID: conversationID,
+		SenderID:       uuid.NullUUID{UUID: actorID, Valid: true},
+		Body:           body,
+		Event:          sql.NullString{String: event, Valid: true},
+		UserID:         memberID,
+	})
+	if err != nil {
+		return database.Message{}, fmt.Errorf("error creating system message: %w", err)
+	}
+	return message, queries.TouchConversation(ctx, conversationID)
+}
+
+// handOverGroup makes the longest standing member other than userID the
+// owner of the group when userID owns it, or deletes the group when nobody
+// else is left. It returns the remaining members. The conversation must be
+// locked with GetConversationForUpdate.
+func handOverGroup(ctx context.Context, queries *database.Queries, conversation database.Conversation, userID uuid.UUID) ([]database.ConversationMember, error) {
+	members, err := queries.GetConversationMembers(ctx, []uuid.UUID{conversation.ID})
+	if err != nil {
+		return nil, fmt.Errorf("error getting conversation members: %w", err)
+	}
+	remaining := []database.ConversationMember{}
+	for _, member := range members {
+		if member.UserID != userID {
+			remaining = append(remaining, member)
+		}
+	}
+
+	if len(remaining) == 0 {
+		return remaining, queries.DeleteConversation(ctx, conversation.ID)
+	}
+	if !conversation.OwnerID.Valid || conversation.OwnerID.UUID == userID {
+		err = queries.SetConversationOwner(ctx, database.SetConversationOwnerParams{ID: conversation.ID, OwnerID: uuid.NullUUID{UUID: remaining[0].UserID, Valid: true}})
+		if err != nil {
+			return nil, fmt.Errorf("error setting conversation owner: %w", err)
+		}
+	}
+	return remaining, nil
+}
+
+// createGroupConversationHandler creates a group owned by the user with
+// the given members, up to groupMaxMembers counting the owner.
+func createGroupConversationHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody GroupConversationRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, name and member_ids fields expected")
+			return
+		}
+		name, ok := validateConversationName(res, reqBody.Name)
+		if !ok {
+			return
+		}
+
+		memberIDs := []uuid.UUID{}
+		seen := map[uuid.UUID]bool{userID: true}
+		for _, memberID := range reqBody.MemberIDs {
+			if seen[memberID] {
+				continue
+			}
+			seen[memberID] = true
+			memberIDs = append(memberIDs, memberID)
+		}
+		if len(memberIDs) == 0 {
+			respondWithError(res, http.StatusBadRequest, "A group needs at least one member besides you")
+			return
+		}
+		if len(memberIDs)+1 > cfg.groupMaxMembers {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Groups can't have more than %d members", cfg.groupMaxMembers))
+			return
+		}
+		for _, memberID := range memberIDs {
+			if !cfg.validateNewMember(res, req, userID, memberID) {
+				return
+			}
+		}
+		blocked, err := cfg.dbQueries.IsBlockedWithin(req.Context(), memberIDs)
+		if err != nil {
+			fmt.Printf("Error checking blocks: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating group")
+			return
+		}
+		if blocked {
+			respondWithError(res, http.StatusForbidden, "Some of the members can't be in the same group")
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating group")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		conversation, err := queries.CreateGroupConversation(req.Context(), database.CreateGroupConversationParams{
+			Name:    sql.NullString{String: name, Valid: true},
+			OwnerID: uuid.NullUUID{UUID: userID, Valid: true},
+		})
+		if err != nil {
+			fmt.Printf("Error creating group: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating group")
+			return
+		}
+		for _, memberID := range append([]uuid.UUID{userID}, memberIDs...) {
+			err = queries.AddConversationMember(req.Context(), database.AddConversationMemberParams{ConversationID: conversation.ID, UserID: memberID})
+			if err != nil {
+				fmt.Printf("Error adding conversation member: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error creating group")
+				return
+			}
+		}
+		message, err := addSystemMessage(req.Context(), queries, conversation.ID, userID, messageEventCreated, uuid.NullUUID{}, name)
+		if err != nil {
+			fmt.Printf("Error adding system message: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating group")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing group: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating group")
+			return
+		}
+		cfg.publishMessage(req.Context(), message)
+
+		cfg.respondWithConversation(res, req, userID, http.StatusCreated, conversation)
+	}
+}
+
+func renameConversationHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody ConversationNameRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, name field expected")
+			return
+		}
+		name, ok := validateConversationName(res, reqBody.Name)
+		if !ok {
+			return
+		}
+
+		conversation, ok := cfg.getOwnedGroup(res, req, userID)
+		if !ok {
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error renaming group")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		_, ok = lockOwnedGroup(res, req, queries, conversation.ID, userID, "Error renaming group")
+		if !ok {
+			return
+		}
+		conversation, err = queries.RenameConversation(req.Context(), database.RenameConversationParams{ID: conversation.ID, Name: sql.NullString{String: name, Valid: true}})
+		if err != nil {
+			fmt.Printf("Error renaming group: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error renaming group")
+			return
+		}
+		message, err := addSystemMessage(req.Context(), queries, conversation.ID, userID, messageEventRenamed, uuid.NullUUID{}, name)
+		if err != nil {
+			fmt.Printf("Error adding system message: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error renaming group")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing rename: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error renaming group")
+			return
+		}
+		cfg.publishMessage(req.Context(), message)
+
+		cfg.respondWithConversation(res, req, userID, http.StatusOK, conversation)
+	}
+}
+
+// addConversationMemberHandler adds a member to a group. The conversation
+// row is locked while counting members so concurrent adds can't go over
+// the limit or race with a change of owner.
+func addConversationMemberHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody ConversationMemberRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, user_id field expected")
+			return
+		}
+
+		conversation, ok := cfg.getOwnedGroup(res, req, userID)
+		if !ok {
+			return
+		}
+		if !cfg.validateNewMember(res, req, userID, reqBody.UserID) {
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding member")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		conversation, ok = lockOwnedGroup(res, req, queries, conversation.ID, userID, "Error adding member")
+		if !ok {
+			return
+		}
+		_, err = queries.GetConversationForMember(req.Context(), database.GetConversationForMemberParams{ID: conversation.ID, UserID: reqBody.UserID})
+		if err == nil {
+			respondWithError(res, http.StatusConflict, "The user is already a member")
+			return
+		}
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error checking membership: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding member")
+			return
+		}
+		members, err := queries.GetConversationMembers(req.Context(), []uuid.UUID{conversation.ID})
+		if err != nil {
+			fmt.Printf("Error getting conversation members: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding member")
+			return
+		}
+		if len(members) >= cfg.groupMaxMembers {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Groups can't have more than %d members", cfg.groupMaxMembers))
+			return
+		}
+		memberIDs := make([]uuid.UUID, len(members))
+		for i, member := range members {
+			memberIDs[i] = member.UserID
+		}
+		blocked, err := queries.IsBlockedWithAny(req.Context(), database.IsBlockedWithAnyParams{UserID: reqBody.UserID, OtherUserIds: memberIDs})
+		if err != nil {
+			fmt.Printf("Error checking blocks: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding member")
+			return
+		}
+		if blocked {
+			respondWithError(res, http.StatusForbidden, fmt.Sprintf("You can't add user %s to this group", reqBody.UserID))
+			return
+		}
+
+		err = queries.AddConversationMember(req.Context(), database.AddConversationMemberParams{ConversationID: conversation.ID, UserID: reqBody.UserID})
+		if err != nil {
+			fmt.Printf("Error adding conversation member: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding member")
+			return
+		}
+		message, err := addSystemMessage(req.Context(), queries, conversation.ID, userID, messageEventMemberAdded, uuid.NullUUID{UUID: reqBody.UserID, Valid: true}, "")
+		if err != nil {
+			fmt.Printf("Error adding system message: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding member")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing member: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding member")
+			return
+		}
+		cfg.publishMessage(req.Context(), message)
+
+		cfg.respondWithConversation(res, req, userID, http.StatusOK, conversation)
+	}
+}
+
+// removeConversationMemberHandler removes a member from a group, owners
+// leave their groups with leaveConversationHandler instead.
+func removeConversationMemberHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		memberID, err := uuid.Parse(req.PathValue("userID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+			return
+		}
+		if memberID == userID {
+			respondWithError(res, http.StatusBadRequest, "Invalid user, leave the group instead")
+			return
+		}
+
+		conversation, ok := cfg.getOwnedGroup(res, req, userID)
+		if !ok {
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error removing member")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		conversation, ok = lockOwnedGroup(res, req, queries, conversation.ID, userID, "Error removing member")
+		if !ok {
+			return
+		}
+		removed, err := queries.RemoveConversationMember(req.Context(), database.RemoveConversationMemberParams{ConversationID: conversation.ID, UserID: memberID})
+		if err != nil {
+			fmt.Printf("Error removing conversation member: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error removing member")
+			return
+		}
+		if removed == 0 {
+			respondWithError(res, http.StatusNotFound, "Member not found")
+			return
+		}
+		message, err := addSystemMessage(req.Context(), queries, conversation.ID, userID, messageEventMemberRemoved, uuid.NullUUID{UUID: memberID, Valid: true}, "")
+		if err != nil {
+			fmt.Printf("Error adding system message: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error removing member")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing member removal: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error removing member")
+			return
+		}
+		cfg.publishMessage(req.Context(), message, memberID)
+
+		cfg.respondWithConversation(res, req, userID, http.StatusOK, conversation)
+	}
+}
+
+// leaveConversationHandler removes the user from a group. When the owner
+// leaves, or the owner's account was purged, ownership goes to the longest
+// standing member, and the group is deleted once nobody is left.
+func leaveConversationHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		conversation, ok := cfg.getMemberConversation(res, req, userID)
+		if !ok {
+			return
+		}
+		if conversation.Kind != conversationKindGroup {
+			respondWithError(res, http.StatusBadRequest, "Only group conversations can be left")
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error leaving group")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		conversation, err = queries.GetConversationForUpdate(req.Context(), conversation.ID)
+		if err != nil {
+			fmt.Printf("Error locking conversation: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error leaving group")
+			return
+		}
+		removed, err := queries.RemoveConversationMember(req.Context(), database.RemoveConversationMemberParams{ConversationID: conversation.ID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error removing conversation member: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error leaving group")
+			return
+		}
+		if removed == 0 {
+			respondWithError(res, http.StatusNotFound, "Conversation not found")
+			return
+		}
+
+		members, err := handOverGroup(req.Context(), queries, conversation, userID)
+		var message database.Message
+		if err == nil && len(members) > 0 {
+			message, err = addSystemMessage(req.Context(), queries, conversation.ID, userID, messageEventMemberLeft, uuid.NullUUID{UUID: userID, Valid: true}, "")
+		}
+		if err != nil {
+			fmt.Printf("Error leaving group: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error leaving group")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing leave: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error leaving group")
+			return
+		}
+		if len(members) > 0 {
+			cfg.publishMessage(req.Context(), message, userID)
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+func TestValidateConversationName(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "Name is trimmed", input: "  Breaking Bad  ", want: "Breaking Bad"},
+		{name: "Multibyte characters count once", input: strings.Repeat("ж", maxConversationNameLength), want: strings.Repeat("ж", maxConversationNameLength)},
+		{name: "Emoji count once", input: strings.Repeat("🧪", maxConversationNameLength), want: strings.Repeat("🧪", maxConversationNameLength)},
+		{name: "Too long", input: strings.Repeat("a", maxConversationNameLength+1), wantErr: true},
+		{name: "Empty", input: "   ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			got, ok := validateConversationName(rec, tt.input)
+			if ok == tt.wantErr {
+				t.Fatalf("validateConversationName() ok = %v, wantErr %v", ok, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("validateConversationName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManageGroupAfterOwnerChanged(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(*apiConfig) func(http.ResponseWriter, *http.Request)
+		method  string
+		body    string
+		// expect adds the queries run before the transaction
+		expect func(mock sqlmock.Sqlmock, member database.User)
+	}{
+		{name: "Rename", handler: renameConversationHandler, method: http.MethodPatch, body: `{"name": "Los Pollos"}`},
+		{name: "Add member", handler: addConversationMemberHandler, method: http.MethodPost, expect: func(mock sqlmock.Sqlmock, member database.User) {
+			expectQuery(mock, "GetUserByID").WithArgs(member.ID).WillReturnRows(userRows(member))
+			expectQuery(mock, "IsBlockedBetween").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		}},
+		{name: "Remove member", handler: removeConversationMemberHandler, method: http.MethodDelete},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			member := newTestUser("jesse@example.com", unusablePasswordHash)
+			member.DmPrivacy = dmPrivacyEveryone
+			conversationID := uuid.New()
+			now := time.Now()
+			body := tt.body
+			if body == "" {
+				body = `{"user_id": "` + member.ID.String() + `"}`
+			}
+
+			expectQuery(mock, "GetConversationForMember").WithArgs(conversationID, userID).
+				WillReturnRows(sqlmock.NewRows(conversationColumns).AddRow(conversationID.String(), now, now, nil, conversationKindGroup, "Group", userID.String()))
+			if tt.expect != nil {
+				tt.expect(mock, member)
+			}
+			// The owner left the group before the lock was taken
+			mock.ExpectBegin()
+			expectQuery(mock, "GetConversationForUpdate").WithArgs(conversationID).
+				WillReturnRows(sqlmock.NewRows(conversationColumns).AddRow(conversationID.String(), now, now, nil, conversationKindGroup, "Group", member.ID.String()))
+			mock.ExpectRollback()
+
+			req := httptest.NewRequest(tt.method, "/api/conversations/"+conversationID.String(), strings.NewReader(body))
+			req.SetPathValue("conversationID", conversationID.String())
+			req.SetPathValue("userID", member.ID.String())
+			rec := httptest.NewRecorder()
+			tt.handler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("Expected status %d, got %d %s", http.StatusForbidden, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
+
+func TestAddMemberBlockedByMember(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	cfg.groupMaxMembers = 10
+	userID := uuid.New()
+	blockerID := uuid.New()
+	member := newTestUser("jesse@example.com", unusablePasswordHash)
+	member.DmPrivacy = dmPrivacyEveryone
+	conversationID := uuid.New()
+	now := time.Now()
+	groupRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows(conversationColumns).AddRow(conversationID.String(), now, now, nil, conversationKindGroup, "Group", userID.String())
+	}
+
+	expectQuery(mock, "GetConversationForMember").WithArgs(conversationID, userID).WillReturnRows(groupRows())
+	expectQuery(mock, "GetUserByID").WithArgs(member.ID).WillReturnRows(userRows(member))
+	expectQuery(mock, "IsBlockedBetween").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectBegin()
+	expectQuery(mock, "GetConversationForUpdate").WithArgs(conversationID).WillReturnRows(groupRows())
+	expectQuery(mock, "GetConversationForMember").WithArgs(conversationID, member.ID).WillReturnRows(sqlmock.NewRows(conversationColumns))
+	expectQuery(mock, "GetConversationMembers").
+		WillReturnRows(sqlmock.NewRows(conversationMemberColumns).
+			AddRow(conversationID.String(), userID.String(), now, nil).
+			AddRow(conversationID.String(), blockerID.String(), now, nil))
+	expectQuery(mock, "IsBlockedWithAny").WithArgs(member.ID, sqlmock.AnyArg()).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/conversations/"+conversationID.String()+"/members", strings.NewReader(`{"user_id": "`+member.ID.String()+`"}`))
+	req.SetPathValue("conversationID", conversationID.String())
+	rec := httptest.NewRecorder()
+	addConversationMemberHandler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d, got %d %s", http.StatusForbidden, rec.Code, rec.Body)
+	}
+	expectMockDone(t, mock)
+}
+
+func TestCreateGroupWithMembersBlockingEachOther(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	cfg.groupMaxMembers = 10
+	userID := uuid.New()
+	walt := newTestUser("walt@example.com", unusablePasswordHash)
+	jesse := newTestUser("jesse@example.com", unusablePasswordHash)
+
+	for _, member := range []database.User{walt, jesse} {
+		member.DmPrivacy = dmPrivacyEveryone
+		expectQuery(mock, "GetUserByID").WithArgs(member.ID).WillReturnRows(userRows(member))
+		expectQuery(mock, "IsBlockedBetween").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	}
+	expectQuery(mock, "IsBlockedWithin").WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	body := `{"name": "Cooks", "member_ids": ["` + walt.ID.String() + `", "` + jesse.ID.String() + `"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/conversations", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	createGroupConversationHandler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d, got %d %s", http.StatusForbidden, rec.Code, rec.Body)
+	}
+	expectMockDone(t, mock)
+}
+
+func TestPurgeUserHandsOverGroups(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	user := newTestUser("walt@example.com", unusablePasswordHash)
+	user.DeletedAt = sql.NullTime{Time: time.Now().Add(-time.Hour), Valid: true}
+	memberID := uuid.New()
+	sharedGroupID := uuid.New()
+	emptyGroupID := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	expectQuery(mock, "LockUser").WithArgs(user.ID).WillReturnRows(userRows(user))
+	expectExec(mock, "CreateAccountDeletion").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectExec(mock, "ClearLoginThrottle").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectExec(mock, "ClearRateLimit").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectQuery(mock, "GetOwnedGroupsForUpdate").WithArgs(user.ID).
+		WillReturnRows(sqlmock.NewRows(conversationColumns).
+			AddRow(sharedGroupID.String(), now, now, nil, conversationKindGroup, "Shared", user.ID.String()).
+			AddRow(emptyGroupID.String(), now, now, nil, conversationKindGroup, "Empty", user.ID.String()))
+	expectQuery(mock, "GetConversationMembers").
+		WillReturnRows(sqlmock.NewRows(conversationMemberColumns).
+			AddRow(sharedGroupID.String(), user.ID.String(), now, nil).
+			AddRow(sharedGroupID.String(), memberID.String(), now, nil))
+	expectExec(mock, "SetConversationOwner").WithArgs(sharedGroupID, memberID).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectQuery(mock, "GetConversationMembers").
+		WillReturnRows(sqlmock.NewRows(conversationMemberColumns).
+			AddRow(emptyGroupID.String(), user.ID.String(), now, nil))
+	expectExec(mock, "DeleteConversation").WithArgs(emptyGroupID).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectExec(mock, "PurgeUser").WithArgs(user.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := cfg.purgeUser(context.Background(), user.ID, now)
+	if err != nil {
+		t.Fatalf("purgeUser() error = %v", err)
+	}
+	expectMockDone(t, mock)
+}
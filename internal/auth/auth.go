@@ -2,6 +2,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"net/http"
@@ -10,54 +11,70 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
 func HashPassword(password string) (string, error) {
-	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", fmt.Errorf("error hashing password")
-	}
-	return string(hashed), nil
+	return DefaultPasswordHasher.Hash(password)
 }
 
 func CheckPasswordHash(password, hash string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	return DefaultPasswordHasher.Check(password, hash)
+}
+
+// AccessClaims are what an access token proves. SessionID is the session
+// of the refresh token it was issued with, revoking the session must stop
+// the access token too.
+type AccessClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	ExpiresAt time.Time
+}
+
+type accessTokenClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
 }
 
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
+func MakeJWT(userID, sessionID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
 	now := time.Now()
 	expires := now.Add(expiresIn)
-	claims := jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(now),
-		ExpiresAt: jwt.NewNumericDate(expires),
-		Subject:   userID.String(),
+	claims := accessTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    "chirpy",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expires),
+			Subject:   userID.String(),
+		},
+		SessionID: sessionID.String(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(tokenSecret))
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	var claims jwt.RegisteredClaims
-	parsed, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
+// ValidateJWT checks the token and returns its claims, tokens issued
+// before sessions existed don't have one and are rejected.
+func ValidateJWT(tokenString, tokenSecret string) (AccessClaims, error) {
+	var claims accessTokenClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
 		return []byte(tokenSecret), nil
 	})
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("error parsing jwt: %w", err)
+		return AccessClaims{}, fmt.Errorf("error parsing jwt: %w", err)
 	}
 
-	userIdRaw, err := parsed.Claims.GetSubject()
+	userId, err := uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("error getting user id from token: %w", err)
+		return AccessClaims{}, fmt.Errorf("error converting user id to uuid: %w", err)
 	}
-
-	userId, err := uuid.Parse(userIdRaw)
+	sessionID, err := uuid.Parse(claims.SessionID)
 	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("error converting user id to uuid: %w", err)
+		return AccessClaims{}, fmt.Errorf("error converting session id to uuid: %w", err)
+	}
+	if claims.ExpiresAt == nil {
+		return AccessClaims{}, fmt.Errorf("token without expiration")
 	}
 
-	return userId, nil
+	return AccessClaims{UserID: userId, SessionID: sessionID, ExpiresAt: claims.ExpiresAt.Time}, nil
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -84,3 +101,10 @@ func GetAPIKey(headers http.Header) (string, error) {
 	}
 	return strings.TrimPrefix(authHeader, "ApiKey "), nil
 }
+
+// HashToken returns the SHA-256 digest of a high entropy token, so only the
+// digest needs to be stored.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
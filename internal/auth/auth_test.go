@@ -5,20 +5,21 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 func TestMakeJWT(t *testing.T) {
 	secret := "my_secret"
 	t.Run("Create a JWT token correctly", func(t *testing.T) {
-		_, err := MakeJWT(uuid.New(), secret, 0*time.Second)
+		_, err := MakeJWT(uuid.New(), uuid.New(), secret, 0*time.Second)
 		if err != nil {
 			t.Errorf("Unexpected error creating a valid token: %v", err)
 		}
 	})
 
 	t.Run("Parse a JWT token correctly", func(t *testing.T) {
-		token, _ := MakeJWT(uuid.New(), secret, 100*time.Second)
+		token, _ := MakeJWT(uuid.New(), uuid.New(), secret, 100*time.Second)
 		_, err := ValidateJWT(token, secret)
 		if err != nil {
 			t.Errorf("Unexpected error validating a valid token: %v", err)
@@ -34,16 +35,47 @@ func TestMakeJWT(t *testing.T) {
 
 	t.Run("Handle an expired JWT token correctly", func(t *testing.T) {
 		// Create expired token
-		token, _ := MakeJWT(uuid.New(), secret, -1*time.Second)
+		token, _ := MakeJWT(uuid.New(), uuid.New(), secret, -1*time.Second)
 		_, err := ValidateJWT(token, secret)
 		if err == nil {
 			t.Errorf("It should generate an error when used with an expired JWT token")
 		}
 	})
 
+	t.Run("Get the claims of a JWT token", func(t *testing.T) {
+		userID := uuid.New()
+		sessionID := uuid.New()
+		token, _ := MakeJWT(userID, sessionID, secret, 100*time.Second)
+		claims, err := ValidateJWT(token, secret)
+		if err != nil {
+			t.Errorf("Unexpected error validating a valid token: %v", err)
+		}
+		if claims.UserID != userID {
+			t.Errorf("Expected user %s, but got %s", userID, claims.UserID)
+		}
+		if claims.SessionID != sessionID {
+			t.Errorf("Expected session %s, but got %s", sessionID, claims.SessionID)
+		}
+		if until := time.Until(claims.ExpiresAt); until <= 90*time.Second || until > 100*time.Second {
+			t.Errorf("Expected the token to expire in 100 seconds, but it expires in %v", until)
+		}
+	})
+
+	t.Run("Reject a token without a session", func(t *testing.T) {
+		claims := jwt.RegisteredClaims{
+			Subject:   uuid.NewString(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(100 * time.Second)),
+		}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		_, err := ValidateJWT(token, secret)
+		if err == nil {
+			t.Errorf("It should generate an error when the token has no session")
+		}
+	})
+
 	t.Run("Reject a token signed with a different secret", func(t *testing.T) {
 		// Create expired token
-		token, _ := MakeJWT(uuid.New(), "another secret", 100*time.Second)
+		token, _ := MakeJWT(uuid.New(), uuid.New(), "another secret", 100*time.Second)
 		_, err := ValidateJWT(token, secret)
 		if err == nil {
 			t.Errorf("It should generate an error due to invalid secret used")
@@ -173,3 +205,17 @@ func TestCheckPasswordHash(t *testing.T) {
 		})
 	}
 }
+
+func TestHashToken(t *testing.T) {
+	token, _ := MakeRefreshToken()
+	if HashToken(token) != HashToken(token) {
+		t.Errorf("Hashing the same token should be deterministic")
+	}
+	if HashToken(token) == token {
+		t.Errorf("The hash should not be the token itself")
+	}
+	other, _ := MakeRefreshToken()
+	if HashToken(token) == HashToken(other) {
+		t.Errorf("Different tokens should have different hashes")
+	}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestValidateCSRFToken(t *testing.T) {
+	token, err := MakeCSRFToken()
+	if err != nil {
+		t.Fatalf("Unexpected error creating csrf token: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		header      string
+		cookieToken string
+		wantErr     bool
+	}{
+		{name: "Matching tokens", header: token, cookieToken: token, wantErr: false},
+		{name: "Missing header", header: "", cookieToken: token, wantErr: true},
+		{name: "Missing cookie", header: token, cookieToken: "", wantErr: true},
+		{name: "Different tokens", header: token, cookieToken: "another_token", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.header != "" {
+				header.Add(CSRFHeader, tt.header)
+			}
+			err := ValidateCSRFToken(header, tt.cookieToken)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateCSRFToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
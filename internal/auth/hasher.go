@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Hasher is a single password hashing algorithm. Hashes are self describing
+// so the algorithm and parameters used can be recovered from the hash.
+type Hasher interface {
+	// Identifies reports whether the hash was produced by this algorithm
+	Identifies(hash string) bool
+	Hash(password string) (string, error)
+	Compare(password, hash string) error
+	// NeedsRehash reports whether the hash uses weaker parameters than the ones configured
+	NeedsRehash(hash string) bool
+}
+
+// PasswordHasher hashes new passwords with the preferred algorithm while
+// still accepting hashes produced by any of the legacy ones.
+type PasswordHasher struct {
+	Preferred Hasher
+	Legacy    []Hasher
+}
+
+var DefaultPasswordHasher = PasswordHasher{
+	Preferred: Argon2idHasher{Params: DefaultArgon2idParams},
+	Legacy:    []Hasher{BcryptHasher{Cost: bcrypt.DefaultCost}},
+}
+
+func (h PasswordHasher) Hash(password string) (string, error) {
+	return h.Preferred.Hash(password)
+}
+
+func (h PasswordHasher) Check(password, hash string) error {
+	hasher := h.hasherFor(hash)
+	if hasher == nil {
+		return ErrUnknownHashFormat
+	}
+	return hasher.Compare(password, hash)
+}
+
+// NeedsRehash reports whether the hash should be replaced by a fresh one,
+// either because it was produced by a legacy algorithm or with weaker
+// parameters than the preferred ones.
+func (h PasswordHasher) NeedsRehash(hash string) bool {
+	if !h.Preferred.Identifies(hash) {
+		return true
+	}
+	return h.Preferred.NeedsRehash(hash)
+}
+
+func (h PasswordHasher) hasherFor(hash string) Hasher {
+	if h.Preferred.Identifies(hash) {
+		return h.Preferred
+	}
+	for _, hasher := range h.Legacy {
+		if hasher.Identifies(hash) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("error hashing password")
+	}
+	return string(hashed), nil
+}
+
+func (h BcryptHasher) Compare(password, hash string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+}
+
+func (h BcryptHasher) NeedsRehash(hash string) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost < h.Cost
+}
+
+type Argon2idParams struct {
+	// Memory in KiB
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// OWASP recommended minimum configuration for argon2id
+var DefaultArgon2idParams = Argon2idParams{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// MinArgon2idParams are the weakest parameters accepted from the
+// configuration, argon2 panics with no parallelism.
+var MinArgon2idParams = Argon2idParams{
+	Memory:      8 * 1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   16,
+}
+
+// Validate checks the parameters aren't below MinArgon2idParams.
+func (p Argon2idParams) Validate() error {
+	minimum := MinArgon2idParams
+	switch {
+	case p.Memory < minimum.Memory:
+		return fmt.Errorf("argon2 memory must be at least %d KiB", minimum.Memory)
+	case p.Iterations < minimum.Iterations:
+		return fmt.Errorf("argon2 iterations must be at least %d", minimum.Iterations)
+	case p.Parallelism < minimum.Parallelism:
+		return fmt.Errorf("argon2 parallelism must be at least %d", minimum.Parallelism)
+	case p.SaltLength < minimum.SaltLength:
+		return fmt.Errorf("argon2 salt length must be at least %d bytes", minimum.SaltLength)
+	case p.KeyLength < minimum.KeyLength:
+		return fmt.Errorf("argon2 key length must be at least %d bytes", minimum.KeyLength)
+	}
+	return nil
+}
+
+// Argon2idHasher encodes hashes in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+type Argon2idHasher struct {
+	Params Argon2idParams
+}
+
+const argon2idPrefix = "$argon2id$"
+
+func (h Argon2idHasher) Identifies(hash string) bool {
+	return strings.HasPrefix(hash, argon2idPrefix)
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.Params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", fmt.Errorf("error generating salt")
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Params.Iterations, h.Params.Memory, h.Params.Parallelism, h.Params.KeyLength)
+	return fmt.Sprintf(
+		"%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		h.Params.Memory,
+		h.Params.Iterations,
+		h.Params.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h Argon2idHasher) Compare(password, hash string) error {
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return err
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+func (h Argon2idHasher) NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+	return params.Memory < h.Params.Memory ||
+		params.Iterations < h.Params.Iterations ||
+		params.Parallelism < h.Params.Parallelism ||
+		params.SaltLength < h.Params.SaltLength ||
+		params.KeyLength < h.Params.KeyLength
+}
+
+func decodeArgon2idHash(hash string) (Argon2idParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idParams{}, nil, nil, ErrUnknownHashFormat
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("unsupported argon2 version: %s", parts[2])
+	}
+
+	var params Argon2idParams
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("error parsing argon2 parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("error decoding argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Argon2idParams{}, nil, nil, fmt.Errorf("error decoding argon2 hash: %w", err)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasherMixedHashes(t *testing.T) {
+	password := "correctPassword123!"
+	current := Argon2idParams{Memory: 8 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	weaker := Argon2idParams{Memory: 4 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	hasher := PasswordHasher{
+		Preferred: Argon2idHasher{Params: current},
+		Legacy:    []Hasher{BcryptHasher{Cost: bcrypt.MinCost + 1}},
+	}
+
+	mustHash := func(h Hasher) string {
+		hash, err := h.Hash(password)
+		if err != nil {
+			t.Fatalf("Unexpected error hashing password: %v", err)
+		}
+		return hash
+	}
+
+	// A database where users signed up under different configurations
+	tests := []struct {
+		name        string
+		hash        string
+		needsRehash bool
+	}{
+		{name: "Current argon2id hash", hash: mustHash(Argon2idHasher{Params: current}), needsRehash: false},
+		{name: "Under-cost argon2id hash", hash: mustHash(Argon2idHasher{Params: weaker}), needsRehash: true},
+		{name: "Legacy bcrypt hash", hash: mustHash(BcryptHasher{Cost: bcrypt.MinCost + 1}), needsRehash: true},
+		{name: "Under-cost legacy bcrypt hash", hash: mustHash(BcryptHasher{Cost: bcrypt.MinCost}), needsRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := hasher.Check(password, tt.hash); err != nil {
+				t.Errorf("Unexpected error checking a valid password: %v", err)
+			}
+			if err := hasher.Check("wrongPassword", tt.hash); err == nil {
+				t.Errorf("Expected an error checking a wrong password")
+			}
+			if got := hasher.NeedsRehash(tt.hash); got != tt.needsRehash {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.needsRehash)
+			}
+		})
+	}
+
+	t.Run("Rehashed password uses the preferred hasher", func(t *testing.T) {
+		legacy := mustHash(BcryptHasher{Cost: bcrypt.MinCost})
+		if !hasher.NeedsRehash(legacy) {
+			t.Fatalf("Expected legacy hash to need a rehash")
+		}
+		upgraded, err := hasher.Hash(password)
+		if err != nil {
+			t.Fatalf("Unexpected error rehashing: %v", err)
+		}
+		if hasher.NeedsRehash(upgraded) {
+			t.Errorf("Rehashed password should not need another rehash")
+		}
+		if err := hasher.Check(password, upgraded); err != nil {
+			t.Errorf("Unexpected error checking rehashed password: %v", err)
+		}
+	})
+
+	t.Run("Bcrypt preferred with under-cost bcrypt hash", func(t *testing.T) {
+		bcryptHasher := PasswordHasher{Preferred: BcryptHasher{Cost: bcrypt.MinCost + 1}}
+		if !bcryptHasher.NeedsRehash(mustHash(BcryptHasher{Cost: bcrypt.MinCost})) {
+			t.Errorf("Expected under-cost bcrypt hash to need a rehash")
+		}
+		if err := bcryptHasher.Check(password, mustHash(Argon2idHasher{Params: current})); err == nil {
+			t.Errorf("Expected an error for a hash format that is not configured")
+		}
+	})
+}
+
+func TestArgon2idHashFormat(t *testing.T) {
+	hash, err := Argon2idHasher{Params: DefaultArgon2idParams}.Hash("password")
+	if err != nil {
+		t.Fatalf("Unexpected error hashing password: %v", err)
+	}
+
+	params, salt, key, err := decodeArgon2idHash(hash)
+	if err != nil {
+		t.Fatalf("Unexpected error decoding %s: %v", hash, err)
+	}
+	if params != DefaultArgon2idParams {
+		t.Errorf("Expected params %+v, got %+v", DefaultArgon2idParams, params)
+	}
+	if len(salt) != int(DefaultArgon2idParams.SaltLength) || len(key) != int(DefaultArgon2idParams.KeyLength) {
+		t.Errorf("Unexpected salt or key length in %s", hash)
+	}
+
+	for _, invalid := range []string{"", "$argon2id$", "$argon2id$v=18$m=1,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=19$m=x$c2FsdA$a2V5"} {
+		if _, _, _, err := decodeArgon2idHash(invalid); err == nil {
+			t.Errorf("Expected an error decoding %q", invalid)
+		}
+	}
+}
+
+func TestArgon2idParamsValidate(t *testing.T) {
+	withParams := func(change func(*Argon2idParams)) Argon2idParams {
+		params := DefaultArgon2idParams
+		change(&params)
+		return params
+	}
+
+	tests := []struct {
+		name    string
+		params  Argon2idParams
+		wantErr bool
+	}{
+		{name: "Default parameters", params: DefaultArgon2idParams},
+		{name: "Minimum parameters", params: MinArgon2idParams},
+		{name: "Too little memory", params: withParams(func(p *Argon2idParams) { p.Memory = 1024 }), wantErr: true},
+		{name: "No iterations", params: withParams(func(p *Argon2idParams) { p.Iterations = 0 }), wantErr: true},
+		{name: "No parallelism", params: withParams(func(p *Argon2idParams) { p.Parallelism = 0 }), wantErr: true},
+		{name: "Short salt", params: withParams(func(p *Argon2idParams) { p.SaltLength = 8 }), wantErr: true},
+		{name: "Short key", params: withParams(func(p *Argon2idParams) { p.KeyLength = 8 }), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
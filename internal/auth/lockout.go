@@ -0,0 +1,42 @@
+package auth
+
+import "time"
+
+type LockoutPolicy struct {
+	// Failed attempts allowed before the first lockout kicks in
+	Threshold int
+	// Lockout applied when the threshold is reached, doubled on every further failure
+	BaseDelay time.Duration
+	// Upper bound for the lockout duration
+	MaxDelay time.Duration
+	// Failures older than this window are forgotten
+	Window time.Duration
+}
+
+var DefaultLockoutPolicy = LockoutPolicy{
+	Threshold: 5,
+	BaseDelay: 30 * time.Second,
+	MaxDelay:  15 * time.Minute,
+	Window:    time.Hour,
+}
+
+// LockoutFor returns how long a key must stay locked after the given number
+// of consecutive failed attempts, zero if it is still under the threshold.
+func (p LockoutPolicy) LockoutFor(failedAttempts int) time.Duration {
+	if failedAttempts < p.Threshold {
+		return 0
+	}
+
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failedAttempts; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutFor(t *testing.T) {
+	policy := LockoutPolicy{
+		Threshold: 3,
+		BaseDelay: 10 * time.Second,
+		MaxDelay:  time.Minute,
+		Window:    time.Hour,
+	}
+
+	tests := []struct {
+		name     string
+		attempts int
+		want     time.Duration
+	}{
+		{name: "No failures", attempts: 0, want: 0},
+		{name: "Under the threshold", attempts: 2, want: 0},
+		{name: "Reaching the threshold", attempts: 3, want: 10 * time.Second},
+		{name: "Backoff doubles", attempts: 4, want: 20 * time.Second},
+		{name: "Backoff doubles again", attempts: 5, want: 40 * time.Second},
+		{name: "Backoff is capped", attempts: 6, want: time.Minute},
+		{name: "Backoff stays capped", attempts: 100, want: time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.LockoutFor(tt.attempts)
+			if got != tt.want {
+				t.Errorf("LockoutFor(%d) = %v, want %v", tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
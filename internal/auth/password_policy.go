@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// bcrypt ignores everything after the first 72 bytes of a password
+const BcryptMaxPasswordBytes = 72
+
+const (
+	PasswordTooShort     = "too_short"
+	PasswordTooLong      = "too_long"
+	PasswordMatchesEmail = "matches_email"
+	PasswordBreached     = "breached"
+)
+
+type PasswordViolation struct {
+	Code    string
+	Message string
+}
+
+type PasswordPolicyError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return "invalid password: " + strings.Join(messages, ", ")
+}
+
+type PasswordPolicy struct {
+	MinLength int
+	// Maximum length in bytes, never above BcryptMaxPasswordBytes
+	MaxLength int
+	Breached  *BreachedPasswords
+}
+
+var DefaultPasswordPolicy = PasswordPolicy{
+	MinLength: 8,
+	MaxLength: BcryptMaxPasswordBytes,
+}
+
+// Validate checks a password against the policy, returning a
+// *PasswordPolicyError listing every violated rule.
+func (p PasswordPolicy) Validate(password, email string) error {
+	var violations []PasswordViolation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooShort,
+			Message: fmt.Sprintf("password must be at least %d characters long", p.MinLength),
+		})
+	}
+
+	maxLength := p.MaxLength
+	if maxLength <= 0 || maxLength > BcryptMaxPasswordBytes {
+		maxLength = BcryptMaxPasswordBytes
+	}
+	if len(password) > maxLength {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordTooLong,
+			Message: fmt.Sprintf("password must be at most %d bytes long", maxLength),
+		})
+	}
+
+	if matchesEmail(password, email) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordMatchesEmail,
+			Message: "password must not be your email",
+		})
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violations = append(violations, PasswordViolation{
+			Code:    PasswordBreached,
+			Message: "password appears in a list of breached passwords",
+		})
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func matchesEmail(password, email string) bool {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return false
+	}
+	if strings.EqualFold(password, email) {
+		return true
+	}
+	localPart, _, found := strings.Cut(email, "@")
+	return found && strings.EqualFold(password, localPart)
+}
+
+// BreachedPasswords holds SHA-1 hashes of known breached passwords indexed
+// by their 5 character prefix, the same k-anonymity layout used by the
+// Pwned Passwords range API.
+type BreachedPasswords struct {
+	ranges map[string]map[string]struct{}
+}
+
+// LoadBreachedPasswords reads a file with one upper or lower case SHA-1
+// hex digest per line, optionally followed by ":<count>".
+func LoadBreachedPasswords(path string) (*BreachedPasswords, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening breached passwords file: %w", err)
+	}
+	defer file.Close()
+
+	breached := &BreachedPasswords{ranges: map[string]map[string]struct{}{}}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		hash, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if len(hash) != sha1.Size*2 {
+			continue
+		}
+		breached.add(strings.ToUpper(hash))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading breached passwords file: %w", err)
+	}
+
+	return breached, nil
+}
+
+func (b *BreachedPasswords) add(hash string) {
+	prefix, suffix := hash[:5], hash[5:]
+	if b.ranges[prefix] == nil {
+		b.ranges[prefix] = map[string]struct{}{}
+	}
+	b.ranges[prefix][suffix] = struct{}{}
+}
+
+func (b *BreachedPasswords) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := b.ranges[hash[:5]][hash[5:]]
+	return found
+}
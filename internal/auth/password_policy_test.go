@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "breached.txt")
+	// SHA-1 of "password123" in uppercase with a count suffix, and of "password"
+	// in lowercase without one
+	content := "CBFDAC6008F9CAB4083784CBD1874F76618D2A97:250000\n" +
+		"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n"
+	if err := os.WriteFile(corpus, []byte(content), 0o600); err != nil {
+		t.Fatalf("Error writing corpus: %v", err)
+	}
+	breached, err := LoadBreachedPasswords(corpus)
+	if err != nil {
+		t.Fatalf("Unexpected error loading corpus: %v", err)
+	}
+
+	policy := PasswordPolicy{MinLength: 8, MaxLength: 100, Breached: breached}
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     []string
+	}{
+		{name: "Valid password", password: "correct horse battery", email: "a@b.com"},
+		{name: "Empty password", password: "", email: "a@b.com", want: []string{PasswordTooShort}},
+		{name: "Short multibyte password", password: "ñandú", email: "a@b.com", want: []string{PasswordTooShort}},
+		{name: "Over bcrypt limit", password: strings.Repeat("a", 73), email: "a@b.com", want: []string{PasswordTooLong}},
+		{name: "Same as email", password: "Walter@Example.com", email: "walter@example.com", want: []string{PasswordMatchesEmail}},
+		{name: "Same as email local part", password: "walterwhite", email: "walterwhite@example.com", want: []string{PasswordMatchesEmail}},
+		{name: "Breached password", password: "password123", email: "a@b.com", want: []string{PasswordBreached}},
+		{name: "Breached lowercase entry", password: "password", email: "a@b.com", want: []string{PasswordBreached}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, tt.email)
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+
+			var policyErr *PasswordPolicyError
+			if !errors.As(err, &policyErr) {
+				t.Fatalf("Expected a PasswordPolicyError, got %v", err)
+			}
+			if len(policyErr.Violations) != len(tt.want) {
+				t.Fatalf("Expected violations %v, got %v", tt.want, policyErr.Violations)
+			}
+			for i, code := range tt.want {
+				if policyErr.Violations[i].Code != code {
+					t.Errorf("Expected violation %s, got %s", code, policyErr.Violations[i].Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// SignValue appends an HMAC-SHA256 of the value, so values the server
+// hands out, like cookies, can be checked for tampering when they come back.
+func SignValue(value, secret string) string {
+	return value + "." + signature(value, secret)
+}
+
+// VerifySignedValue returns the value of a string built by SignValue with
+// the same secret.
+func VerifySignedValue(signed, secret string) (string, error) {
+	index := strings.LastIndex(signed, ".")
+	if index < 0 {
+		return "", fmt.Errorf("unsigned value")
+	}
+	value := signed[:index]
+	if !hmac.Equal([]byte(signed[index+1:]), []byte(signature(value, secret))) {
+		return "", fmt.Errorf("invalid signature")
+	}
+	return value, nil
+}
+
+func signature(value, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
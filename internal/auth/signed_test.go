@@ -0,0 +1,34 @@
+package auth
+
+import "testing"
+
+func TestVerifySignedValue(t *testing.T) {
+	secret := "my_secret"
+	signed := SignValue("state.nonce.verifier", secret)
+
+	tests := []struct {
+		name    string
+		signed  string
+		secret  string
+		want    string
+		wantErr bool
+	}{
+		{name: "Valid signature", signed: signed, secret: secret, want: "state.nonce.verifier"},
+		{name: "Empty value", signed: SignValue("", secret), secret: secret, want: ""},
+		{name: "Different secret", signed: signed, secret: "other_secret", wantErr: true},
+		{name: "Tampered value", signed: "state.nonce.other" + signed[len("state.nonce.verifier"):], secret: secret, wantErr: true},
+		{name: "Missing signature", signed: "state", secret: secret, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifySignedValue(tt.signed, tt.secret)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifySignedValue() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifySignedValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package chirptext
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+// URLLength is what every link counts towards the chirp length, no
+// matter how long it really is.
+const URLLength = 23
+
+// URLPattern matches the links weighted as URLLength. Clients counting
+// characters must use the same pattern.
+const URLPattern = `https?://[^\s]+`
+
+var urlRegexp = regexp.MustCompile(URLPattern)
+
+// Length weighs URLs and grapheme clusters regardless of their size, so
+// texts are also capped in bytes.
+const (
+	MaxURLBytes = 2048
+	MaxBytes    = 16 * 1024
+)
+
+var (
+	ErrURLTooLong = fmt.Errorf("links can't be longer than %d bytes", MaxURLBytes)
+	ErrTooLarge   = fmt.Errorf("text can't be larger than %d bytes", MaxBytes)
+)
+
+// CheckSize returns ErrTooLarge or ErrURLTooLong when the text or one of its
+// URLs is larger than Length can fairly count.
+func CheckSize(text string) error {
+	if len(text) > MaxBytes {
+		return ErrTooLarge
+	}
+	for _, url := range urlRegexp.FindAllString(text, -1) {
+		if len(url) > MaxURLBytes {
+			return ErrURLTooLong
+		}
+	}
+	return nil
+}
+
+// Normalize returns the NFC form of the text, chirps are stored normalized
+// so the same text always has the same length.
+func Normalize(text string) string {
+	return norm.NFC.String(text)
+}
+
+// Length counts the text in grapheme clusters, what users perceive as
+// characters, with every URL counted as URLLength. The text should be
+// normalized first.
+func Length(text string) int {
+	length := 0
+	last := 0
+	for _, match := range urlRegexp.FindAllStringIndex(text, -1) {
+		length += uniseg.GraphemeClusterCount(text[last:match[0]]) + URLLength
+		last = match[1]
+	}
+	return length + uniseg.GraphemeClusterCount(text[last:])
+}
+
+// URLs returns the distinct links in the text, in order of appearance.
+func URLs(text string) []string {
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, url := range urlRegexp.FindAllString(text, -1) {
+		if !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
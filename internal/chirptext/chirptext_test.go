@@ -0,0 +1,87 @@
+package chirptext
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "ASCII", text: "hello world", want: 11},
+		{name: "Empty", text: "", want: 0},
+		{name: "Cyrillic counts characters not bytes", text: strings.Repeat("ж", 70), want: 70},
+		{name: "Emoji", text: strings.Repeat("😀", 50), want: 50},
+		{name: "Emoji ZWJ sequence is one character", text: "👩‍👩‍👧‍👦", want: 1},
+		{name: "Flag is one character", text: "🇦🇷", want: 1},
+		{name: "Combining accent is one character", text: "e\u0301", want: 1},
+		{name: "URL has a fixed length", text: "https://example.com/a/very/long/path/that/goes/on/and/on", want: URLLength},
+		{name: "Text around URLs", text: "see http://a.io and https://b.io/x ok", want: 4 + URLLength + 5 + URLLength + 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Length(tt.text); got != tt.want {
+				t.Errorf("Length(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	decomposed := "cafe\u0301"
+	normalized := Normalize(decomposed)
+	if normalized != "caf\u00e9" {
+		t.Errorf("Normalize(%q) = %q, want NFC form", decomposed, normalized)
+	}
+	if Length(normalized) != Length(decomposed) {
+		t.Errorf("Normalization changed the length of %q", decomposed)
+	}
+}
+
+func TestURLs(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "No links", text: "just text", want: []string{}},
+		{name: "Links in order", text: "see https://b.io/x and http://a.io", want: []string{"https://b.io/x", "http://a.io"}},
+		{name: "Duplicated links", text: "https://a.io https://a.io", want: []string{"https://a.io"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := URLs(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("URLs(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	longURL := "https://example.com/" + strings.Repeat("a", MaxURLBytes)
+
+	tests := []struct {
+		name    string
+		text    string
+		wantErr error
+	}{
+		{name: "Short text", text: "hello https://example.com/path"},
+		{name: "URL at the limit", text: longURL[:MaxURLBytes]},
+		{name: "URL over the limit", text: "see " + longURL, wantErr: ErrURLTooLong},
+		{name: "One huge grapheme cluster", text: "e" + strings.Repeat("\u0301", MaxBytes), wantErr: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckSize(tt.text); err != tt.wantErr {
+				t.Errorf("CheckSize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: blocks.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const blockUser = `-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type BlockUserParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) BlockUser(ctx context.Context, arg BlockUserParams) error {
+	_, err := q.db.ExecContext(ctx, blockUser, arg.BlockerID, arg.BlockedID)
+	return err
+}
+
+const deleteFollowsBetween = `-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+    OR (follower_id = $2 AND followee_id = $1)
+`
+
+type DeleteFollowsBetweenParams struct {
+	UserID      uuid.UUID
+	OtherUserID uuid.UUID
+}
+
+func (q *Queries) DeleteFollowsBetween(ctx context.Context, arg DeleteFollowsBetweenParams) error {
+	_, err := q.db.ExecContext(ctx, deleteFollowsBetween, arg.UserID, arg.OtherUserID)
+	return err
+}
+
+const getBlockedUsers = `-- name: GetBlockedUsers :many
+SELECT blocker_id, blocked_id, created_at FROM blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]Block, error) {
+	rows, err := q.db.QueryContext(ctx, getBlockedUsers, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Block
+	for rows.Next() {
+		var i Block
+		if err := rows.Scan(&i.BlockerID, &i.BlockedID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMutedUsers = `-- name: GetMutedUsers :many
+SELECT muter_id, muted_id, created_at FROM mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC
+`
+
+func (q *Queries) GetMutedUsers(ctx context.Context, muterID uuid.UUID) ([]Mute, error) {
+	rows, err := q.db.QueryContext(ctx, getMutedUsers, muterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Mute
+	for rows.Next() {
+		var i Mute
+		if err := rows.Scan(&i.MuterID, &i.MutedID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getNotificationRecipients = `-- name: GetNotificationRecipients :many
+SELECT recipient_id::UUID FROM unnest($1::UUID[]) AS recipient_id
+WHERE NOT author_hidden_for($2, recipient_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE blocker_id = $2 AND blocked_id = recipient_id
+    )
+`
+
+type GetNotificationRecipientsParams struct {
+	RecipientIds []uuid.UUID
+	AuthorID     uuid.UUID
+}
+
+func (q *Queries) GetNotificationRecipients(ctx context.Context, arg GetNotificationRecipientsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getNotificationRecipients, pq.Array(arg.RecipientIds), arg.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var recipient_id uuid.UUID
+		if err := rows.Scan(&recipient_id); err != nil {
+			return nil, err
+		}
+		items = append(items, recipient_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isBlocked = `-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE blocker_id = $1 AND blocked_id = $2
+)
+`
+
+type IsBlockedParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) IsBlocked(ctx context.Context, arg IsBlockedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlocked, arg.BlockerID, arg.BlockedID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isBlockedBetween = `-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = $1 AND blocked_id = $2)
+        OR (blocker_id = $2 AND blocked_id = $1)
+)
+`
+
+type IsBlockedBetweenParams struct {
+	UserID      uuid.UUID
+	OtherUserID uuid.UUID
+}
+
+func (q *Queries) IsBlockedBetween(ctx context.Context, arg IsBlockedBetweenParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedBetween, arg.UserID, arg.OtherUserID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isBlockedWithAny = `-- name: IsBlockedWithAny :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = $1 AND blocked_id = ANY($2::UUID[]))
+        OR (blocked_id = $1 AND blocker_id = ANY($2::UUID[]))
+)
+`
+
+type IsBlockedWithAnyParams struct {
+	UserID       uuid.UUID
+	OtherUserIds []uuid.UUID
+}
+
+func (q *Queries) IsBlockedWithAny(ctx context.Context, arg IsBlockedWithAnyParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedWithAny, arg.UserID, pq.Array(arg.OtherUserIds))
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const isBlockedWithin = `-- name: IsBlockedWithin :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE blocker_id = ANY($1::UUID[]) AND blocked_id = ANY($1::UUID[])
+)
+`
+
+func (q *Queries) IsBlockedWithin(ctx context.Context, userIds []uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isBlockedWithin, pq.Array(userIds))
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const muteUser = `-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type MuteUserParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) MuteUser(ctx context.Context, arg MuteUserParams) error {
+	_, err := q.db.ExecContext(ctx, muteUser, arg.MuterID, arg.MutedID)
+	return err
+}
+
+const unblockUser = `-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2
+`
+
+type UnblockUserParams struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+}
+
+func (q *Queries) UnblockUser(ctx context.Context, arg UnblockUserParams) error {
+	_, err := q.db.ExecContext(ctx, unblockUser, arg.BlockerID, arg.BlockedID)
+	return err
+}
+
+const unmuteUser = `-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2
+`
+
+type UnmuteUserParams struct {
+	MuterID uuid.UUID
+	MutedID uuid.UUID
+}
+
+func (q *Queries) UnmuteUser(ctx context.Context, arg UnmuteUserParams) error {
+	_, err := q.db.ExecContext(ctx, unmuteUser, arg.MuterID, arg.MutedID)
+	return err
+}
@@ -0,0 +1,290 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: bookmarks.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createBookmark = `-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, folder_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, chirp_id) DO UPDATE
+SET folder_id = EXCLUDED.folder_id
+`
+
+type CreateBookmarkParams struct {
+	UserID   uuid.UUID
+	ChirpID  uuid.UUID
+	FolderID uuid.NullUUID
+}
+
+func (q *Queries) CreateBookmark(ctx context.Context, arg CreateBookmarkParams) error {
+	_, err := q.db.ExecContext(ctx, createBookmark, arg.UserID, arg.ChirpID, arg.FolderID)
+	return err
+}
+
+const createBookmarkFolder = `-- name: CreateBookmarkFolder :one
+INSERT INTO bookmark_folders (id, created_at, updated_at, user_id, name)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+ON CONFLICT (user_id, name) DO NOTHING
+RETURNING id, created_at, updated_at, user_id, name
+`
+
+type CreateBookmarkFolderParams struct {
+	UserID uuid.UUID
+	Name   string
+}
+
+func (q *Queries) CreateBookmarkFolder(ctx context.Context, arg CreateBookmarkFolderParams) (BookmarkFolder, error) {
+	row := q.db.QueryRowContext(ctx, createBookmarkFolder, arg.UserID, arg.Name)
+	var i BookmarkFolder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
+
+const deleteBookmark = `-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2
+`
+
+type DeleteBookmarkParams struct {
+	UserID  uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) DeleteBookmark(ctx context.Context, arg DeleteBookmarkParams) error {
+	_, err := q.db.ExecContext(ctx, deleteBookmark, arg.UserID, arg.ChirpID)
+	return err
+}
+
+const deleteBookmarkFolder = `-- name: DeleteBookmarkFolder :execrows
+DELETE FROM bookmark_folders
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteBookmarkFolderParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteBookmarkFolder(ctx context.Context, arg DeleteBookmarkFolderParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteBookmarkFolder, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getBookmarkFolder = `-- name: GetBookmarkFolder :one
+SELECT id, created_at, updated_at, user_id, name FROM bookmark_folders
+WHERE id = $1 AND user_id = $2
+`
+
+type GetBookmarkFolderParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetBookmarkFolder(ctx context.Context, arg GetBookmarkFolderParams) (BookmarkFolder, error) {
+	row := q.db.QueryRowContext(ctx, getBookmarkFolder, arg.ID, arg.UserID)
+	var i BookmarkFolder
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Name,
+	)
+	return i, err
+}
+
+const getBookmarkFolders = `-- name: GetBookmarkFolders :many
+SELECT id, created_at, updated_at, user_id, name FROM bookmark_folders
+WHERE user_id = $1
+ORDER BY name ASC
+`
+
+func (q *Queries) GetBookmarkFolders(ctx context.Context, userID uuid.UUID) ([]BookmarkFolder, error) {
+	rows, err := q.db.QueryContext(ctx, getBookmarkFolders, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BookmarkFolder
+	for rows.Next() {
+		var i BookmarkFolder
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Name,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBookmarkedChirpIDs = `-- name: GetBookmarkedChirpIDs :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id = $1 AND chirp_id = ANY($2::UUID[])
+`
+
+type GetBookmarkedChirpIDsParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+func (q *Queries) GetBookmarkedChirpIDs(ctx context.Context, arg GetBookmarkedChirpIDsParams) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getBookmarkedChirpIDs, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var chirp_id uuid.UUID
+		if err := rows.Scan(&chirp_id); err != nil {
+			return nil, err
+		}
+		items = append(items, chirp_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBookmarks = `-- name: GetBookmarks :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.status, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive, bookmarks.folder_id, bookmarks.created_at AS bookmarked_at FROM bookmarks
+INNER JOIN chirps ON chirps.id = bookmarks.chirp_id
+INNER JOIN users ON users.id = chirps.user_id
+WHERE bookmarks.user_id = $1
+    AND users.deleted_at IS NULL
+    AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $1)
+    AND ($2::UUID IS NULL OR bookmarks.folder_id = $2)
+    AND (
+        $3::TIMESTAMP IS NULL
+        OR (bookmarks.created_at, bookmarks.chirp_id) < ($3::TIMESTAMP, $4::UUID)
+    )
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT $5
+`
+
+type GetBookmarksParams struct {
+	UserID     uuid.UUID
+	FolderID   uuid.NullUUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	PageLimit  int32
+}
+
+type GetBookmarksRow struct {
+	Chirp        Chirp
+	FolderID     uuid.NullUUID
+	BookmarkedAt time.Time
+}
+
+func (q *Queries) GetBookmarks(ctx context.Context, arg GetBookmarksParams) ([]GetBookmarksRow, error) {
+	rows, err := q.db.QueryContext(ctx, getBookmarks,
+		arg.UserID,
+		arg.FolderID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetBookmarksRow
+	for rows.Next() {
+		var i GetBookmarksRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.EditedAt,
+			&i.Chirp.Status,
+			&i.Chirp.PublishAt,
+			&i.Chirp.Visibility,
+			&i.Chirp.ContentWarning,
+			&i.Chirp.Sensitive,
+			&i.FolderID,
+			&i.BookmarkedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserBookmarks = `-- name: GetUserBookmarks :many
+SELECT user_id, chirp_id, folder_id, created_at FROM bookmarks
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetUserBookmarks(ctx context.Context, userID uuid.UUID) ([]Bookmark, error) {
+	rows, err := q.db.QueryContext(ctx, getUserBookmarks, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Bookmark
+	for rows.Next() {
+		var i Bookmark
+		if err := rows.Scan(
+			&i.UserID,
+			&i.ChirpID,
+			&i.FolderID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
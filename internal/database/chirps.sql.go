@@ -7,25 +7,95 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, visibility, content_warning, sensitive)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	Visibility     string
+	ContentWarning sql.NullString
+	Sensitive      bool
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.Visibility,
+		arg.ContentWarning,
+		arg.Sensitive,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const createChirpMention = `-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+type CreateChirpMentionParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) CreateChirpMention(ctx context.Context, arg CreateChirpMentionParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpMention, arg.ChirpID, arg.UserID)
+	return err
+}
+
+const createScheduledChirp = `-- name: CreateScheduledChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, status, publish_at, visibility, content_warning, sensitive)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, 'scheduled', $3, $4, $5, $6
+)
+RETURNING id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive
+`
+
+type CreateScheduledChirpParams struct {
+	Body           string
+	UserID         uuid.UUID
+	PublishAt      sql.NullTime
+	Visibility     string
+	ContentWarning sql.NullString
+	Sensitive      bool
+}
+
+func (q *Queries) CreateScheduledChirp(ctx context.Context, arg CreateScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createScheduledChirp,
+		arg.Body,
+		arg.UserID,
+		arg.PublishAt,
+		arg.Visibility,
+		arg.ContentWarning,
+		arg.Sensitive,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -33,6 +103,12 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
@@ -47,13 +123,38 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteScheduledChirp = `-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id = $1 AND user_id = $2 AND status = 'scheduled'
+`
+
+type DeleteScheduledChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteScheduledChirp(ctx context.Context, arg DeleteScheduledChirpParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteScheduledChirp, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE id = $1
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.status, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $2)
 `
 
-func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirp, id)
+type GetChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirp(ctx context.Context, arg GetChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirp, arg.ID, arg.ViewerID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -61,19 +162,94 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
 	)
 	return i, err
 }
 
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.status, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = $1 AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $2)
+FOR UPDATE OF chirps
+`
+
+type GetChirpForUpdateParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, arg GetChirpForUpdateParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const getChirpMentions = `-- name: GetChirpMentions :many
+SELECT chirp_id, user_id FROM chirp_mentions
+WHERE chirp_id = ANY($1::UUID[])
+`
+
+func (q *Queries) GetChirpMentions(ctx context.Context, chirpIds []uuid.UUID) ([]ChirpMention, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpMentions, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ChirpMention
+	for rows.Next() {
+		var i ChirpMention
+		if err := rows.Scan(&i.ChirpID, &i.UserID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getChirps = `-- name: GetChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.status, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $1)
+    AND NOT author_hidden_for(chirps.user_id, $1)
 ORDER BY
-    CASE WHEN $1::TEXT = 'ASC' THEN chirps.created_at END ASC,
-    CASE WHEN $1::TEXT = 'DESC' THEN chirps.created_at END DESC
+    CASE WHEN $2::TEXT = 'ASC' THEN chirps.created_at END ASC,
+    CASE WHEN $2::TEXT = 'DESC' THEN chirps.created_at END DESC
 `
 
-func (q *Queries) GetChirps(ctx context.Context, orderBy string) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirps, orderBy)
+type GetChirpsParams struct {
+	ViewerID uuid.NullUUID
+	OrderBy  string
+}
+
+func (q *Queries) GetChirps(ctx context.Context, arg GetChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirps, arg.ViewerID, arg.OrderBy)
 	if err != nil {
 		return nil, err
 	}
@@ -87,6 +263,12 @@ func (q *Queries) GetChirps(ctx context.Context, orderBy string) ([]Chirp, error
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
 		); err != nil {
 			return nil, err
 		}
@@ -102,20 +284,23 @@ func (q *Queries) GetChirps(ctx context.Context, orderBy string) ([]Chirp, error
 }
 
 const getChirpsByAuthor = `-- name: GetChirpsByAuthor :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.status, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = $1 AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $2)
 ORDER BY
-    CASE WHEN $2::TEXT = 'ASC' THEN chirps.created_at END ASC,
-    CASE WHEN $2::TEXT = 'DESC' THEN chirps.created_at END DESC
+    CASE WHEN $3::TEXT = 'ASC' THEN chirps.created_at END ASC,
+    CASE WHEN $3::TEXT = 'DESC' THEN chirps.created_at END DESC
 `
 
 type GetChirpsByAuthorParams struct {
-	UserID  uuid.UUID
-	OrderBy string
+	UserID   uuid.UUID
+	ViewerID uuid.NullUUID
+	OrderBy  string
 }
 
 func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorParams) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, arg.UserID, arg.OrderBy)
+	rows, err := q.db.QueryContext(ctx, getChirpsByAuthor, arg.UserID, arg.ViewerID, arg.OrderBy)
 	if err != nil {
 		return nil, err
 	}
@@ -129,6 +314,12 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorPa
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
 		); err != nil {
 			return nil, err
 		}
@@ -143,11 +334,230 @@ func (q *Queries) GetChirpsByAuthor(ctx context.Context, arg GetChirpsByAuthorPa
 	return items, nil
 }
 
+const getScheduledChirp = `-- name: GetScheduledChirp :one
+SELECT id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive FROM chirps
+WHERE id = $1 AND user_id = $2 AND status = 'scheduled'
+`
+
+type GetScheduledChirpParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetScheduledChirp(ctx context.Context, arg GetScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getScheduledChirp, arg.ID, arg.UserID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const getScheduledChirpsByAuthor = `-- name: GetScheduledChirpsByAuthor :many
+SELECT id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive FROM chirps
+WHERE user_id = $1 AND status = 'scheduled'
+ORDER BY publish_at ASC
+`
+
+func (q *Queries) GetScheduledChirpsByAuthor(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirpsByAuthor, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const publishDueChirps = `-- name: PublishDueChirps :many
+UPDATE chirps
+SET status = 'published',
+    created_at = NOW(),
+    updated_at = NOW()
+WHERE status = 'scheduled' AND publish_at <= NOW()
+RETURNING id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive
+`
+
+func (q *Queries) PublishDueChirps(ctx context.Context) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, publishDueChirps)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpSensitive = `-- name: SetChirpSensitive :one
+UPDATE chirps
+SET sensitive = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive
+`
+
+type SetChirpSensitiveParams struct {
+	ID        uuid.UUID
+	Sensitive bool
+}
+
+func (q *Queries) SetChirpSensitive(ctx context.Context, arg SetChirpSensitiveParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpSensitive, arg.ID, arg.Sensitive)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    edited_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
+const updateScheduledChirp = `-- name: UpdateScheduledChirp :one
+UPDATE chirps
+SET body = $3,
+    publish_at = $4,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status = 'scheduled'
+RETURNING id, created_at, updated_at, body, user_id, edited_at, status, publish_at, visibility, content_warning, sensitive
+`
+
+type UpdateScheduledChirpParams struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Body      string
+	PublishAt sql.NullTime
+}
+
+func (q *Queries) UpdateScheduledChirp(ctx context.Context, arg UpdateScheduledChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateScheduledChirp,
+		arg.ID,
+		arg.UserID,
+		arg.Body,
+		arg.PublishAt,
+	)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.EditedAt,
+		&i.Status,
+		&i.PublishAt,
+		&i.Visibility,
+		&i.ContentWarning,
+		&i.Sensitive,
+	)
+	return i, err
+}
+
 const upgradeUser = `-- name: UpgradeUser :one
 UPDATE users
 SET is_chirpy_red = TRUE
 WHERE id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy
 `
 
 func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
@@ -160,6 +570,10 @@ func (q *Queries) UpgradeUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
 	)
 	return i, err
 }
@@ -0,0 +1,651 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: conversations.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addConversationMember = `-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type AddConversationMemberParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) AddConversationMember(ctx context.Context, arg AddConversationMemberParams) error {
+	_, err := q.db.ExecContext(ctx, addConversationMember, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const createDirectConversation = `-- name: CreateDirectConversation :one
+INSERT INTO conversations (id, created_at, updated_at, direct_key)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING id, created_at, updated_at, direct_key, kind, name, owner_id
+`
+
+func (q *Queries) CreateDirectConversation(ctx context.Context, directKey sql.NullString) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, createDirectConversation, directKey)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DirectKey,
+		&i.Kind,
+		&i.Name,
+		&i.OwnerID,
+	)
+	return i, err
+}
+
+const createGroupConversation = `-- name: CreateGroupConversation :one
+INSERT INTO conversations (id, created_at, updated_at, kind, name, owner_id)
+VALUES (gen_random_uuid(), NOW(), NOW(), 'group', $1, $2)
+RETURNING id, created_at, updated_at, direct_key, kind, name, owner_id
+`
+
+type CreateGroupConversationParams struct {
+	Name    sql.NullString
+	OwnerID uuid.NullUUID
+}
+
+func (q *Queries) CreateGroupConversation(ctx context.Context, arg CreateGroupConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, createGroupConversation, arg.Name, arg.OwnerID)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DirectKey,
+		&i.Kind,
+		&i.Name,
+		&i.OwnerID,
+	)
+	return i, err
+}
+
+const createMessage = `-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING id, created_at, conversation_id, sender_id, body, event, user_id
+`
+
+type CreateMessageParams struct {
+	ConversationID uuid.UUID
+	SenderID       uuid.NullUUID
+	Body           string
+}
+
+func (q *Queries) CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, createMessage, arg.ConversationID, arg.SenderID, arg.Body)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+		&i.Event,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const createSystemMessage = `-- name: CreateSystemMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body, event, user_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5)
+RETURNING id, created_at, conversation_id, sender_id, body, event, user_id
+`
+
+type CreateSystemMessageParams struct {
+	ConversationID uuid.UUID
+	SenderID       uuid.NullUUID
+	Body           string
+	Event          sql.NullString
+	UserID         uuid.NullUUID
+}
+
+func (q *Queries) CreateSystemMessage(ctx context.Context, arg CreateSystemMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, createSystemMessage,
+		arg.ConversationID,
+		arg.SenderID,
+		arg.Body,
+		arg.Event,
+		arg.UserID,
+	)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+		&i.Event,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const deleteConversation = `-- name: DeleteConversation :exec
+DELETE FROM conversations
+WHERE id = $1
+`
+
+func (q *Queries) DeleteConversation(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteConversation, id)
+	return err
+}
+
+const getConversationForMember = `-- name: GetConversationForMember :one
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.direct_key, conversations.kind, conversations.name, conversations.owner_id FROM conversations
+INNER JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = $1 AND conversation_members.user_id = $2
+`
+
+type GetConversationForMemberParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetConversationForMember(ctx context.Context, arg GetConversationForMemberParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getConversationForMember, arg.ID, arg.UserID)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DirectKey,
+		&i.Kind,
+		&i.Name,
+		&i.OwnerID,
+	)
+	return i, err
+}
+
+const getConversationForUpdate = `-- name: GetConversationForUpdate :one
+SELECT id, created_at, updated_at, direct_key, kind, name, owner_id FROM conversations
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetConversationForUpdate(ctx context.Context, id uuid.UUID) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getConversationForUpdate, id)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DirectKey,
+		&i.Kind,
+		&i.Name,
+		&i.OwnerID,
+	)
+	return i, err
+}
+
+const getConversationMembers = `-- name: GetConversationMembers :many
+SELECT conversation_id, user_id, joined_at, last_read_at FROM conversation_members
+WHERE conversation_id = ANY($1::UUID[])
+ORDER BY joined_at ASC
+`
+
+func (q *Queries) GetConversationMembers(ctx context.Context, conversationIds []uuid.UUID) ([]ConversationMember, error) {
+	rows, err := q.db.QueryContext(ctx, getConversationMembers, pq.Array(conversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ConversationMember
+	for rows.Next() {
+		var i ConversationMember
+		if err := rows.Scan(
+			&i.ConversationID,
+			&i.UserID,
+			&i.JoinedAt,
+			&i.LastReadAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationPartners = `-- name: GetConversationPartners :many
+SELECT DISTINCT other.user_id FROM conversation_members own
+INNER JOIN conversation_members other ON other.conversation_id = own.conversation_id
+WHERE own.user_id = $1 AND other.user_id <> $1
+`
+
+func (q *Queries) GetConversationPartners(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, getConversationPartners, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []uuid.UUID
+	for rows.Next() {
+		var user_id uuid.UUID
+		if err := rows.Scan(&user_id); err != nil {
+			return nil, err
+		}
+		items = append(items, user_id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getConversationsForUser = `-- name: GetConversationsForUser :many
+SELECT conversations.id, conversations.created_at, conversations.updated_at, conversations.direct_key, conversations.kind, conversations.name, conversations.owner_id FROM conversations
+INNER JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = $1
+ORDER BY conversations.updated_at DESC
+`
+
+func (q *Queries) GetConversationsForUser(ctx context.Context, userID uuid.UUID) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, getConversationsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Conversation
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DirectKey,
+			&i.Kind,
+			&i.Name,
+			&i.OwnerID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getDirectConversation = `-- name: GetDirectConversation :one
+SELECT id, created_at, updated_at, direct_key, kind, name, owner_id FROM conversations
+WHERE direct_key = $1
+`
+
+func (q *Queries) GetDirectConversation(ctx context.Context, directKey sql.NullString) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, getDirectConversation, directKey)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DirectKey,
+		&i.Kind,
+		&i.Name,
+		&i.OwnerID,
+	)
+	return i, err
+}
+
+const getLastMessages = `-- name: GetLastMessages :many
+SELECT DISTINCT ON (conversation_id) id, created_at, conversation_id, sender_id, body, event, user_id FROM messages
+WHERE conversation_id = ANY($1::UUID[])
+ORDER BY conversation_id, created_at DESC, id DESC
+`
+
+func (q *Queries) GetLastMessages(ctx context.Context, conversationIds []uuid.UUID) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, getLastMessages, pq.Array(conversationIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+			&i.Event,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessage = `-- name: GetMessage :one
+SELECT id, created_at, conversation_id, sender_id, body, event, user_id FROM messages
+WHERE id = $1 AND conversation_id = $2
+`
+
+type GetMessageParams struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+}
+
+func (q *Queries) GetMessage(ctx context.Context, arg GetMessageParams) (Message, error) {
+	row := q.db.QueryRowContext(ctx, getMessage, arg.ID, arg.ConversationID)
+	var i Message
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ConversationID,
+		&i.SenderID,
+		&i.Body,
+		&i.Event,
+		&i.UserID,
+	)
+	return i, err
+}
+
+const getMessages = `-- name: GetMessages :many
+SELECT id, created_at, conversation_id, sender_id, body, event, user_id FROM messages
+WHERE conversation_id = $1
+    AND (
+        $2::TIMESTAMP IS NULL
+        OR (created_at, id) < ($2::TIMESTAMP, $3::UUID)
+    )
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetMessagesParams struct {
+	ConversationID uuid.UUID
+	CursorTime     sql.NullTime
+	CursorID       uuid.NullUUID
+	PageLimit      int32
+}
+
+func (q *Queries) GetMessages(ctx context.Context, arg GetMessagesParams) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, getMessages,
+		arg.ConversationID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+			&i.Event,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMessagesBySender = `-- name: GetMessagesBySender :many
+SELECT id, created_at, conversation_id, sender_id, body, event, user_id FROM messages
+WHERE sender_id = $1 AND event IS NULL
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetMessagesBySender(ctx context.Context, senderID uuid.NullUUID) ([]Message, error) {
+	rows, err := q.db.QueryContext(ctx, getMessagesBySender, senderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Message
+	for rows.Next() {
+		var i Message
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ConversationID,
+			&i.SenderID,
+			&i.Body,
+			&i.Event,
+			&i.UserID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getOwnedGroupsForUpdate = `-- name: GetOwnedGroupsForUpdate :many
+SELECT id, created_at, updated_at, direct_key, kind, name, owner_id FROM conversations
+WHERE owner_id = $1 AND kind = 'group'
+FOR UPDATE
+`
+
+func (q *Queries) GetOwnedGroupsForUpdate(ctx context.Context, ownerID uuid.NullUUID) ([]Conversation, error) {
+	rows, err := q.db.QueryContext(ctx, getOwnedGroupsForUpdate, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Conversation
+	for rows.Next() {
+		var i Conversation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DirectKey,
+			&i.Kind,
+			&i.Name,
+			&i.OwnerID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnreadCounts = `-- name: GetUnreadCounts :many
+SELECT messages.conversation_id, COUNT(*) AS unread FROM messages
+INNER JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id
+WHERE conversation_members.user_id = $1
+    AND (messages.sender_id IS NULL OR messages.sender_id <> $1)
+    AND (conversation_members.last_read_at IS NULL OR messages.created_at > conversation_members.last_read_at)
+GROUP BY messages.conversation_id
+`
+
+type GetUnreadCountsRow struct {
+	ConversationID uuid.UUID
+	Unread         int64
+}
+
+func (q *Queries) GetUnreadCounts(ctx context.Context, userID uuid.UUID) ([]GetUnreadCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUnreadCounts, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUnreadCountsRow
+	for rows.Next() {
+		var i GetUnreadCountsRow
+		if err := rows.Scan(&i.ConversationID, &i.Unread); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markConversationRead = `-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2
+`
+
+type MarkConversationReadParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) MarkConversationRead(ctx context.Context, arg MarkConversationReadParams) error {
+	_, err := q.db.ExecContext(ctx, markConversationRead, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const moveReadCursor = `-- name: MoveReadCursor :exec
+UPDATE conversation_members
+SET last_read_at = GREATEST(last_read_at, $1::TIMESTAMP)
+WHERE conversation_id = $2 AND user_id = $3
+`
+
+type MoveReadCursorParams struct {
+	ReadAt         time.Time
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) MoveReadCursor(ctx context.Context, arg MoveReadCursorParams) error {
+	_, err := q.db.ExecContext(ctx, moveReadCursor, arg.ReadAt, arg.ConversationID, arg.UserID)
+	return err
+}
+
+const removeConversationMember = `-- name: RemoveConversationMember :execrows
+DELETE FROM conversation_members
+WHERE conversation_id = $1 AND user_id = $2
+`
+
+type RemoveConversationMemberParams struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+}
+
+func (q *Queries) RemoveConversationMember(ctx context.Context, arg RemoveConversationMemberParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeConversationMember, arg.ConversationID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const renameConversation = `-- name: RenameConversation :one
+UPDATE conversations
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, direct_key, kind, name, owner_id
+`
+
+type RenameConversationParams struct {
+	ID   uuid.UUID
+	Name sql.NullString
+}
+
+func (q *Queries) RenameConversation(ctx context.Context, arg RenameConversationParams) (Conversation, error) {
+	row := q.db.QueryRowContext(ctx, renameConversation, arg.ID, arg.Name)
+	var i Conversation
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DirectKey,
+		&i.Kind,
+		&i.Name,
+		&i.OwnerID,
+	)
+	return i, err
+}
+
+const setConversationOwner = `-- name: SetConversationOwner :exec
+UPDATE conversations
+SET owner_id = $2
+WHERE id = $1
+`
+
+type SetConversationOwnerParams struct {
+	ID      uuid.UUID
+	OwnerID uuid.NullUUID
+}
+
+func (q *Queries) SetConversationOwner(ctx context.Context, arg SetConversationOwnerParams) error {
+	_, err := q.db.ExecContext(ctx, setConversationOwner, arg.ID, arg.OwnerID)
+	return err
+}
+
+const touchConversation = `-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TouchConversation(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, touchConversation, id)
+	return err
+}
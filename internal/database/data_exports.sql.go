@@ -0,0 +1,174 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: data_exports.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const claimDataExport = `-- name: ClaimDataExport :one
+UPDATE data_exports
+SET status = 'processing',
+    updated_at = NOW()
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+        OR (status = 'processing' AND updated_at < $1::TIMESTAMP)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, created_at, updated_at, user_id, status, expires_at
+`
+
+func (q *Queries) ClaimDataExport(ctx context.Context, staleBefore time.Time) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, claimDataExport, staleBefore)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const completeDataExport = `-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready',
+    expires_at = $2,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+type CompleteDataExportParams struct {
+	ID        uuid.UUID
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) CompleteDataExport(ctx context.Context, arg CompleteDataExportParams) error {
+	_, err := q.db.ExecContext(ctx, completeDataExport, arg.ID, arg.ExpiresAt)
+	return err
+}
+
+const createDataExport = `-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id, status)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, 'pending'
+)
+RETURNING id, created_at, updated_at, user_id, status, expires_at
+`
+
+func (q *Queries) CreateDataExport(ctx context.Context, userID uuid.UUID) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, createDataExport, userID)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const createDataExportArchive = `-- name: CreateDataExportArchive :exec
+INSERT INTO data_export_archives (export_id, content)
+VALUES ($1, $2)
+ON CONFLICT (export_id) DO NOTHING
+`
+
+type CreateDataExportArchiveParams struct {
+	ExportID uuid.UUID
+	Content  []byte
+}
+
+func (q *Queries) CreateDataExportArchive(ctx context.Context, arg CreateDataExportArchiveParams) error {
+	_, err := q.db.ExecContext(ctx, createDataExportArchive, arg.ExportID, arg.Content)
+	return err
+}
+
+const deleteExpiredDataExports = `-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at < NOW()
+    OR (status = 'failed' AND updated_at < $1::TIMESTAMP)
+`
+
+func (q *Queries) DeleteExpiredDataExports(ctx context.Context, failedBefore time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteExpiredDataExports, failedBefore)
+	return err
+}
+
+const failDataExport = `-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed',
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) FailDataExport(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, failDataExport, id)
+	return err
+}
+
+const getActiveDataExport = `-- name: GetActiveDataExport :one
+SELECT id, created_at, updated_at, user_id, status, expires_at FROM data_exports
+WHERE user_id = $1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetActiveDataExport(ctx context.Context, userID uuid.UUID) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, getActiveDataExport, userID)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getDataExport = `-- name: GetDataExport :one
+SELECT id, created_at, updated_at, user_id, status, expires_at FROM data_exports
+WHERE id = $1
+`
+
+func (q *Queries) GetDataExport(ctx context.Context, id uuid.UUID) (DataExport, error) {
+	row := q.db.QueryRowContext(ctx, getDataExport, id)
+	var i DataExport
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Status,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getDataExportArchive = `-- name: GetDataExportArchive :one
+SELECT content FROM data_export_archives
+WHERE export_id = $1
+`
+
+func (q *Queries) GetDataExportArchive(ctx context.Context, exportID uuid.UUID) ([]byte, error) {
+	row := q.db.QueryRowContext(ctx, getDataExportArchive, exportID)
+	var content []byte
+	err := row.Scan(&content)
+	return content, err
+}
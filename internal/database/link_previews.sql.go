@@ -0,0 +1,175 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: link_previews.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const claimLinkPreview = `-- name: ClaimLinkPreview :one
+UPDATE link_previews
+SET status = 'processing',
+    updated_at = NOW()
+WHERE url = (
+    SELECT url FROM link_previews
+    WHERE status = 'pending'
+        OR (status = 'processing' AND updated_at < $1::TIMESTAMP)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING url, created_at, updated_at, status, title, description, image_url, site_name, canonical_url
+`
+
+func (q *Queries) ClaimLinkPreview(ctx context.Context, staleBefore time.Time) (LinkPreview, error) {
+	row := q.db.QueryRowContext(ctx, claimLinkPreview, staleBefore)
+	var i LinkPreview
+	err := row.Scan(
+		&i.Url,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Status,
+		&i.Title,
+		&i.Description,
+		&i.ImageUrl,
+		&i.SiteName,
+		&i.CanonicalUrl,
+	)
+	return i, err
+}
+
+const completeLinkPreview = `-- name: CompleteLinkPreview :exec
+UPDATE link_previews
+SET status = 'ready',
+    title = $2,
+    description = $3,
+    image_url = $4,
+    site_name = $5,
+    canonical_url = $6,
+    updated_at = NOW()
+WHERE url = $1
+`
+
+type CompleteLinkPreviewParams struct {
+	Url          string
+	Title        string
+	Description  string
+	ImageUrl     string
+	SiteName     string
+	CanonicalUrl string
+}
+
+func (q *Queries) CompleteLinkPreview(ctx context.Context, arg CompleteLinkPreviewParams) error {
+	_, err := q.db.ExecContext(ctx, completeLinkPreview,
+		arg.Url,
+		arg.Title,
+		arg.Description,
+		arg.ImageUrl,
+		arg.SiteName,
+		arg.CanonicalUrl,
+	)
+	return err
+}
+
+const createChirpLink = `-- name: CreateChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, position)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING
+`
+
+type CreateChirpLinkParams struct {
+	ChirpID  uuid.UUID
+	Url      string
+	Position int32
+}
+
+func (q *Queries) CreateChirpLink(ctx context.Context, arg CreateChirpLinkParams) error {
+	_, err := q.db.ExecContext(ctx, createChirpLink, arg.ChirpID, arg.Url, arg.Position)
+	return err
+}
+
+const createLinkPreview = `-- name: CreateLinkPreview :exec
+INSERT INTO link_previews (url, created_at, updated_at, status)
+VALUES ($1, NOW(), NOW(), 'pending')
+ON CONFLICT (url) DO NOTHING
+`
+
+func (q *Queries) CreateLinkPreview(ctx context.Context, url string) error {
+	_, err := q.db.ExecContext(ctx, createLinkPreview, url)
+	return err
+}
+
+const deleteChirpLinks = `-- name: DeleteChirpLinks :exec
+DELETE FROM chirp_links
+WHERE chirp_id = $1
+`
+
+func (q *Queries) DeleteChirpLinks(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteChirpLinks, chirpID)
+	return err
+}
+
+const failLinkPreview = `-- name: FailLinkPreview :exec
+UPDATE link_previews
+SET status = 'failed',
+    updated_at = NOW()
+WHERE url = $1
+`
+
+func (q *Queries) FailLinkPreview(ctx context.Context, url string) error {
+	_, err := q.db.ExecContext(ctx, failLinkPreview, url)
+	return err
+}
+
+const getChirpsLinkPreviews = `-- name: GetChirpsLinkPreviews :many
+SELECT chirp_links.chirp_id, link_previews.url, link_previews.created_at, link_previews.updated_at, link_previews.status, link_previews.title, link_previews.description, link_previews.image_url, link_previews.site_name, link_previews.canonical_url FROM chirp_links
+INNER JOIN link_previews ON link_previews.url = chirp_links.url
+WHERE chirp_links.chirp_id = ANY($1::UUID[]) AND link_previews.status = 'ready'
+ORDER BY chirp_links.chirp_id, chirp_links.position
+`
+
+type GetChirpsLinkPreviewsRow struct {
+	ChirpID     uuid.UUID
+	LinkPreview LinkPreview
+}
+
+func (q *Queries) GetChirpsLinkPreviews(ctx context.Context, chirpIds []uuid.UUID) ([]GetChirpsLinkPreviewsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsLinkPreviews, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpsLinkPreviewsRow
+	for rows.Next() {
+		var i GetChirpsLinkPreviewsRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.LinkPreview.Url,
+			&i.LinkPreview.CreatedAt,
+			&i.LinkPreview.UpdatedAt,
+			&i.LinkPreview.Status,
+			&i.LinkPreview.Title,
+			&i.LinkPreview.Description,
+			&i.LinkPreview.ImageUrl,
+			&i.LinkPreview.SiteName,
+			&i.LinkPreview.CanonicalUrl,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,460 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: lists.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const addListMember = `-- name: AddListMember :execrows
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type AddListMemberParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) AddListMember(ctx context.Context, arg AddListMemberParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, addListMember, arg.ListID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const countListMembers = `-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1
+`
+
+func (q *Queries) CountListMembers(ctx context.Context, listID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countListMembers, listID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countListSubscribers = `-- name: CountListSubscribers :many
+SELECT list_id, COUNT(*) AS subscribers FROM list_subscriptions
+WHERE list_id = ANY($1::UUID[])
+GROUP BY list_id
+`
+
+type CountListSubscribersRow struct {
+	ListID      uuid.UUID
+	Subscribers int64
+}
+
+func (q *Queries) CountListSubscribers(ctx context.Context, listIds []uuid.UUID) ([]CountListSubscribersRow, error) {
+	rows, err := q.db.QueryContext(ctx, countListSubscribers, pq.Array(listIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountListSubscribersRow
+	for rows.Next() {
+		var i CountListSubscribersRow
+		if err := rows.Scan(&i.ListID, &i.Subscribers); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countLists = `-- name: CountLists :one
+SELECT COUNT(*) FROM lists
+WHERE owner_id = $1
+`
+
+func (q *Queries) CountLists(ctx context.Context, ownerID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countLists, ownerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createList = `-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, visibility)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
+ON CONFLICT (owner_id, name) DO NOTHING
+RETURNING id, created_at, updated_at, owner_id, name, description, visibility
+`
+
+type CreateListParams struct {
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	Visibility  string
+}
+
+func (q *Queries) CreateList(ctx context.Context, arg CreateListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, createList,
+		arg.OwnerID,
+		arg.Name,
+		arg.Description,
+		arg.Visibility,
+	)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const deleteList = `-- name: DeleteList :execrows
+DELETE FROM lists
+WHERE id = $1 AND owner_id = $2
+`
+
+type DeleteListParams struct {
+	ID      uuid.UUID
+	OwnerID uuid.UUID
+}
+
+func (q *Queries) DeleteList(ctx context.Context, arg DeleteListParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteList, arg.ID, arg.OwnerID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteListSubscriptions = `-- name: DeleteListSubscriptions :exec
+DELETE FROM list_subscriptions
+WHERE list_id = $1
+`
+
+func (q *Queries) DeleteListSubscriptions(ctx context.Context, listID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteListSubscriptions, listID)
+	return err
+}
+
+const getListChirps = `-- name: GetListChirps :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.edited_at, chirps.status, chirps.publish_at, chirps.visibility, chirps.content_warning, chirps.sensitive FROM chirps
+INNER JOIN list_members ON list_members.user_id = chirps.user_id
+INNER JOIN users ON users.id = chirps.user_id
+WHERE list_members.list_id = $1 AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, $2)
+    AND NOT author_hidden_for(chirps.user_id, $2)
+    AND (
+        $3::TIMESTAMP IS NULL
+        OR (chirps.created_at, chirps.id) < ($3::TIMESTAMP, $4::UUID)
+    )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT $5
+`
+
+type GetListChirpsParams struct {
+	ListID     uuid.UUID
+	ViewerID   uuid.NullUUID
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	PageLimit  int32
+}
+
+func (q *Queries) GetListChirps(ctx context.Context, arg GetListChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getListChirps,
+		arg.ListID,
+		arg.ViewerID,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.Status,
+			&i.PublishAt,
+			&i.Visibility,
+			&i.ContentWarning,
+			&i.Sensitive,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getListForUpdate = `-- name: GetListForUpdate :one
+SELECT id, created_at, updated_at, owner_id, name, description, visibility FROM lists
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetListForUpdate(ctx context.Context, id uuid.UUID) (List, error) {
+	row := q.db.QueryRowContext(ctx, getListForUpdate, id)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const getListMembers = `-- name: GetListMembers :many
+SELECT list_members.list_id, list_members.user_id, list_members.created_at FROM list_members
+INNER JOIN users ON users.id = list_members.user_id
+WHERE list_members.list_id = $1 AND users.deleted_at IS NULL
+ORDER BY list_members.created_at ASC
+`
+
+func (q *Queries) GetListMembers(ctx context.Context, listID uuid.UUID) ([]ListMember, error) {
+	rows, err := q.db.QueryContext(ctx, getListMembers, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListMember
+	for rows.Next() {
+		var i ListMember
+		if err := rows.Scan(&i.ListID, &i.UserID, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getListsByOwner = `-- name: GetListsByOwner :many
+SELECT id, created_at, updated_at, owner_id, name, description, visibility FROM lists
+WHERE owner_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetListsByOwner(ctx context.Context, ownerID uuid.UUID) ([]List, error) {
+	rows, err := q.db.QueryContext(ctx, getListsByOwner, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []List
+	for rows.Next() {
+		var i List
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.OwnerID,
+			&i.Name,
+			&i.Description,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSubscribedLists = `-- name: GetSubscribedLists :many
+SELECT lists.id, lists.created_at, lists.updated_at, lists.owner_id, lists.name, lists.description, lists.visibility FROM lists
+INNER JOIN list_subscriptions ON list_subscriptions.list_id = lists.id
+INNER JOIN users ON users.id = lists.owner_id
+WHERE list_subscriptions.user_id = $1 AND lists.visibility = 'public' AND users.deleted_at IS NULL
+ORDER BY list_subscriptions.created_at ASC
+`
+
+func (q *Queries) GetSubscribedLists(ctx context.Context, userID uuid.UUID) ([]List, error) {
+	rows, err := q.db.QueryContext(ctx, getSubscribedLists, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []List
+	for rows.Next() {
+		var i List
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.OwnerID,
+			&i.Name,
+			&i.Description,
+			&i.Visibility,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleList = `-- name: GetVisibleList :one
+SELECT lists.id, lists.created_at, lists.updated_at, lists.owner_id, lists.name, lists.description, lists.visibility FROM lists
+INNER JOIN users ON users.id = lists.owner_id
+WHERE lists.id = $1 AND users.deleted_at IS NULL
+    AND (lists.visibility = 'public' OR lists.owner_id = $2)
+`
+
+type GetVisibleListParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetVisibleList(ctx context.Context, arg GetVisibleListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleList, arg.ID, arg.ViewerID)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.Visibility,
+	)
+	return i, err
+}
+
+const removeListMember = `-- name: RemoveListMember :execrows
+DELETE FROM list_members
+WHERE list_id = $1 AND user_id = $2
+`
+
+type RemoveListMemberParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) RemoveListMember(ctx context.Context, arg RemoveListMemberParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, removeListMember, arg.ListID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const subscribeToList = `-- name: SubscribeToList :exec
+INSERT INTO list_subscriptions (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING
+`
+
+type SubscribeToListParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) SubscribeToList(ctx context.Context, arg SubscribeToListParams) error {
+	_, err := q.db.ExecContext(ctx, subscribeToList, arg.ListID, arg.UserID)
+	return err
+}
+
+const unsubscribeFromList = `-- name: UnsubscribeFromList :exec
+DELETE FROM list_subscriptions
+WHERE list_id = $1 AND user_id = $2
+`
+
+type UnsubscribeFromListParams struct {
+	ListID uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) UnsubscribeFromList(ctx context.Context, arg UnsubscribeFromListParams) error {
+	_, err := q.db.ExecContext(ctx, unsubscribeFromList, arg.ListID, arg.UserID)
+	return err
+}
+
+const updateList = `-- name: UpdateList :one
+UPDATE lists
+SET name = $3, description = $4, visibility = $5, updated_at = NOW()
+WHERE lists.id = $1 AND lists.owner_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM lists other
+        WHERE other.owner_id = $2 AND other.name = $3 AND other.id <> $1
+    )
+RETURNING id, created_at, updated_at, owner_id, name, description, visibility
+`
+
+type UpdateListParams struct {
+	ID          uuid.UUID
+	OwnerID     uuid.UUID
+	Name        string
+	Description string
+	Visibility  string
+}
+
+func (q *Queries) UpdateList(ctx context.Context, arg UpdateListParams) (List, error) {
+	row := q.db.QueryRowContext(ctx, updateList,
+		arg.ID,
+		arg.OwnerID,
+		arg.Name,
+		arg.Description,
+		arg.Visibility,
+	)
+	var i List
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OwnerID,
+		&i.Name,
+		&i.Description,
+		&i.Visibility,
+	)
+	return i, err
+}
@@ -0,0 +1,123 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: login_throttles.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const clearLoginThrottle = `-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1
+`
+
+func (q *Queries) ClearLoginThrottle(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, clearLoginThrottle, key)
+	return err
+}
+
+const getLockedLoginThrottles = `-- name: GetLockedLoginThrottles :many
+SELECT key, created_at, updated_at, failed_attempts, locked_until FROM login_throttles
+WHERE key LIKE $1::TEXT || '%'
+    AND locked_until > NOW()
+ORDER BY locked_until DESC
+`
+
+func (q *Queries) GetLockedLoginThrottles(ctx context.Context, keyPrefix string) ([]LoginThrottle, error) {
+	rows, err := q.db.QueryContext(ctx, getLockedLoginThrottles, keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []LoginThrottle
+	for rows.Next() {
+		var i LoginThrottle
+		if err := rows.Scan(
+			&i.Key,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.FailedAttempts,
+			&i.LockedUntil,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getLoginThrottle = `-- name: GetLoginThrottle :one
+SELECT key, created_at, updated_at, failed_attempts, locked_until FROM login_throttles
+WHERE key = $1
+`
+
+func (q *Queries) GetLoginThrottle(ctx context.Context, key string) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, getLoginThrottle, key)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Key,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const lockLoginThrottle = `-- name: LockLoginThrottle :exec
+UPDATE login_throttles
+SET locked_until = $2,
+    updated_at = NOW()
+WHERE key = $1
+`
+
+type LockLoginThrottleParams struct {
+	Key         string
+	LockedUntil sql.NullTime
+}
+
+func (q *Queries) LockLoginThrottle(ctx context.Context, arg LockLoginThrottleParams) error {
+	_, err := q.db.ExecContext(ctx, lockLoginThrottle, arg.Key, arg.LockedUntil)
+	return err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (key, created_at, updated_at, failed_attempts, locked_until)
+VALUES ($1, NOW(), NOW(), 1, NULL)
+ON CONFLICT (key) DO UPDATE
+SET failed_attempts = CASE
+        WHEN login_throttles.updated_at < $2::TIMESTAMP THEN 1
+        ELSE login_throttles.failed_attempts + 1
+    END,
+    updated_at = NOW()
+RETURNING key, created_at, updated_at, failed_attempts, locked_until
+`
+
+type RecordLoginFailureParams struct {
+	Key         string
+	ResetBefore time.Time
+}
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) (LoginThrottle, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure, arg.Key, arg.ResetBefore)
+	var i LoginThrottle
+	err := row.Scan(
+		&i.Key,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.FailedAttempts,
+		&i.LockedUntil,
+	)
+	return i, err
+}
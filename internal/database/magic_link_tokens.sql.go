@@ -0,0 +1,53 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: magic_link_tokens.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const consumeMagicLinkToken = `-- name: ConsumeMagicLinkToken :one
+UPDATE magic_link_tokens
+SET used_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING token_hash, created_at, updated_at, user_id, expires_at, used_at
+`
+
+func (q *Queries) ConsumeMagicLinkToken(ctx context.Context, tokenHash string) (MagicLinkToken, error) {
+	row := q.db.QueryRowContext(ctx, consumeMagicLinkToken, tokenHash)
+	var i MagicLinkToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.UsedAt,
+	)
+	return i, err
+}
+
+const createMagicLinkToken = `-- name: CreateMagicLinkToken :exec
+INSERT INTO magic_link_tokens (token_hash, created_at, updated_at, user_id, expires_at, used_at)
+VALUES ($1, NOW(), NOW(), $2, $3, NULL)
+`
+
+type CreateMagicLinkTokenParams struct {
+	TokenHash string
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+func (q *Queries) CreateMagicLinkToken(ctx context.Context, arg CreateMagicLinkTokenParams) error {
+	_, err := q.db.ExecContext(ctx, createMagicLinkToken, arg.TokenHash, arg.UserID, arg.ExpiresAt)
+	return err
+}
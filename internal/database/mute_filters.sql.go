@@ -0,0 +1,233 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: mute_filters.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const countMuteFilters = `-- name: CountMuteFilters :one
+SELECT COUNT(*) FROM mute_filters
+WHERE user_id = $1
+`
+
+func (q *Queries) CountMuteFilters(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countMuteFilters, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createMuteFilter = `-- name: CreateMuteFilter :one
+INSERT INTO mute_filters (id, created_at, updated_at, user_id, phrase, whole_word, only_non_followed, action, expires_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, phrase) DO NOTHING
+RETURNING id, created_at, updated_at, user_id, phrase, whole_word, only_non_followed, action, expires_at
+`
+
+type CreateMuteFilterParams struct {
+	UserID          uuid.UUID
+	Phrase          string
+	WholeWord       bool
+	OnlyNonFollowed bool
+	Action          string
+	ExpiresAt       sql.NullTime
+}
+
+func (q *Queries) CreateMuteFilter(ctx context.Context, arg CreateMuteFilterParams) (MuteFilter, error) {
+	row := q.db.QueryRowContext(ctx, createMuteFilter,
+		arg.UserID,
+		arg.Phrase,
+		arg.WholeWord,
+		arg.OnlyNonFollowed,
+		arg.Action,
+		arg.ExpiresAt,
+	)
+	var i MuteFilter
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Phrase,
+		&i.WholeWord,
+		&i.OnlyNonFollowed,
+		&i.Action,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const deleteMuteFilter = `-- name: DeleteMuteFilter :execrows
+DELETE FROM mute_filters
+WHERE id = $1 AND user_id = $2
+`
+
+type DeleteMuteFilterParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) DeleteMuteFilter(ctx context.Context, arg DeleteMuteFilterParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteMuteFilter, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getActiveMuteFilters = `-- name: GetActiveMuteFilters :many
+SELECT id, created_at, updated_at, user_id, phrase, whole_word, only_non_followed, action, expires_at FROM mute_filters
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+`
+
+func (q *Queries) GetActiveMuteFilters(ctx context.Context, userID uuid.UUID) ([]MuteFilter, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveMuteFilters, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MuteFilter
+	for rows.Next() {
+		var i MuteFilter
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Phrase,
+			&i.WholeWord,
+			&i.OnlyNonFollowed,
+			&i.Action,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getMuteFilter = `-- name: GetMuteFilter :one
+SELECT id, created_at, updated_at, user_id, phrase, whole_word, only_non_followed, action, expires_at FROM mute_filters
+WHERE id = $1 AND user_id = $2
+`
+
+type GetMuteFilterParams struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+func (q *Queries) GetMuteFilter(ctx context.Context, arg GetMuteFilterParams) (MuteFilter, error) {
+	row := q.db.QueryRowContext(ctx, getMuteFilter, arg.ID, arg.UserID)
+	var i MuteFilter
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Phrase,
+		&i.WholeWord,
+		&i.OnlyNonFollowed,
+		&i.Action,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
+
+const getMuteFilters = `-- name: GetMuteFilters :many
+SELECT id, created_at, updated_at, user_id, phrase, whole_word, only_non_followed, action, expires_at FROM mute_filters
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetMuteFilters(ctx context.Context, userID uuid.UUID) ([]MuteFilter, error) {
+	rows, err := q.db.QueryContext(ctx, getMuteFilters, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []MuteFilter
+	for rows.Next() {
+		var i MuteFilter
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Phrase,
+			&i.WholeWord,
+			&i.OnlyNonFollowed,
+			&i.Action,
+			&i.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateMuteFilter = `-- name: UpdateMuteFilter :one
+UPDATE mute_filters
+SET phrase = $3, whole_word = $4, only_non_followed = $5, action = $6, expires_at = $7, updated_at = NOW()
+WHERE mute_filters.id = $1 AND mute_filters.user_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM mute_filters other
+        WHERE other.user_id = $2 AND other.phrase = $3 AND other.id <> $1
+    )
+RETURNING id, created_at, updated_at, user_id, phrase, whole_word, only_non_followed, action, expires_at
+`
+
+type UpdateMuteFilterParams struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	Phrase          string
+	WholeWord       bool
+	OnlyNonFollowed bool
+	Action          string
+	ExpiresAt       sql.NullTime
+}
+
+func (q *Queries) UpdateMuteFilter(ctx context.Context, arg UpdateMuteFilterParams) (MuteFilter, error) {
+	row := q.db.QueryRowContext(ctx, updateMuteFilter,
+		arg.ID,
+		arg.UserID,
+		arg.Phrase,
+		arg.WholeWord,
+		arg.OnlyNonFollowed,
+		arg.Action,
+		arg.ExpiresAt,
+	)
+	var i MuteFilter
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Phrase,
+		&i.WholeWord,
+		&i.OnlyNonFollowed,
+		&i.Action,
+		&i.ExpiresAt,
+	)
+	return i, err
+}
@@ -0,0 +1,244 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: polls.sql
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createPoll = `-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, created_at, closes_at)
+VALUES ($1, NOW(), $2)
+`
+
+type CreatePollParams struct {
+	ChirpID  uuid.UUID
+	ClosesAt time.Time
+}
+
+func (q *Queries) CreatePoll(ctx context.Context, arg CreatePollParams) error {
+	_, err := q.db.ExecContext(ctx, createPoll, arg.ChirpID, arg.ClosesAt)
+	return err
+}
+
+const createPollOption = `-- name: CreatePollOption :exec
+INSERT INTO poll_options (id, chirp_id, position, text)
+VALUES (gen_random_uuid(), $1, $2, $3)
+`
+
+type CreatePollOptionParams struct {
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+}
+
+func (q *Queries) CreatePollOption(ctx context.Context, arg CreatePollOptionParams) error {
+	_, err := q.db.ExecContext(ctx, createPollOption, arg.ChirpID, arg.Position, arg.Text)
+	return err
+}
+
+const createPollVote = `-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, option_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (chirp_id, user_id) DO NOTHING
+`
+
+type CreatePollVoteParams struct {
+	ChirpID  uuid.UUID
+	UserID   uuid.UUID
+	OptionID uuid.UUID
+}
+
+func (q *Queries) CreatePollVote(ctx context.Context, arg CreatePollVoteParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, createPollVote, arg.ChirpID, arg.UserID, arg.OptionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getAllUserPollVotes = `-- name: GetAllUserPollVotes :many
+SELECT chirp_id, user_id, option_id, created_at FROM poll_votes
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetAllUserPollVotes(ctx context.Context, userID uuid.UUID) ([]PollVote, error) {
+	rows, err := q.db.QueryContext(ctx, getAllUserPollVotes, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PollVote
+	for rows.Next() {
+		var i PollVote
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.UserID,
+			&i.OptionID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPoll = `-- name: GetPoll :one
+SELECT chirp_id, created_at, closes_at FROM polls
+WHERE chirp_id = $1
+`
+
+func (q *Queries) GetPoll(ctx context.Context, chirpID uuid.UUID) (Poll, error) {
+	row := q.db.QueryRowContext(ctx, getPoll, chirpID)
+	var i Poll
+	err := row.Scan(&i.ChirpID, &i.CreatedAt, &i.ClosesAt)
+	return i, err
+}
+
+const getPollOption = `-- name: GetPollOption :one
+SELECT id, chirp_id, position, text FROM poll_options
+WHERE id = $1 AND chirp_id = $2
+`
+
+type GetPollOptionParams struct {
+	ID      uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) GetPollOption(ctx context.Context, arg GetPollOptionParams) (PollOption, error) {
+	row := q.db.QueryRowContext(ctx, getPollOption, arg.ID, arg.ChirpID)
+	var i PollOption
+	err := row.Scan(
+		&i.ID,
+		&i.ChirpID,
+		&i.Position,
+		&i.Text,
+	)
+	return i, err
+}
+
+const getPollOptionsWithVotes = `-- name: GetPollOptionsWithVotes :many
+SELECT poll_options.id, poll_options.chirp_id, poll_options.position, poll_options.text, COUNT(poll_votes.user_id) AS votes FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE poll_options.chirp_id = ANY($1::UUID[])
+GROUP BY poll_options.id
+ORDER BY poll_options.chirp_id, poll_options.position
+`
+
+type GetPollOptionsWithVotesRow struct {
+	ID       uuid.UUID
+	ChirpID  uuid.UUID
+	Position int32
+	Text     string
+	Votes    int64
+}
+
+func (q *Queries) GetPollOptionsWithVotes(ctx context.Context, chirpIds []uuid.UUID) ([]GetPollOptionsWithVotesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getPollOptionsWithVotes, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPollOptionsWithVotesRow
+	for rows.Next() {
+		var i GetPollOptionsWithVotesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChirpID,
+			&i.Position,
+			&i.Text,
+			&i.Votes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getPolls = `-- name: GetPolls :many
+SELECT chirp_id, created_at, closes_at FROM polls
+WHERE chirp_id = ANY($1::UUID[])
+`
+
+func (q *Queries) GetPolls(ctx context.Context, chirpIds []uuid.UUID) ([]Poll, error) {
+	rows, err := q.db.QueryContext(ctx, getPolls, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Poll
+	for rows.Next() {
+		var i Poll
+		if err := rows.Scan(&i.ChirpID, &i.CreatedAt, &i.ClosesAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserPollVotes = `-- name: GetUserPollVotes :many
+SELECT chirp_id, user_id, option_id, created_at FROM poll_votes
+WHERE user_id = $1 AND chirp_id = ANY($2::UUID[])
+`
+
+type GetUserPollVotesParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+func (q *Queries) GetUserPollVotes(ctx context.Context, arg GetUserPollVotesParams) ([]PollVote, error) {
+	rows, err := q.db.QueryContext(ctx, getUserPollVotes, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PollVote
+	for rows.Next() {
+		var i PollVote
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.UserID,
+			&i.OptionID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -0,0 +1,50 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: rate_limits.sql
+
+package database
+
+import (
+	"context"
+	"time"
+)
+
+const clearRateLimit = `-- name: ClearRateLimit :exec
+DELETE FROM rate_limits
+WHERE key = $1
+`
+
+func (q *Queries) ClearRateLimit(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, clearRateLimit, key)
+	return err
+}
+
+const hitRateLimit = `-- name: HitRateLimit :one
+INSERT INTO rate_limits (key, window_start, hits)
+VALUES ($1, NOW(), 1)
+ON CONFLICT (key) DO UPDATE
+SET hits = CASE
+        WHEN rate_limits.window_start <= $2::TIMESTAMP THEN 1
+        ELSE rate_limits.hits + 1
+    END,
+    window_start = CASE
+        WHEN rate_limits.window_start <= $2::TIMESTAMP THEN NOW()
+        ELSE rate_limits.window_start
+    END
+RETURNING key, window_start, hits
+`
+
+type HitRateLimitParams struct {
+	Key              string
+	WindowOverBefore time.Time
+}
+
+// Counts a hit in the current fixed window of the key, a new window starts
+// with the first hit after the previous one is over.
+func (q *Queries) HitRateLimit(ctx context.Context, arg HitRateLimitParams) (RateLimit, error) {
+	row := q.db.QueryRowContext(ctx, hitRateLimit, arg.Key, arg.WindowOverBefore)
+	var i RateLimit
+	err := row.Scan(&i.Key, &i.WindowStart, &i.Hits)
+	return i, err
+}
@@ -0,0 +1,256 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: reactions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const createReaction = `-- name: CreateReaction :one
+INSERT INTO reactions (id, created_at, chirp_id, user_id, emoji)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+ON CONFLICT (chirp_id, user_id, emoji) DO NOTHING
+RETURNING id, created_at, chirp_id, user_id, emoji
+`
+
+type CreateReactionParams struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+	Emoji   string
+}
+
+func (q *Queries) CreateReaction(ctx context.Context, arg CreateReactionParams) (Reaction, error) {
+	row := q.db.QueryRowContext(ctx, createReaction, arg.ChirpID, arg.UserID, arg.Emoji)
+	var i Reaction
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ChirpID,
+		&i.UserID,
+		&i.Emoji,
+	)
+	return i, err
+}
+
+const deleteReaction = `-- name: DeleteReaction :execrows
+DELETE FROM reactions
+WHERE id = $1 AND chirp_id = $2 AND user_id = $3
+`
+
+type DeleteReactionParams struct {
+	ID      uuid.UUID
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+func (q *Queries) DeleteReaction(ctx context.Context, arg DeleteReactionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteReaction, arg.ID, arg.ChirpID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getReaction = `-- name: GetReaction :one
+SELECT id, created_at, chirp_id, user_id, emoji FROM reactions
+WHERE id = $1 AND chirp_id = $2
+`
+
+type GetReactionParams struct {
+	ID      uuid.UUID
+	ChirpID uuid.UUID
+}
+
+func (q *Queries) GetReaction(ctx context.Context, arg GetReactionParams) (Reaction, error) {
+	row := q.db.QueryRowContext(ctx, getReaction, arg.ID, arg.ChirpID)
+	var i Reaction
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.ChirpID,
+		&i.UserID,
+		&i.Emoji,
+	)
+	return i, err
+}
+
+const getReactionCounts = `-- name: GetReactionCounts :many
+SELECT reactions.chirp_id, reactions.emoji, COUNT(*) AS reactions FROM reactions
+INNER JOIN users ON users.id = reactions.user_id
+WHERE reactions.chirp_id = ANY($1::UUID[])
+    AND reactions.emoji = ANY($2::TEXT[])
+    AND users.deleted_at IS NULL
+GROUP BY reactions.chirp_id, reactions.emoji
+`
+
+type GetReactionCountsParams struct {
+	ChirpIds []uuid.UUID
+	Emojis   []string
+}
+
+type GetReactionCountsRow struct {
+	ChirpID   uuid.UUID
+	Emoji     string
+	Reactions int64
+}
+
+func (q *Queries) GetReactionCounts(ctx context.Context, arg GetReactionCountsParams) ([]GetReactionCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReactionCounts, pq.Array(arg.ChirpIds), pq.Array(arg.Emojis))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReactionCountsRow
+	for rows.Next() {
+		var i GetReactionCountsRow
+		if err := rows.Scan(&i.ChirpID, &i.Emoji, &i.Reactions); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReactions = `-- name: GetReactions :many
+SELECT reactions.id, reactions.created_at, reactions.chirp_id, reactions.user_id, reactions.emoji FROM reactions
+INNER JOIN users ON users.id = reactions.user_id
+WHERE reactions.chirp_id = $1
+    AND reactions.emoji = ANY($2::TEXT[])
+    AND users.deleted_at IS NULL
+    AND (
+        $3::TIMESTAMP IS NULL
+        OR (reactions.created_at, reactions.id) < ($3::TIMESTAMP, $4::UUID)
+    )
+ORDER BY reactions.created_at DESC, reactions.id DESC
+LIMIT $5
+`
+
+type GetReactionsParams struct {
+	ChirpID    uuid.UUID
+	Emojis     []string
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	PageLimit  int32
+}
+
+func (q *Queries) GetReactions(ctx context.Context, arg GetReactionsParams) ([]Reaction, error) {
+	rows, err := q.db.QueryContext(ctx, getReactions,
+		arg.ChirpID,
+		pq.Array(arg.Emojis),
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reaction
+	for rows.Next() {
+		var i Reaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			&i.UserID,
+			&i.Emoji,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUserReactions = `-- name: GetUserReactions :many
+SELECT id, created_at, chirp_id, user_id, emoji FROM reactions
+WHERE user_id = $1
+ORDER BY created_at ASC
+`
+
+func (q *Queries) GetUserReactions(ctx context.Context, userID uuid.UUID) ([]Reaction, error) {
+	rows, err := q.db.QueryContext(ctx, getUserReactions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reaction
+	for rows.Next() {
+		var i Reaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			&i.UserID,
+			&i.Emoji,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getViewerReactions = `-- name: GetViewerReactions :many
+SELECT id, created_at, chirp_id, user_id, emoji FROM reactions
+WHERE user_id = $1 AND chirp_id = ANY($2::UUID[])
+`
+
+type GetViewerReactionsParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+func (q *Queries) GetViewerReactions(ctx context.Context, arg GetViewerReactionsParams) ([]Reaction, error) {
+	rows, err := q.db.QueryContext(ctx, getViewerReactions, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Reaction
+	for rows.Next() {
+		var i Reaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.ChirpID,
+			&i.UserID,
+			&i.Emoji,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
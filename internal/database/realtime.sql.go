@@ -0,0 +1,19 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: realtime.sql
+
+package database
+
+import (
+	"context"
+)
+
+const notifyRealtime = `-- name: NotifyRealtime :exec
+SELECT pg_notify('realtime', $1::TEXT)
+`
+
+func (q *Queries) NotifyRealtime(ctx context.Context, payload string) error {
+	_, err := q.db.ExecContext(ctx, notifyRealtime, payload)
+	return err
+}
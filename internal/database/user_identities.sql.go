@@ -0,0 +1,131 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.27.0
+// source: user_identities.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createUserIdentity = `-- name: CreateUserIdentity :one
+INSERT INTO user_identities (id, created_at, updated_at, user_id, issuer, subject, email)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING id, created_at, updated_at, user_id, issuer, subject, email
+`
+
+type CreateUserIdentityParams struct {
+	UserID  uuid.UUID
+	Issuer  string
+	Subject string
+	Email   string
+}
+
+func (q *Queries) CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error) {
+	row := q.db.QueryRowContext(ctx, createUserIdentity,
+		arg.UserID,
+		arg.Issuer,
+		arg.Subject,
+		arg.Email,
+	)
+	var i UserIdentity
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.Issuer,
+		&i.Subject,
+		&i.Email,
+	)
+	return i, err
+}
+
+const getUserByIdentity = `-- name: GetUserByIdentity :one
+SELECT u.id, u.created_at, u.updated_at, u.email, u.hashed_password, u.is_chirpy_red, u.deleted_at, u.is_moderator, u.sensitive_content, u.dm_privacy FROM users u
+INNER JOIN user_identities ui ON u.id = ui.user_id
+WHERE ui.issuer = $1 AND ui.subject = $2
+`
+
+type GetUserByIdentityParams struct {
+	Issuer  string
+	Subject string
+}
+
+func (q *Queries) GetUserByIdentity(ctx context.Context, arg GetUserByIdentityParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIdentity, arg.Issuer, arg.Subject)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
+	)
+	return i, err
+}
+
+const getUserIdentities = `-- name: GetUserIdentities :many
+SELECT id, created_at, updated_at, user_id, issuer, subject, email FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at
+`
+
+func (q *Queries) GetUserIdentities(ctx context.Context, userID uuid.UUID) ([]UserIdentity, error) {
+	rows, err := q.db.QueryContext(ctx, getUserIdentities, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []UserIdentity
+	for rows.Next() {
+		var i UserIdentity
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.UserID,
+			&i.Issuer,
+			&i.Subject,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateUserIdentityEmail = `-- name: UpdateUserIdentityEmail :exec
+UPDATE user_identities
+SET email = $3,
+    updated_at = NOW()
+WHERE issuer = $1 AND subject = $2
+`
+
+type UpdateUserIdentityEmailParams struct {
+	Issuer  string
+	Subject string
+	Email   string
+}
+
+func (q *Queries) UpdateUserIdentityEmail(ctx context.Context, arg UpdateUserIdentityEmailParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserIdentityEmail, arg.Issuer, arg.Subject, arg.Email)
+	return err
+}
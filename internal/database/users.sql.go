@@ -7,16 +7,35 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const createAccountDeletion = `-- name: CreateAccountDeletion :exec
+INSERT INTO account_deletions (id, created_at, requested_at, was_chirpy_red)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2
+)
+`
+
+type CreateAccountDeletionParams struct {
+	RequestedAt  time.Time
+	WasChirpyRed bool
+}
+
+func (q *Queries) CreateAccountDeletion(ctx context.Context, arg CreateAccountDeletionParams) error {
+	_, err := q.db.ExecContext(ctx, createAccountDeletion, arg.RequestedAt, arg.WasChirpyRed)
+	return err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (
     gen_random_uuid(), NOW(), NOW(), $1, $2
 )
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy
 `
 
 type CreateUserParams struct {
@@ -34,6 +53,10 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
 	)
 	return i, err
 }
@@ -48,7 +71,7 @@ func (q *Queries) DeleteAllUsers(ctx context.Context) error {
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red FROM users
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy FROM users
 WHERE email = $1
 `
 
@@ -62,6 +85,185 @@ func (q *Queries) GetUser(ctx context.Context, email string) (User, error) {
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy FROM users
+WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
+	)
+	return i, err
+}
+
+const getUsersToPurge = `-- name: GetUsersToPurge :many
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy FROM users
+WHERE deleted_at IS NOT NULL
+    AND deleted_at < $2::TIMESTAMP
+LIMIT $1
+`
+
+type GetUsersToPurgeParams struct {
+	Limit         int32
+	DeletedBefore time.Time
+}
+
+func (q *Queries) GetUsersToPurge(ctx context.Context, arg GetUsersToPurgeParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersToPurge, arg.Limit, arg.DeletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Email,
+			&i.HashedPassword,
+			&i.IsChirpyRed,
+			&i.DeletedAt,
+			&i.IsModerator,
+			&i.SensitiveContent,
+			&i.DmPrivacy,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockUser = `-- name: LockUser :one
+SELECT id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy FROM users
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) LockUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, lockUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
+	)
+	return i, err
+}
+
+const purgeUser = `-- name: PurgeUser :execrows
+DELETE FROM users
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) PurgeUser(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeUser, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreUser = `-- name: RestoreUser :exec
+UPDATE users
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, restoreUser, id)
+	return err
+}
+
+const setUserModerator = `-- name: SetUserModerator :one
+UPDATE users
+SET is_moderator = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy
+`
+
+type SetUserModeratorParams struct {
+	ID          uuid.UUID
+	IsModerator bool
+}
+
+func (q *Queries) SetUserModerator(ctx context.Context, arg SetUserModeratorParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserModerator, arg.ID, arg.IsModerator)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
+	)
+	return i, err
+}
+
+const softDeleteUser = `-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy
+`
+
+func (q *Queries) SoftDeleteUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, softDeleteUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
 	)
 	return i, err
 }
@@ -73,7 +275,7 @@ SET email = $2,
     updated_at = NOW()
 WHERE
     id = $1
-RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy
 `
 
 type UpdateUserParams struct {
@@ -92,6 +294,59 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Email,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
+	)
+	return i, err
+}
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
+
+const updateUserPreferences = `-- name: UpdateUserPreferences :one
+UPDATE users
+SET sensitive_content = COALESCE($1, sensitive_content),
+    dm_privacy = COALESCE($2, dm_privacy),
+    updated_at = NOW()
+WHERE id = $3
+RETURNING id, created_at, updated_at, email, hashed_password, is_chirpy_red, deleted_at, is_moderator, sensitive_content, dm_privacy
+`
+
+type UpdateUserPreferencesParams struct {
+	SensitiveContent sql.NullString
+	DmPrivacy        sql.NullString
+	ID               uuid.UUID
+}
+
+func (q *Queries) UpdateUserPreferences(ctx context.Context, arg UpdateUserPreferencesParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserPreferences, arg.SensitiveContent, arg.DmPrivacy, arg.ID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Email,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.DeletedAt,
+		&i.IsModerator,
+		&i.SensitiveContent,
+		&i.DmPrivacy,
 	)
 	return i, err
 }
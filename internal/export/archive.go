@@ -0,0 +1,40 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Entry is a single JSON document inside an export archive.
+type Entry struct {
+	Name string
+	Data any
+}
+
+// WriteArchive writes the entries as indented JSON files into a ZIP archive.
+func WriteArchive(w io.Writer, entries []Entry, modified time.Time) error {
+	archive := zip.NewWriter(w)
+
+	for _, entry := range entries {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     entry.Name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		})
+		if err != nil {
+			return fmt.Errorf("error creating %s: %w", entry.Name, err)
+		}
+
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(entry.Data)
+		if err != nil {
+			return fmt.Errorf("error writing %s: %w", entry.Name, err)
+		}
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWriteArchive(t *testing.T) {
+	type chirp struct {
+		Body string `json:"body"`
+	}
+
+	var buf bytes.Buffer
+	err := WriteArchive(&buf, []Entry{
+		{Name: "profile.json", Data: map[string]string{"email": "walt@example.com"}},
+		{Name: "chirps.json", Data: []chirp{{Body: "Say my name"}, {Body: "I am the one who knocks"}}},
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error writing archive: %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Unexpected error reading archive: %v", err)
+	}
+	if len(reader.File) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(reader.File))
+	}
+
+	file, err := reader.Open("chirps.json")
+	if err != nil {
+		t.Fatalf("Expected chirps.json in the archive: %v", err)
+	}
+	defer file.Close()
+	content, _ := io.ReadAll(file)
+
+	var chirps []chirp
+	err = json.Unmarshal(content, &chirps)
+	if err != nil {
+		t.Fatalf("Unexpected error decoding chirps.json: %v", err)
+	}
+	if len(chirps) != 2 || chirps[1].Body != "I am the one who knocks" {
+		t.Errorf("Unexpected chirps %+v", chirps)
+	}
+}
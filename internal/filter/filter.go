@@ -0,0 +1,72 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Filter matches chirps containing a word, hashtag or phrase. Matching is
+// case insensitive and, for whole words, a phrase only matches when it
+// isn't part of a longer word, so "cat" matches "#cat" but not "catalog".
+type Filter struct {
+	Phrase    string
+	WholeWord bool
+}
+
+var folder = cases.Fold()
+
+func fold(text string) string {
+	return folder.String(norm.NFC.String(text))
+}
+
+// Normalize returns the form phrases are stored in.
+func Normalize(phrase string) string {
+	return strings.Join(strings.Fields(norm.NFC.String(phrase)), " ")
+}
+
+func (f Filter) Match(text string) bool {
+	phrase := fold(Normalize(f.Phrase))
+	if phrase == "" {
+		return false
+	}
+	text = fold(text)
+
+	for offset := 0; offset < len(text); {
+		index := strings.Index(text[offset:], phrase)
+		if index < 0 {
+			return false
+		}
+		start := offset + index
+		end := start + len(phrase)
+		if !f.WholeWord || (isBoundaryBefore(text, start) && isBoundaryAfter(text, end)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+func isBoundaryBefore(text string, index int) bool {
+	if index == 0 {
+		return true
+	}
+	r, _ := utf8.DecodeLastRuneInString(text[:index])
+	return !isWordRune(r)
+}
+
+func isBoundaryAfter(text string, index int) bool {
+	if index >= len(text) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(text[index:])
+	return !isWordRune(r)
+}
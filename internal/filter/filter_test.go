@@ -0,0 +1,36 @@
+package filter
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		text   string
+		want   bool
+	}{
+		{name: "Word", filter: Filter{Phrase: "spoiler", WholeWord: true}, text: "no spoiler here", want: true},
+		{name: "Case insensitive", filter: Filter{Phrase: "Spoiler", WholeWord: true}, text: "SPOILER alert", want: true},
+		{name: "Part of a longer word", filter: Filter{Phrase: "cat", WholeWord: true}, text: "a catalog", want: false},
+		{name: "Later whole word occurrence", filter: Filter{Phrase: "cat", WholeWord: true}, text: "catalog of a cat", want: true},
+		{name: "Substring when not whole word", filter: Filter{Phrase: "cat", WholeWord: false}, text: "a catalog", want: true},
+		{name: "Word matches hashtag", filter: Filter{Phrase: "golang", WholeWord: true}, text: "I love #golang", want: true},
+		{name: "Hashtag", filter: Filter{Phrase: "#finale", WholeWord: true}, text: "watching the #Finale tonight", want: true},
+		{name: "Hashtag doesn't match plain word", filter: Filter{Phrase: "#finale", WholeWord: true}, text: "the finale", want: false},
+		{name: "Phrase with extra spaces", filter: Filter{Phrase: "  season   finale ", WholeWord: true}, text: "the season finale!", want: true},
+		{name: "Punctuation is a boundary", filter: Filter{Phrase: "spoiler", WholeWord: true}, text: "(spoiler)", want: true},
+		{name: "Unicode case folding", filter: Filter{Phrase: "stra\u00dfe", WholeWord: true}, text: "STRASSE closed", want: true},
+		{name: "Accented letters are word characters", filter: Filter{Phrase: "caf", WholeWord: true}, text: "caf\u00e9", want: false},
+		{name: "Normalization", filter: Filter{Phrase: "cafe\u0301", WholeWord: true}, text: "un caf\u00e9", want: true},
+		{name: "Empty phrase", filter: Filter{Phrase: " ", WholeWord: true}, text: "anything", want: false},
+		{name: "No match", filter: Filter{Phrase: "spoiler", WholeWord: true}, text: "nothing to see", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.text); got != tt.want {
+				t.Errorf("%+v.Match(%q) = %v, want %v", tt.filter, tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error creating mailer: %v", err)
+	}
+
+	t.Run("Write a message to the sink directory", func(t *testing.T) {
+		err := m.Send(context.Background(), Message{To: "walt@example.com", Subject: "Hello", Body: "Say my name"})
+		if err != nil {
+			t.Fatalf("Unexpected error sending mail: %v", err)
+		}
+
+		files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+		if len(files) != 1 {
+			t.Fatalf("Expected 1 mail file, got %d", len(files))
+		}
+		content, _ := os.ReadFile(files[0])
+		for _, expected := range []string{"To: walt@example.com", "Subject: Hello", "Say my name"} {
+			if !strings.Contains(string(content), expected) {
+				t.Errorf("Expected mail to contain %q, got %q", expected, content)
+			}
+		}
+	})
+
+	t.Run("Reject header injection", func(t *testing.T) {
+		err := m.Send(context.Background(), Message{To: "walt@example.com\r\nBcc: jesse@example.com", Subject: "Hello"})
+		if err == nil {
+			t.Errorf("Expected an error for a recipient with line breaks")
+		}
+	})
+}
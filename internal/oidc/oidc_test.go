@@ -0,0 +1,269 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockProvider is a minimal OpenID Connect provider issuing ID tokens for a
+// fixed subject through the authorization code flow with PKCE.
+type mockProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu     sync.Mutex
+	codes  map[string]mockAuthorization
+	claims func(issuer, nonce string) IDTokenClaims
+}
+
+type mockAuthorization struct {
+	nonce         string
+	codeChallenge string
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	p := &mockProvider{t: t, key: key, kid: "test-key", codes: map[string]mockAuthorization{}}
+	p.claims = func(issuer, nonce string) IDTokenClaims {
+		now := time.Now()
+		return IDTokenClaims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    issuer,
+				Subject:   "user-123",
+				Audience:  jwt.ClaimStrings{"chirpy"},
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+			},
+			Nonce:         nonce,
+			Email:         "walt@example.com",
+			EmailVerified: true,
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(Discovery{
+			Issuer:                p.server.URL,
+			AuthorizationEndpoint: p.server.URL + "/authorize",
+			TokenEndpoint:         p.server.URL + "/token",
+			JWKSURI:               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(map[string]any{"keys": []jsonWebKey{{
+			Kid: p.kid,
+			Kty: "RSA",
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(res http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		p.mu.Lock()
+		auth, found := p.codes[req.PostForm.Get("code")]
+		delete(p.codes, req.PostForm.Get("code"))
+		p.mu.Unlock()
+
+		if !found || CodeChallenge(req.PostForm.Get("code_verifier")) != auth.codeChallenge {
+			res.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(res).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(res).Encode(map[string]string{"id_token": p.sign(p.claims(p.server.URL, auth.nonce))})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+// authorize simulates the user approving the login at the provider and
+// returns the code sent back to the redirect URL.
+func (p *mockProvider) authorize(authURL string) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("Error parsing auth URL: %v", err)
+	}
+	query := parsed.Query()
+	if query.Get("code_challenge_method") != "S256" {
+		p.t.Fatalf("Expected S256 PKCE challenge, got %q", query.Get("code_challenge_method"))
+	}
+
+	code, _ := RandomString()
+	p.mu.Lock()
+	p.codes[code] = mockAuthorization{nonce: query.Get("nonce"), codeChallenge: query.Get("code_challenge")}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *mockProvider) sign(claims IDTokenClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		p.t.Fatalf("Error signing id token: %v", err)
+	}
+	return signed
+}
+
+func newTestClient(p *mockProvider) *Client {
+	return NewClient(Config{
+		Issuer:      p.server.URL,
+		ClientID:    "chirpy",
+		RedirectURL: "http://localhost:8080/api/login/oidc/callback",
+	})
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	provider := newMockProvider(t)
+	client := newTestClient(provider)
+	ctx := context.Background()
+
+	state, _ := RandomString()
+	nonce, _ := RandomString()
+	verifier, _ := RandomString()
+
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("Unexpected error building auth URL: %v", err)
+	}
+	if !strings.HasPrefix(authURL, provider.server.URL+"/authorize?") {
+		t.Fatalf("Unexpected auth URL %s", authURL)
+	}
+	parsed, _ := url.Parse(authURL)
+	for name, expected := range map[string]string{"client_id": "chirpy", "response_type": "code", "state": state, "nonce": nonce} {
+		if got := parsed.Query().Get(name); got != expected {
+			t.Errorf("Expected %s=%q in auth URL, got %q", name, expected, got)
+		}
+	}
+
+	t.Run("Reject a code exchanged with the wrong verifier", func(t *testing.T) {
+		code := provider.authorize(authURL)
+		_, err := client.Exchange(ctx, code, "wrong-verifier")
+		if err == nil {
+			t.Errorf("Expected an error exchanging with the wrong PKCE verifier")
+		}
+	})
+
+	t.Run("Exchange a code and verify the ID token", func(t *testing.T) {
+		code := provider.authorize(authURL)
+		rawIDToken, err := client.Exchange(ctx, code, verifier)
+		if err != nil {
+			t.Fatalf("Unexpected error exchanging code: %v", err)
+		}
+
+		claims, err := client.VerifyIDToken(ctx, rawIDToken, nonce)
+		if err != nil {
+			t.Fatalf("Unexpected error verifying id token: %v", err)
+		}
+		if claims.Subject != "user-123" || claims.Email != "walt@example.com" || !claims.EmailVerified {
+			t.Errorf("Unexpected claims %+v", claims)
+		}
+	})
+
+	t.Run("Codes are single use", func(t *testing.T) {
+		code := provider.authorize(authURL)
+		if _, err := client.Exchange(ctx, code, verifier); err != nil {
+			t.Fatalf("Unexpected error exchanging code: %v", err)
+		}
+		if _, err := client.Exchange(ctx, code, verifier); err == nil {
+			t.Errorf("Expected an error reusing a code")
+		}
+	})
+}
+
+func TestVerifyIDToken(t *testing.T) {
+	provider := newMockProvider(t)
+	client := newTestClient(provider)
+	ctx := context.Background()
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name    string
+		token   func() string
+		wantErr bool
+	}{
+		{
+			name:    "Valid token",
+			token:   func() string { return provider.sign(provider.claims(provider.server.URL, "nonce")) },
+			wantErr: false,
+		},
+		{
+			name:    "Wrong nonce",
+			token:   func() string { return provider.sign(provider.claims(provider.server.URL, "another nonce")) },
+			wantErr: true,
+		},
+		{
+			name:    "Wrong issuer",
+			token:   func() string { return provider.sign(provider.claims("https://evil.example.com", "nonce")) },
+			wantErr: true,
+		},
+		{
+			name: "Wrong audience",
+			token: func() string {
+				claims := provider.claims(provider.server.URL, "nonce")
+				claims.Audience = jwt.ClaimStrings{"another-client"}
+				return provider.sign(claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Expired token",
+			token: func() string {
+				claims := provider.claims(provider.server.URL, "nonce")
+				claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+				return provider.sign(claims)
+			},
+			wantErr: true,
+		},
+		{
+			name: "Signed with an unknown key",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodRS256, provider.claims(provider.server.URL, "nonce"))
+				token.Header["kid"] = provider.kid
+				signed, _ := token.SignedString(otherKey)
+				return signed
+			},
+			wantErr: true,
+		},
+		{
+			name: "Symmetric algorithm",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, provider.claims(provider.server.URL, "nonce"))
+				token.Header["kid"] = provider.kid
+				signed, _ := token.SignedString([]byte("secret"))
+				return signed
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.VerifyIDToken(ctx, tt.token(), "nonce")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyIDToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package oidctest provides a minimal OpenID Connect provider for tests of
+// code using the oidc package.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ivportilla/chirpy/internal/oidc"
+)
+
+const ClientID = "chirpy"
+
+// Provider issues ID tokens through the authorization code flow with PKCE.
+// Change Subject, Email and EmailVerified to log in as someone else.
+type Provider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu            sync.Mutex
+	codes         map[string]authorization
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+type authorization struct {
+	nonce         string
+	codeChallenge string
+}
+
+func NewProvider(t *testing.T) *Provider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+
+	p := &Provider{
+		t:             t,
+		key:           key,
+		codes:         map[string]authorization{},
+		Subject:       "user-123",
+		Email:         "walt@example.com",
+		EmailVerified: true,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(oidc.Discovery{
+			Issuer:                p.server.URL,
+			AuthorizationEndpoint: p.server.URL + "/authorize",
+			TokenEndpoint:         p.server.URL + "/token",
+			JWKSURI:               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(res http.ResponseWriter, req *http.Request) {
+		json.NewEncoder(res).Encode(map[string]any{"keys": []map[string]string{{
+			"kid": "test-key",
+			"kty": "RSA",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(res http.ResponseWriter, req *http.Request) {
+		req.ParseForm()
+		p.mu.Lock()
+		auth, found := p.codes[req.PostForm.Get("code")]
+		delete(p.codes, req.PostForm.Get("code"))
+		p.mu.Unlock()
+
+		if !found || oidc.CodeChallenge(req.PostForm.Get("code_verifier")) != auth.codeChallenge {
+			res.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(res).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(res).Encode(map[string]string{"id_token": p.sign(auth.nonce)})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+
+	return p
+}
+
+func (p *Provider) Issuer() string {
+	return p.server.URL
+}
+
+// NewClient returns a client of the provider redirecting to redirectURL.
+func (p *Provider) NewClient(redirectURL string) *oidc.Client {
+	return oidc.NewClient(oidc.Config{
+		Issuer:      p.server.URL,
+		ClientID:    ClientID,
+		RedirectURL: redirectURL,
+	})
+}
+
+// Authorize simulates the user approving the login at the provider and
+// returns the code sent back to the redirect URL.
+func (p *Provider) Authorize(authURL string) string {
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("Error parsing auth URL: %v", err)
+	}
+	query := parsed.Query()
+
+	code, _ := oidc.RandomString()
+	p.mu.Lock()
+	p.codes[code] = authorization{nonce: query.Get("nonce"), codeChallenge: query.Get("code_challenge")}
+	p.mu.Unlock()
+	return code
+}
+
+func (p *Provider) sign(nonce string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	claims := oidc.IDTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.server.URL,
+			Subject:   p.Subject,
+			Audience:  jwt.ClaimStrings{ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Nonce:         nonce,
+		Email:         p.Email,
+		EmailVerified: p.EmailVerified,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	signed, err := token.SignedString(p.key)
+	if err != nil {
+		p.t.Fatalf("Error signing id token: %v", err)
+	}
+	return signed
+}
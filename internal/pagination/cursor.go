@@ -0,0 +1,66 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Cursor points right after the last item of a page sorted by (time, id).
+// It is opaque to clients.
+type Cursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%s|%s", c.Time.Format(time.RFC3339Nano), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(encoded string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("error decoding cursor: %w", err)
+	}
+
+	rawTime, rawID, found := strings.Cut(string(raw), "|")
+	if !found {
+		return Cursor{}, fmt.Errorf("invalid cursor")
+	}
+	cursorTime, err := time.Parse(time.RFC3339Nano, rawTime)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor time: %w", err)
+	}
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor id: %w", err)
+	}
+
+	return Cursor{Time: cursorTime, ID: id}, nil
+}
+
+// ParseLimit returns the page size requested in the query string, falling
+// back to DefaultLimit and capped to MaxLimit.
+func ParseLimit(raw string) (int, error) {
+	if raw == "" {
+		return DefaultLimit, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 {
+		return 0, fmt.Errorf("invalid limit %q", raw)
+	}
+	if limit > MaxLimit {
+		return MaxLimit, nil
+	}
+	return limit, nil
+}
@@ -0,0 +1,56 @@
+package pagination
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("Round trip a cursor", func(t *testing.T) {
+		cursor := Cursor{Time: time.Date(2024, 12, 4, 10, 30, 0, 123456000, time.UTC), ID: uuid.New()}
+		decoded, err := DecodeCursor(cursor.Encode())
+		if err != nil {
+			t.Fatalf("Unexpected error decoding cursor: %v", err)
+		}
+		if !decoded.Time.Equal(cursor.Time) || decoded.ID != cursor.ID {
+			t.Errorf("Expected %+v, got %+v", cursor, decoded)
+		}
+	})
+
+	t.Run("Reject invalid cursors", func(t *testing.T) {
+		for _, invalid := range []string{"", "not base64!", Cursor{}.Encode()[:10], "bm9waXBl"} {
+			if _, err := DecodeCursor(invalid); err == nil {
+				t.Errorf("Expected an error decoding %q", invalid)
+			}
+		}
+	})
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    int
+		wantErr bool
+	}{
+		{raw: "", want: DefaultLimit},
+		{raw: "5", want: 5},
+		{raw: "1000", want: MaxLimit},
+		{raw: "0", wantErr: true},
+		{raw: "-3", wantErr: true},
+		{raw: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseLimit(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimit(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %d, want %d", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package poll
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MinOptions      = 2
+	MaxOptions      = 4
+	MaxOptionLength = 25
+
+	MinDuration = 5 * time.Minute
+	MaxDuration = 7 * 24 * time.Hour
+)
+
+// ValidateOptions trims the options and checks there are between
+// MinOptions and MaxOptions distinct, non empty ones.
+func ValidateOptions(options []string) ([]string, error) {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return nil, fmt.Errorf("a poll must have between %d and %d options", MinOptions, MaxOptions)
+	}
+
+	cleaned := make([]string, len(options))
+	seen := map[string]bool{}
+	for i, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" {
+			return nil, fmt.Errorf("poll options can't be empty")
+		}
+		if utf8.RuneCountInString(option) > MaxOptionLength {
+			return nil, fmt.Errorf("poll options can't be longer than %d characters", MaxOptionLength)
+		}
+		key := strings.ToLower(option)
+		if seen[key] {
+			return nil, fmt.Errorf("poll options must be different")
+		}
+		seen[key] = true
+		cleaned[i] = option
+	}
+
+	return cleaned, nil
+}
+
+// ValidateClosesAt checks the poll stays open between MinDuration and
+// MaxDuration after the chirp is published.
+func ValidateClosesAt(closesAt, publishedAt time.Time) error {
+	duration := closesAt.Sub(publishedAt)
+	if duration < MinDuration || duration > MaxDuration {
+		return fmt.Errorf("a poll must close between %s and %s after the chirp is published", MinDuration, MaxDuration)
+	}
+	return nil
+}
+
+// Percentages returns the share of votes of each option rounded to one
+// decimal. The rounding uses the largest remainder method so the result
+// always adds up to 100 when there are votes.
+func Percentages(votes []int64) []float64 {
+	percentages := make([]float64, len(votes))
+	var total int64
+	for _, count := range votes {
+		total += count
+	}
+	if total == 0 {
+		return percentages
+	}
+
+	// Work in tenths of a percent to distribute the rounding leftovers
+	const scale = 1000
+	tenths := make([]int64, len(votes))
+	remainders := make([]float64, len(votes))
+	var assigned int64
+	for i, count := range votes {
+		exact := float64(count) * scale / float64(total)
+		tenths[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(tenths[i])
+		assigned += tenths[i]
+	}
+
+	order := make([]int, len(votes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := 0; assigned < scale; i++ {
+		tenths[order[i%len(order)]]++
+		assigned++
+	}
+
+	for i := range tenths {
+		percentages[i] = float64(tenths[i]) / 10
+	}
+	return percentages
+}
@@ -0,0 +1,83 @@
+package poll
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "Valid options are trimmed", options: []string{" Yes", "No "}, want: []string{"Yes", "No"}},
+		{name: "Four options", options: []string{"a", "b", "c", "d"}, want: []string{"a", "b", "c", "d"}},
+		{name: "Too few options", options: []string{"Yes"}, wantErr: true},
+		{name: "Too many options", options: []string{"a", "b", "c", "d", "e"}, wantErr: true},
+		{name: "Empty option", options: []string{"Yes", "  "}, wantErr: true},
+		{name: "Duplicated option", options: []string{"Yes", "yes"}, wantErr: true},
+		{name: "Too long option", options: []string{"Yes", "This option is way too long to fit"}, wantErr: true},
+		{name: "Length counts characters", options: []string{"Sí", "ñññññññññññññññññññññññññ"}, want: []string{"Sí", "ñññññññññññññññññññññññññ"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateOptions(tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateOptions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateClosesAt(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		closesAt time.Time
+		wantErr  bool
+	}{
+		{name: "One day", closesAt: now.Add(24 * time.Hour)},
+		{name: "Too short", closesAt: now.Add(time.Minute), wantErr: true},
+		{name: "In the past", closesAt: now.Add(-time.Hour), wantErr: true},
+		{name: "Too long", closesAt: now.Add(MaxDuration + time.Hour), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateClosesAt(tt.closesAt, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateClosesAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPercentages(t *testing.T) {
+	tests := []struct {
+		name  string
+		votes []int64
+		want  []float64
+	}{
+		{name: "No votes", votes: []int64{0, 0}, want: []float64{0, 0}},
+		{name: "Even split", votes: []int64{1, 1}, want: []float64{50, 50}},
+		{name: "Thirds add up to 100", votes: []int64{1, 1, 1}, want: []float64{33.4, 33.3, 33.3}},
+		{name: "Largest remainder wins", votes: []int64{2, 1, 0}, want: []float64{66.7, 33.3, 0}},
+		{name: "Single option with all votes", votes: []int64{0, 7, 0, 0}, want: []float64{0, 100, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Percentages(tt.votes)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Percentages(%v) = %v, want %v", tt.votes, got, tt.want)
+			}
+		})
+	}
+}
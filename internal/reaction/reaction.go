@@ -0,0 +1,63 @@
+package reaction
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+const MaxEmojis = 20
+
+// DefaultEmojis is the set used when none is configured.
+var DefaultEmojis = Set{"👍", "❤️", "😂", "😮", "😢", "🎉"}
+
+// Set is the ordered list of emojis users can react with. Clients should
+// show the reactions in this order.
+type Set []string
+
+// ParseSet parses a comma separated list of emojis. Every entry must be a
+// single user perceived character, they are stored in NFC.
+func ParseSet(raw string) (Set, error) {
+	set := Set{}
+	seen := map[string]bool{}
+	for _, emoji := range strings.Split(raw, ",") {
+		emoji = norm.NFC.String(strings.TrimSpace(emoji))
+		if emoji == "" {
+			continue
+		}
+		if uniseg.GraphemeClusterCount(emoji) != 1 {
+			return nil, fmt.Errorf("%q is not a single emoji", emoji)
+		}
+		if seen[emoji] {
+			return nil, fmt.Errorf("%q is repeated", emoji)
+		}
+		seen[emoji] = true
+		set = append(set, emoji)
+	}
+
+	if len(set) == 0 {
+		return nil, fmt.Errorf("the set can't be empty")
+	}
+	if len(set) > MaxEmojis {
+		return nil, fmt.Errorf("the set can't have more than %d emojis", MaxEmojis)
+	}
+	return set, nil
+}
+
+// Index returns the position of the emoji in the set, or -1 if it isn't
+// one of them.
+func (s Set) Index(emoji string) int {
+	emoji = norm.NFC.String(emoji)
+	for i, allowed := range s {
+		if allowed == emoji {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s Set) Contains(emoji string) bool {
+	return s.Index(emoji) >= 0
+}
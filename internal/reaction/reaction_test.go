@@ -0,0 +1,60 @@
+package reaction
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     string
+		want    Set
+		wantErr bool
+	}{
+		{name: "Emojis are trimmed", raw: " 👍, 🎉 ", want: Set{"👍", "🎉"}},
+		{name: "Empty entries are skipped", raw: "👍,,🎉,", want: Set{"👍", "🎉"}},
+		{name: "Emoji with variation selector", raw: "❤️", want: Set{"❤️"}},
+		{name: "Flag counts as one emoji", raw: "🇦🇷", want: Set{"🇦🇷"}},
+		{name: "Family sequence counts as one emoji", raw: "👨‍👩‍👧", want: Set{"👨‍👩‍👧"}},
+		{name: "Empty set", raw: " , ", wantErr: true},
+		{name: "More than one character", raw: "👍👍", wantErr: true},
+		{name: "Repeated emoji", raw: "👍,👍", wantErr: true},
+		{name: "Too many emojis", raw: "a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p,q,r,s,t,u", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSet(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSet() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetIndex(t *testing.T) {
+	set := Set{"👍", "\u00e9"}
+
+	tests := []struct {
+		name  string
+		emoji string
+		want  int
+	}{
+		{name: "First emoji", emoji: "👍", want: 0},
+		{name: "Input is normalized", emoji: "e\u0301", want: 1},
+		{name: "Not in the set", emoji: "🎉", want: -1},
+		{name: "Empty", emoji: "", want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := set.Index(tt.emoji); got != tt.want {
+				t.Errorf("Index() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
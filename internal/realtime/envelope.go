@@ -0,0 +1,55 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Envelope carries an event between server replicas, every replica
+// delivers it to the connections it holds. Disconnect envelopes drop the
+// users' connections instead, Event.Type is then the reason, only the
+// ones of SessionID when it is set. Envelopes of conversation messages
+// only carry a MessageRef, each replica loads the message to fill Event.
+type Envelope struct {
+	UserIDs    []uuid.UUID `json:"user_ids"`
+	Event      Event       `json:"event"`
+	Disconnect bool        `json:"disconnect,omitempty"`
+	SessionID  *uuid.UUID  `json:"session_id,omitempty"`
+	Message    *MessageRef `json:"message,omitempty"`
+}
+
+// MessageRef identifies a conversation message.
+type MessageRef struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+// NewEnvelope builds the envelope of an event, data is encoded as JSON.
+func NewEnvelope(userIDs []uuid.UUID, eventType string, data any) (Envelope, error) {
+	event := Event{Type: eventType}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return Envelope{}, fmt.Errorf("error encoding event data: %w", err)
+		}
+		event.Data = encoded
+	}
+	return Envelope{UserIDs: userIDs, Event: event}, nil
+}
+
+// Deliver hands the envelope to the hub.
+func (h *Hub) Deliver(envelope Envelope) {
+	if envelope.Disconnect {
+		for _, userID := range envelope.UserIDs {
+			if envelope.SessionID != nil {
+				h.DisconnectSession(userID, *envelope.SessionID, envelope.Event.Type)
+				continue
+			}
+			h.Disconnect(userID, envelope.Event.Type)
+		}
+		return
+	}
+	h.Publish(envelope.UserIDs, envelope.Event)
+}
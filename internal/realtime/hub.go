@@ -0,0 +1,161 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ClientBuffer is how many events a client can have pending before it is
+// considered too slow and disconnected.
+const ClientBuffer = 64
+
+// Event is what clients receive, Type tells them how to read Data.
+type Event struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Client is one connection of a user, a user can have several when they
+// are logged in from more than one device. SessionID is the login session
+// the connection was opened with.
+type Client struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+
+	events chan Event
+	done   chan struct{}
+	once   sync.Once
+	reason string
+}
+
+// Events delivers the events published to the client's user.
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Done is closed when the hub drops the client, Reason tells why.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *Client) Reason() string {
+	<-c.done
+	return c.reason
+}
+
+func (c *Client) close(reason string) {
+	c.once.Do(func() {
+		c.reason = reason
+		close(c.done)
+	})
+}
+
+// Hub keeps the connections of each user so events can be fanned out to
+// all of their devices.
+type Hub struct {
+	mu      sync.Mutex
+	clients map[uuid.UUID]map[*Client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: map[uuid.UUID]map[*Client]struct{}{}}
+}
+
+// Register adds a connection for the user, first reports whether it is
+// the only one the user has.
+func (h *Hub) Register(userID, sessionID uuid.UUID) (client *Client, first bool) {
+	client = &Client{
+		UserID:    userID,
+		SessionID: sessionID,
+		events:    make(chan Event, ClientBuffer),
+		done:      make(chan struct{}),
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.clients[userID] == nil {
+		h.clients[userID] = map[*Client]struct{}{}
+	}
+	h.clients[userID][client] = struct{}{}
+	return client, len(h.clients[userID]) == 1
+}
+
+// Unregister removes the connection, last reports whether the user has no
+// connections left. Unregistering a client twice is a no-op.
+func (h *Hub) Unregister(client *Client) (last bool) {
+	client.close("closed")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	clients, ok := h.clients[client.UserID]
+	if !ok {
+		return false
+	}
+	if _, ok := clients[client]; !ok {
+		return false
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.clients, client.UserID)
+		return true
+	}
+	return false
+}
+
+// Publish sends the event to every connection of the users without
+// blocking, clients whose buffer is full are disconnected.
+func (h *Hub) Publish(userIDs []uuid.UUID, event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, userID := range userIDs {
+		for client := range h.clients[userID] {
+			select {
+			case <-client.done:
+			case client.events <- event:
+			default:
+				client.close("too slow")
+			}
+		}
+	}
+}
+
+// Disconnect drops every connection of the user.
+func (h *Hub) Disconnect(userID uuid.UUID, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients[userID] {
+		client.close(reason)
+	}
+}
+
+// DisconnectSession drops the connections of the user opened with the
+// session, the user's other sessions stay connected.
+func (h *Hub) DisconnectSession(userID, sessionID uuid.UUID, reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for client := range h.clients[userID] {
+		if client.SessionID == sessionID {
+			client.close(reason)
+		}
+	}
+}
+
+// DisconnectAll drops every connection, used when the server shuts down.
+func (h *Hub) DisconnectAll(reason string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, clients := range h.clients {
+		for client := range clients {
+			client.close(reason)
+		}
+	}
+}
+
+// Online reports whether the user has a connection to this hub.
+func (h *Hub) Online(userID uuid.UUID) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients[userID]) > 0
+}
@@ -0,0 +1,132 @@
+package realtime
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestHubRegister(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+
+	phone, first := hub.Register(userID, uuid.New())
+	if !first {
+		t.Errorf("first connection reported as not first")
+	}
+	laptop, first := hub.Register(userID, uuid.New())
+	if first {
+		t.Errorf("second connection reported as first")
+	}
+	if !hub.Online(userID) {
+		t.Errorf("user with connections reported offline")
+	}
+
+	if last := hub.Unregister(phone); last {
+		t.Errorf("unregistering with a connection left reported as last")
+	}
+	if last := hub.Unregister(phone); last {
+		t.Errorf("unregistering twice reported as last")
+	}
+	if last := hub.Unregister(laptop); !last {
+		t.Errorf("unregistering the last connection not reported as last")
+	}
+	if hub.Online(userID) {
+		t.Errorf("user without connections reported online")
+	}
+}
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	otherUserID := uuid.New()
+
+	phone, _ := hub.Register(userID, uuid.New())
+	laptop, _ := hub.Register(userID, uuid.New())
+	other, _ := hub.Register(otherUserID, uuid.New())
+
+	envelope, err := NewEnvelope([]uuid.UUID{userID}, "message", map[string]string{"body": "hi"})
+	if err != nil {
+		t.Fatalf("NewEnvelope() error = %v", err)
+	}
+	hub.Deliver(envelope)
+
+	for name, client := range map[string]*Client{"phone": phone, "laptop": laptop} {
+		select {
+		case event := <-client.Events():
+			if event.Type != "message" || string(event.Data) != `{"body":"hi"}` {
+				t.Errorf("%s got %s %s", name, event.Type, event.Data)
+			}
+		default:
+			t.Errorf("%s didn't get the event", name)
+		}
+	}
+	select {
+	case event := <-other.Events():
+		t.Errorf("other user got %s", event.Type)
+	default:
+	}
+}
+
+func TestHubSlowClient(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	client, _ := hub.Register(userID, uuid.New())
+
+	for range ClientBuffer + 1 {
+		hub.Publish([]uuid.UUID{userID}, Event{Type: "typing"})
+	}
+
+	select {
+	case <-client.Done():
+		if client.Reason() != "too slow" {
+			t.Errorf("Reason() = %q, want too slow", client.Reason())
+		}
+	default:
+		t.Errorf("slow client wasn't disconnected")
+	}
+}
+
+func TestHubDisconnect(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	phone, _ := hub.Register(userID, uuid.New())
+	laptop, _ := hub.Register(userID, uuid.New())
+
+	hub.Deliver(Envelope{UserIDs: []uuid.UUID{userID}, Event: Event{Type: "session revoked"}, Disconnect: true})
+
+	for name, client := range map[string]*Client{"phone": phone, "laptop": laptop} {
+		select {
+		case <-client.Done():
+			if client.Reason() != "session revoked" {
+				t.Errorf("%s Reason() = %q, want session revoked", name, client.Reason())
+			}
+		default:
+			t.Errorf("%s wasn't disconnected", name)
+		}
+	}
+}
+
+func TestHubDisconnectSession(t *testing.T) {
+	hub := NewHub()
+	userID := uuid.New()
+	sessionID := uuid.New()
+	phone, _ := hub.Register(userID, sessionID)
+	phoneTab, _ := hub.Register(userID, sessionID)
+	laptop, _ := hub.Register(userID, uuid.New())
+
+	hub.Deliver(Envelope{UserIDs: []uuid.UUID{userID}, Event: Event{Type: "session revoked"}, Disconnect: true, SessionID: &sessionID})
+
+	for name, client := range map[string]*Client{"phone": phone, "phone tab": phoneTab} {
+		select {
+		case <-client.Done():
+		default:
+			t.Errorf("%s wasn't disconnected", name)
+		}
+	}
+	select {
+	case <-laptop.Done():
+		t.Errorf("connection of another session was disconnected")
+	default:
+	}
+}
@@ -0,0 +1,146 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var ErrBlockedAddress = errors.New("address not allowed")
+
+type Config struct {
+	// Timeout for the whole fetch, redirects included
+	Timeout time.Duration
+	// Only the first MaxBytes of the page are read
+	MaxBytes     int64
+	MaxRedirects int
+	UserAgent    string
+	// Lets the fetcher reach loopback and private networks, only meant
+	// for tests and local development
+	AllowPrivateNetworks bool
+}
+
+var DefaultConfig = Config{
+	Timeout:      5 * time.Second,
+	MaxBytes:     512 << 10,
+	MaxRedirects: 3,
+	UserAgent:    "ChirpyBot/1.0 (+link previews)",
+}
+
+// Fetcher downloads untrusted URLs. Every connection, redirects included,
+// is checked against the resolved IP right before dialing, so DNS can't be
+// used to reach internal services.
+type Fetcher struct {
+	cfg    Config
+	client *http.Client
+}
+
+func NewFetcher(cfg Config) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if cfg.AllowPrivateNetworks {
+				return nil
+			}
+			return checkAddress(address)
+		},
+	}
+
+	transport := &http.Transport{
+		// No proxy, it would make the dialer check the proxy address instead
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.Timeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.MaxRedirects {
+				return fmt.Errorf("too many redirects")
+			}
+			return checkScheme(req.URL)
+		},
+	}
+
+	return &Fetcher{cfg: cfg, client: client}
+}
+
+// checkAddress rejects anything that isn't a public unicast address.
+func checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	ip = ip.Unmap()
+
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || isSharedAddress(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+	}
+	return nil
+}
+
+// Carrier grade NAT range, not covered by IsPrivate
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func isSharedAddress(ip netip.Addr) bool {
+	return sharedAddressSpace.Contains(ip)
+}
+
+func checkScheme(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", target.Scheme)
+	}
+	return nil
+}
+
+// Fetch downloads an HTML page and returns its preview.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Preview, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return Preview{}, fmt.Errorf("error parsing url: %w", err)
+	}
+	err = checkScheme(target)
+	if err != nil {
+		return Preview{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return Preview{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html")
+
+	res, err := f.client.Do(req)
+	if err != nil {
+		return Preview{}, fmt.Errorf("error fetching %s: %w", rawURL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return Preview{}, fmt.Errorf("unexpected status %d from %s", res.StatusCode, rawURL)
+	}
+	mediaType, _, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return Preview{}, fmt.Errorf("unsupported content type %q", res.Header.Get("Content-Type"))
+	}
+
+	return Parse(io.LimitReader(res.Body, f.cfg.MaxBytes), res.Request.URL)
+}
@@ -0,0 +1,128 @@
+package unfurl
+
+import (
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const maxFieldLength = 300
+
+// Preview is the metadata shown for a link, taken from the OpenGraph and
+// Twitter card tags with the title and description tags as fallback.
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+func (p Preview) IsEmpty() bool {
+	return p.Title == "" && p.Description == "" && p.ImageURL == ""
+}
+
+// Parse reads the metadata from the document head. pageURL is the final
+// URL of the page, used to resolve relative image URLs.
+func Parse(body io.Reader, pageURL *url.URL) (Preview, error) {
+	meta := map[string]string{}
+	var title strings.Builder
+	inTitle := false
+
+	tokenizer := html.NewTokenizer(body)
+	for {
+		tokenType := tokenizer.Next()
+		switch tokenType {
+		case html.ErrorToken:
+			// A truncated page still has the head we care about
+			if err := tokenizer.Err(); err != nil && !errors.Is(err, io.EOF) {
+				return Preview{}, err
+			}
+			return buildPreview(meta, title.String(), pageURL), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "meta":
+				key, content := metaTag(token)
+				if _, found := meta[key]; key != "" && !found {
+					meta[key] = content
+				}
+			case "title":
+				inTitle = tokenType == html.StartTagToken
+			case "body":
+				return buildPreview(meta, title.String(), pageURL), nil
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "title" {
+				inTitle = false
+			} else if string(name) == "head" {
+				return buildPreview(meta, title.String(), pageURL), nil
+			}
+		}
+	}
+}
+
+// metaTag returns the lowercased property or name of a meta tag and its
+// content.
+func metaTag(token html.Token) (string, string) {
+	var key, content string
+	for _, attr := range token.Attr {
+		switch attr.Key {
+		case "property", "name":
+			if key == "" {
+				key = strings.ToLower(strings.TrimSpace(attr.Val))
+			}
+		case "content":
+			content = attr.Val
+		}
+	}
+	return key, content
+}
+
+func buildPreview(meta map[string]string, title string, pageURL *url.URL) Preview {
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if value := cleanField(meta[key]); value != "" {
+				return value
+			}
+		}
+		return ""
+	}
+
+	preview := Preview{
+		URL:         pageURL.String(),
+		Title:       first("og:title", "twitter:title"),
+		Description: first("og:description", "twitter:description", "description"),
+		SiteName:    first("og:site_name"),
+	}
+	if preview.Title == "" {
+		preview.Title = cleanField(title)
+	}
+	if canonical := first("og:url"); canonical != "" {
+		if canonicalURL, err := pageURL.Parse(canonical); err == nil && (canonicalURL.Scheme == "http" || canonicalURL.Scheme == "https") {
+			preview.URL = canonicalURL.String()
+		}
+	}
+	if image := first("og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if imageURL, err := pageURL.Parse(image); err == nil && (imageURL.Scheme == "http" || imageURL.Scheme == "https") {
+			preview.ImageURL = imageURL.String()
+		}
+	}
+	return preview
+}
+
+func cleanField(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	runes := []rune(value)
+	if len(runes) > maxFieldLength {
+		return string(runes[:maxFieldLength-1]) + "…"
+	}
+	return value
+}
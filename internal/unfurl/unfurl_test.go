@@ -0,0 +1,215 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFetcher() *Fetcher {
+	cfg := DefaultConfig
+	cfg.Timeout = time.Second
+	cfg.AllowPrivateNetworks = true
+	return NewFetcher(cfg)
+}
+
+func htmlHandler(body string) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(res, body)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/og", htmlHandler(`<html><head>
+		<title>Fallback title</title>
+		<meta property="og:title" content="  OpenGraph   title ">
+		<meta property="og:description" content="OpenGraph description">
+		<meta property="og:image" content="/images/cover.png">
+		<meta property="og:site_name" content="Example">
+		<meta name="twitter:title" content="Twitter title">
+	</head><body><meta property="og:title" content="Ignored"></body></html>`))
+	mux.HandleFunc("/twitter", htmlHandler(`<html><head>
+		<meta name="twitter:title" content="Twitter title">
+		<meta name="twitter:image" content="https://cdn.example.com/card.jpg">
+		<meta name="description" content="Plain description">
+	</head></html>`))
+	mux.HandleFunc("/title", htmlHandler(`<html><head><title>Just a title</title></head></html>`))
+	mux.HandleFunc("/redirect", func(res http.ResponseWriter, req *http.Request) {
+		http.Redirect(res, req, "/og", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		path string
+		want Preview
+	}{
+		{
+			name: "OpenGraph tags",
+			path: "/og",
+			want: Preview{
+				URL:         server.URL + "/og",
+				Title:       "OpenGraph title",
+				Description: "OpenGraph description",
+				ImageURL:    server.URL + "/images/cover.png",
+				SiteName:    "Example",
+			},
+		},
+		{
+			name: "Twitter card tags",
+			path: "/twitter",
+			want: Preview{
+				URL:         server.URL + "/twitter",
+				Title:       "Twitter title",
+				Description: "Plain description",
+				ImageURL:    "https://cdn.example.com/card.jpg",
+			},
+		},
+		{
+			name: "Title tag fallback",
+			path: "/title",
+			want: Preview{URL: server.URL + "/title", Title: "Just a title"},
+		},
+		{
+			name: "Follows redirects",
+			path: "/redirect",
+			want: Preview{
+				URL:         server.URL + "/og",
+				Title:       "OpenGraph title",
+				Description: "OpenGraph description",
+				ImageURL:    server.URL + "/images/cover.png",
+				SiteName:    "Example",
+			},
+		},
+	}
+
+	fetcher := testFetcher()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetcher.Fetch(context.Background(), server.URL+tt.path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Fetch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchErrors(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(res http.ResponseWriter, req *http.Request) {
+		res.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(res, `{}`)
+	})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/slow", func(res http.ResponseWriter, req *http.Request) {
+		select {
+		case <-time.After(3 * time.Second):
+		case <-req.Context().Done():
+		}
+	})
+	mux.HandleFunc("/loop", func(res http.ResponseWriter, req *http.Request) {
+		http.Redirect(res, req, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/file", func(res http.ResponseWriter, req *http.Request) {
+		http.Redirect(res, req, "file:///etc/passwd", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	fetcher := testFetcher()
+	for _, target := range []string{
+		server.URL + "/json",
+		server.URL + "/missing",
+		server.URL + "/slow",
+		server.URL + "/loop",
+		server.URL + "/file",
+		"file:///etc/passwd",
+		"gopher://example.com",
+	} {
+		t.Run(target, func(t *testing.T) {
+			_, err := fetcher.Fetch(context.Background(), target)
+			if err == nil {
+				t.Errorf("Expected an error fetching %s", target)
+			}
+		})
+	}
+}
+
+func TestFetchBlocksPrivateNetworks(t *testing.T) {
+	server := httptest.NewServer(htmlHandler(`<title>Internal</title>`))
+	defer server.Close()
+
+	fetcher := NewFetcher(DefaultConfig)
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Expected ErrBlockedAddress fetching a loopback server, got %v", err)
+	}
+
+	redirector := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		http.Redirect(res, req, server.URL, http.StatusFound)
+	}))
+	defer redirector.Close()
+	_, err = fetcher.Fetch(context.Background(), redirector.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Expected ErrBlockedAddress following a redirect, got %v", err)
+	}
+}
+
+func TestFetchSizeLimit(t *testing.T) {
+	padding := strings.Repeat("<!-- padding -->", 1024)
+	server := httptest.NewServer(htmlHandler(`<html><head><title>Early title</title>` + padding + `<meta property="og:description" content="Too far">`))
+	defer server.Close()
+
+	cfg := DefaultConfig
+	cfg.AllowPrivateNetworks = true
+	cfg.MaxBytes = 1024
+	preview, err := NewFetcher(cfg).Fetch(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if preview.Title != "Early title" || preview.Description != "" {
+		t.Errorf("Expected only the metadata within the size limit, got %+v", preview)
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		blocked bool
+	}{
+		{address: "93.184.215.14:443"},
+		{address: "[2606:2800:21f:cb07:6820:80da:af6b:8b2c]:443"},
+		{address: "127.0.0.1:80", blocked: true},
+		{address: "10.1.2.3:80", blocked: true},
+		{address: "172.16.0.1:80", blocked: true},
+		{address: "192.168.1.1:80", blocked: true},
+		{address: "169.254.169.254:80", blocked: true},
+		{address: "100.64.0.1:80", blocked: true},
+		{address: "0.0.0.0:80", blocked: true},
+		{address: "[::1]:80", blocked: true},
+		{address: "[fd00::1]:80", blocked: true},
+		{address: "[fe80::1]:80", blocked: true},
+		{address: "[::ffff:127.0.0.1]:80", blocked: true},
+		{address: "224.0.0.1:80", blocked: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			err := checkAddress(tt.address)
+			if (err != nil) != tt.blocked {
+				t.Errorf("checkAddress(%s) error = %v, blocked %v", tt.address, err, tt.blocked)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/chirptext"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+const (
+	// Previews stuck in processing longer than this are picked up again
+	linkPreviewStaleAfter = 5 * time.Minute
+	linkPreviewBatchSize  = 20
+)
+
+type LinkPreview struct {
+	URL          string `json:"url"`
+	CanonicalURL string `json:"canonical_url,omitempty"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	ImageURL     string `json:"image_url,omitempty"`
+	SiteName     string `json:"site_name,omitempty"`
+}
+
+// saveChirpLinks replaces the links of the chirp with the ones in its body
+// and queues the previews that were never fetched. Previews are shared by
+// every chirp linking the same URL.
+func saveChirpLinks(ctx context.Context, queries *database.Queries, chirpID uuid.UUID, body string) error {
+	err := queries.DeleteChirpLinks(ctx, chirpID)
+	if err != nil {
+		return fmt.Errorf("error deleting chirp links: %w", err)
+	}
+
+	for i, url := range chirptext.URLs(body) {
+		err = queries.CreateLinkPreview(ctx, url)
+		if err != nil {
+			return fmt.Errorf("error creating link preview: %w", err)
+		}
+		err = queries.CreateChirpLink(ctx, database.CreateChirpLinkParams{ChirpID: chirpID, Url: url, Position: int32(i)})
+		if err != nil {
+			return fmt.Errorf("error creating chirp link: %w", err)
+		}
+	}
+	return nil
+}
+
+// setLinkPreviews attaches the preview of the first link with one.
+func (cfg *apiConfig) setLinkPreviews(ctx context.Context, chirps []Chirp) error {
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	rows, err := cfg.dbQueries.GetChirpsLinkPreviews(ctx, chirpIDs)
+	if err != nil {
+		return fmt.Errorf("error getting link previews: %w", err)
+	}
+	previews := map[uuid.UUID]*LinkPreview{}
+	for _, row := range rows {
+		if _, found := previews[row.ChirpID]; found {
+			continue
+		}
+		previews[row.ChirpID] = &LinkPreview{
+			URL:          row.LinkPreview.Url,
+			CanonicalURL: row.LinkPreview.CanonicalUrl,
+			Title:        row.LinkPreview.Title,
+			Description:  row.LinkPreview.Description,
+			ImageURL:     row.LinkPreview.ImageUrl,
+			SiteName:     row.LinkPreview.SiteName,
+		}
+	}
+
+	for i := range chirps {
+		chirps[i].LinkPreview = previews[chirps[i].ID]
+	}
+	return nil
+}
+
+// processLinkPreviews fetches the pending previews. They are claimed with
+// SKIP LOCKED so each URL is fetched by a single replica.
+func (cfg *apiConfig) processLinkPreviews(ctx context.Context) error {
+	for range linkPreviewBatchSize {
+		linkPreview, err := cfg.dbQueries.ClaimLinkPreview(ctx, time.Now().Add(-linkPreviewStaleAfter))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil
+			}
+			return fmt.Errorf("error claiming link preview: %w", err)
+		}
+
+		preview, err := cfg.linkFetcher.Fetch(ctx, linkPreview.Url)
+		if err == nil && preview.IsEmpty() {
+			err = fmt.Errorf("no metadata found")
+		}
+		if err != nil {
+			fmt.Printf("Error fetching link preview %s: %v\n", linkPreview.Url, err)
+			err = cfg.dbQueries.FailLinkPreview(ctx, linkPreview.Url)
+			if err != nil {
+				fmt.Printf("Error marking link preview %s as failed: %v\n", linkPreview.Url, err)
+			}
+			continue
+		}
+
+		err = cfg.dbQueries.CompleteLinkPreview(ctx, database.CompleteLinkPreviewParams{
+			Url:          linkPreview.Url,
+			Title:        preview.Title,
+			Description:  preview.Description,
+			ImageUrl:     preview.ImageURL,
+			SiteName:     preview.SiteName,
+			CanonicalUrl: preview.URL,
+		})
+		if err != nil {
+			return fmt.Errorf("error completing link preview: %w", err)
+		}
+	}
+	return nil
+}
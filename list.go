@@ -0,0 +1,591 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/chirptext"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/pagination"
+)
+
+const (
+	maxListNameLength        = 25
+	maxListDescriptionLength = 100
+	maxLists                 = 100
+	maxListMembers           = 500
+)
+
+const (
+	listVisibilityPublic  = "public"
+	listVisibilityPrivate = "private"
+)
+
+type List struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	OwnerID     uuid.UUID `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Visibility  string    `json:"visibility"`
+	Subscribers int64     `json:"subscribers"`
+}
+
+type ListRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// private (default) or public
+	Visibility string `json:"visibility"`
+}
+
+type ListMemberRequest struct {
+	UserID uuid.UUID `json:"user_id"`
+}
+
+type ListChirpsPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+func decodeListRequest(res http.ResponseWriter, req *http.Request) (database.CreateListParams, bool) {
+	var reqBody ListRequest
+	err := json.NewDecoder(req.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Error decoding body, name field expected")
+		return database.CreateListParams{}, false
+	}
+
+	name := chirptext.Normalize(strings.TrimSpace(reqBody.Name))
+	if name == "" || chirptext.CheckSize(name) != nil || chirptext.Length(name) > maxListNameLength {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("List name must have between 1 and %d characters", maxListNameLength))
+		return database.CreateListParams{}, false
+	}
+	description := chirptext.Normalize(strings.TrimSpace(reqBody.Description))
+	if chirptext.CheckSize(description) != nil || chirptext.Length(description) > maxListDescriptionLength {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("List description is too long, the limit is %d characters", maxListDescriptionLength))
+		return database.CreateListParams{}, false
+	}
+
+	visibility := reqBody.Visibility
+	if visibility == "" {
+		visibility = listVisibilityPrivate
+	}
+	if visibility != listVisibilityPublic && visibility != listVisibilityPrivate {
+		respondWithError(res, http.StatusBadRequest, "Invalid visibility, it must be public or private")
+		return database.CreateListParams{}, false
+	}
+
+	return database.CreateListParams{Name: name, Description: description, Visibility: visibility}, true
+}
+
+// toLists adds the subscriber count of each list.
+func (cfg *apiConfig) toLists(ctx context.Context, lists []database.List) ([]List, error) {
+	ids := make([]uuid.UUID, len(lists))
+	for i, list := range lists {
+		ids[i] = list.ID
+	}
+	counts, err := cfg.dbQueries.CountListSubscribers(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("error counting subscribers: %w", err)
+	}
+	subscribers := map[uuid.UUID]int64{}
+	for _, count := range counts {
+		subscribers[count.ListID] = count.Subscribers
+	}
+
+	response := make([]List, len(lists))
+	for i, list := range lists {
+		response[i] = List{
+			ID:          list.ID,
+			CreatedAt:   list.CreatedAt,
+			UpdatedAt:   list.UpdatedAt,
+			OwnerID:     list.OwnerID,
+			Name:        list.Name,
+			Description: list.Description,
+			Visibility:  list.Visibility,
+			Subscribers: subscribers[list.ID],
+		}
+	}
+	return response, nil
+}
+
+func (cfg *apiConfig) respondWithLists(res http.ResponseWriter, req *http.Request, lists []database.List) {
+	response, err := cfg.toLists(req.Context(), lists)
+	if err != nil {
+		fmt.Printf("Error getting lists data: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting lists")
+		return
+	}
+	respondWithJSON(res, http.StatusOK, response)
+}
+
+func (cfg *apiConfig) respondWithList(res http.ResponseWriter, req *http.Request, code int, list database.List) {
+	response, err := cfg.toLists(req.Context(), []database.List{list})
+	if err != nil {
+		fmt.Printf("Error getting list data: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting list")
+		return
+	}
+	respondWithJSON(res, code, response[0])
+}
+
+// getVisibleList gets the list in the path, private lists of other users
+// are reported as not found.
+func (cfg *apiConfig) getVisibleList(res http.ResponseWriter, req *http.Request) (database.List, bool) {
+	listID, err := uuid.Parse(req.PathValue("listID"))
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Invalid list ID, it must be a UUID")
+		return database.List{}, false
+	}
+
+	list, err := cfg.dbQueries.GetVisibleList(req.Context(), database.GetVisibleListParams{ID: listID, ViewerID: viewerID(req)})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(res, http.StatusNotFound, "List not found")
+			return database.List{}, false
+		}
+		fmt.Printf("Error getting list: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting list")
+		return database.List{}, false
+	}
+	return list, true
+}
+
+// getOwnedList gets the list in the path, only its owner can manage it.
+func (cfg *apiConfig) getOwnedList(res http.ResponseWriter, req *http.Request, userID uuid.UUID) (database.List, bool) {
+	list, ok := cfg.getVisibleList(res, req)
+	if !ok {
+		return database.List{}, false
+	}
+	if list.OwnerID != userID {
+		respondWithError(res, http.StatusForbidden, "Only the list owner can manage it")
+		return database.List{}, false
+	}
+	return list, true
+}
+
+func createListHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		params, ok := decodeListRequest(res, req)
+		if !ok {
+			return
+		}
+		params.OwnerID = userID
+
+		count, err := cfg.dbQueries.CountLists(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error counting lists: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating list")
+			return
+		}
+		if count >= maxLists {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("You can't have more than %d lists", maxLists))
+			return
+		}
+
+		list, err := cfg.dbQueries.CreateList(req.Context(), params)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusConflict, "A list with this name already exists")
+				return
+			}
+			fmt.Printf("Error creating list: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating list")
+			return
+		}
+
+		cfg.respondWithList(res, req, http.StatusCreated, list)
+	}
+}
+
+func getListsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		lists, err := cfg.dbQueries.GetListsByOwner(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting lists: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting lists")
+			return
+		}
+
+		cfg.respondWithLists(res, req, lists)
+	}
+}
+
+// getSubscribedListsHandler lists the public lists the user subscribed to,
+// lists made private since then are left out.
+func getSubscribedListsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		lists, err := cfg.dbQueries.GetSubscribedLists(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting subscribed lists: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting lists")
+			return
+		}
+
+		cfg.respondWithLists(res, req, lists)
+	}
+}
+
+func getListHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		list, ok := cfg.getVisibleList(res, req)
+		if !ok {
+			return
+		}
+
+		cfg.respondWithList(res, req, http.StatusOK, list)
+	}
+}
+
+// updateListHandler replaces the list details. Making a list private
+// removes its subscribers.
+func updateListHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		params, ok := decodeListRequest(res, req)
+		if !ok {
+			return
+		}
+
+		list, ok := cfg.getOwnedList(res, req, userID)
+		if !ok {
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating list")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		list, err = queries.UpdateList(req.Context(), database.UpdateListParams{
+			ID:          list.ID,
+			OwnerID:     userID,
+			Name:        params.Name,
+			Description: params.Description,
+			Visibility:  params.Visibility,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusConflict, "A list with this name already exists")
+				return
+			}
+			fmt.Printf("Error updating list: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating list")
+			return
+		}
+		if list.Visibility == listVisibilityPrivate {
+			err = queries.DeleteListSubscriptions(req.Context(), list.ID)
+			if err != nil {
+				fmt.Printf("Error removing list subscriptions: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error updating list")
+				return
+			}
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing list: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating list")
+			return
+		}
+
+		cfg.respondWithList(res, req, http.StatusOK, list)
+	}
+}
+
+func deleteListHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		listID, err := uuid.Parse(req.PathValue("listID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid list ID, it must be a UUID")
+			return
+		}
+
+		deleted, err := cfg.dbQueries.DeleteList(req.Context(), database.DeleteListParams{ID: listID, OwnerID: userID})
+		if err != nil {
+			fmt.Printf("Error deleting list: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error deleting list")
+			return
+		}
+		if deleted == 0 {
+			respondWithError(res, http.StatusNotFound, "List not found")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func getListMembersHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		list, ok := cfg.getVisibleList(res, req)
+		if !ok {
+			return
+		}
+
+		members, err := cfg.dbQueries.GetListMembers(req.Context(), list.ID)
+		if err != nil {
+			fmt.Printf("Error getting list members: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting list members")
+			return
+		}
+
+		response := make([]RelatedUser, len(members))
+		for i, member := range members {
+			response[i] = RelatedUser{UserID: member.UserID, CreatedAt: member.CreatedAt}
+		}
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+// addListMemberHandler adds a user to the list, users who blocked the
+// list owner can't be added. The list row is locked while counting members
+// so concurrent adds can't go over the limit.
+func addListMemberHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody ListMemberRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, user_id field expected")
+			return
+		}
+
+		list, ok := cfg.getOwnedList(res, req, userID)
+		if !ok {
+			return
+		}
+
+		member, err := cfg.dbQueries.GetUserByID(req.Context(), reqBody.UserID)
+		if err != nil && err != sql.ErrNoRows {
+			fmt.Printf("Error getting user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting user")
+			return
+		}
+		if err == sql.ErrNoRows || member.DeletedAt.Valid {
+			respondWithError(res, http.StatusNotFound, "User not found")
+			return
+		}
+		blocked, err := cfg.dbQueries.IsBlocked(req.Context(), database.IsBlockedParams{BlockerID: member.ID, BlockedID: userID})
+		if err != nil {
+			fmt.Printf("Error checking block: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding list member")
+			return
+		}
+		if blocked {
+			respondWithError(res, http.StatusForbidden, "You can't add this user to a list")
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding list member")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		// Locking the list serializes concurrent adds so the limit holds
+		_, err = queries.GetListForUpdate(req.Context(), list.ID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "List not found")
+				return
+			}
+			fmt.Printf("Error locking list: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding list member")
+			return
+		}
+		count, err := queries.CountListMembers(req.Context(), list.ID)
+		if err != nil {
+			fmt.Printf("Error counting list members: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding list member")
+			return
+		}
+		if count >= maxListMembers {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Lists can't have more than %d members", maxListMembers))
+			return
+		}
+
+		_, err = queries.AddListMember(req.Context(), database.AddListMemberParams{ListID: list.ID, UserID: member.ID})
+		if err != nil {
+			fmt.Printf("Error adding list member: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding list member")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing list member: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error adding list member")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func removeListMemberHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		memberID, err := uuid.Parse(req.PathValue("userID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+			return
+		}
+
+		list, ok := cfg.getOwnedList(res, req, userID)
+		if !ok {
+			return
+		}
+
+		removed, err := cfg.dbQueries.RemoveListMember(req.Context(), database.RemoveListMemberParams{ListID: list.ID, UserID: memberID})
+		if err != nil {
+			fmt.Printf("Error removing list member: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error removing list member")
+			return
+		}
+		if removed == 0 {
+			respondWithError(res, http.StatusNotFound, "Member not found")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+// getListChirpsHandler returns the timeline of the list members' chirps,
+// newest first, with the same visibility rules and filters as GET
+// /api/chirps.
+func getListChirpsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		limit, err := pagination.ParseLimit(query.Get("limit"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid limit, it must be a positive number")
+			return
+		}
+
+		list, ok := cfg.getVisibleList(res, req)
+		if !ok {
+			return
+		}
+
+		viewer := viewerID(req)
+		params := database.GetListChirpsParams{ListID: list.ID, ViewerID: viewer, PageLimit: int32(limit + 1)}
+		if rawCursor := query.Get("cursor"); rawCursor != "" {
+			cursor, err := pagination.DecodeCursor(rawCursor)
+			if err != nil {
+				respondWithError(res, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+			params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+
+		chirps, err := cfg.dbQueries.GetListChirps(req.Context(), params)
+		if err != nil {
+			fmt.Printf("Error getting list chirps: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirps")
+			return
+		}
+
+		page := ListChirpsPage{}
+		if len(chirps) > limit {
+			chirps = chirps[:limit]
+			last := chirps[len(chirps)-1]
+			page.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+		}
+
+		page.Chirps, err = cfg.toChirpsForViewer(req, chirps)
+		if err != nil {
+			fmt.Printf("Error getting chirps viewer data: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirps")
+			return
+		}
+		if viewer.Valid {
+			page.Chirps, err = cfg.applyMuteFilters(req.Context(), viewer.UUID, page.Chirps)
+			if err != nil {
+				fmt.Printf("Error applying mute filters: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error getting chirps")
+				return
+			}
+		}
+
+		respondWithJSON(res, http.StatusOK, page)
+	}
+}
+
+// subscribeToListHandler subscribes the user to another user's public list.
+func subscribeToListHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		list, ok := cfg.getVisibleList(res, req)
+		if !ok {
+			return
+		}
+		if list.OwnerID == userID {
+			respondWithError(res, http.StatusBadRequest, "You can't subscribe to your own list")
+			return
+		}
+		if list.Visibility != listVisibilityPublic {
+			respondWithError(res, http.StatusNotFound, "List not found")
+			return
+		}
+
+		err := cfg.dbQueries.SubscribeToList(req.Context(), database.SubscribeToListParams{ListID: list.ID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error subscribing to list: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error subscribing to list")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func unsubscribeFromListHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		listID, err := uuid.Parse(req.PathValue("listID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid list ID, it must be a UUID")
+			return
+		}
+
+		err = cfg.dbQueries.UnsubscribeFromList(req.Context(), database.UnsubscribeFromListParams{ListID: listID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error unsubscribing from list: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error unsubscribing from list")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var listColumns = []string{"id", "created_at", "updated_at", "owner_id", "name", "description", "visibility"}
+
+func listRows(id, ownerID uuid.UUID, visibility string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(listColumns).AddRow(id.String(), now, now, ownerID.String(), "Cooks", "", visibility)
+}
+
+func TestDecodeListRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		listName string
+		want     string
+		wantErr  bool
+	}{
+		{name: "Name is trimmed", listName: "  Cooks  ", want: "Cooks"},
+		{name: "Name is normalized", listName: "Cafe\u0301", want: "Caf\u00e9"},
+		{name: "Emoji count once", listName: strings.Repeat("🧪", maxListNameLength), want: strings.Repeat("🧪", maxListNameLength)},
+		{name: "Combining marks count once", listName: strings.Repeat("e\u0301\u0301", maxListNameLength), want: strings.Repeat("\u00e9\u0301", maxListNameLength)},
+		{name: "Too long", listName: strings.Repeat("a", maxListNameLength+1), wantErr: true},
+		{name: "Empty", listName: "   ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/lists", strings.NewReader(`{"name": "`+tt.listName+`"}`))
+			rec := httptest.NewRecorder()
+			got, ok := decodeListRequest(rec, req)
+			if ok == tt.wantErr {
+				t.Fatalf("decodeListRequest() ok = %v, wantErr %v", ok, tt.wantErr)
+			}
+			if got.Name != tt.want {
+				t.Errorf("decodeListRequest() name = %q, want %q", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddListMemberLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		members    int
+		wantStatus int
+	}{
+		{name: "Under the limit", members: maxListMembers - 1, wantStatus: http.StatusNoContent},
+		{name: "At the limit", members: maxListMembers, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			member := newTestUser("jesse@example.com", unusablePasswordHash)
+			listID := uuid.New()
+
+			expectQuery(mock, "GetVisibleList").WithArgs(listID, uuid.NullUUID{UUID: userID, Valid: true}).
+				WillReturnRows(listRows(listID, userID, listVisibilityPrivate))
+			expectQuery(mock, "GetUserByID").WithArgs(member.ID).WillReturnRows(userRows(member))
+			expectQuery(mock, "IsBlocked").WithArgs(member.ID, userID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			// Members are counted with the list locked
+			mock.ExpectBegin()
+			expectQuery(mock, "GetListForUpdate").WithArgs(listID).WillReturnRows(listRows(listID, userID, listVisibilityPrivate))
+			expectQuery(mock, "CountListMembers").WithArgs(listID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.members))
+			if tt.wantStatus == http.StatusNoContent {
+				expectExec(mock, "AddListMember").WithArgs(listID, member.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/lists/"+listID.String()+"/members", strings.NewReader(`{"user_id": "`+member.ID.String()+`"}`))
+			req.SetPathValue("listID", listID.String())
+			rec := httptest.NewRecorder()
+			addListMemberHandler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
+
+func TestGetPrivateList(t *testing.T) {
+	tests := []struct {
+		name       string
+		viewer     bool
+		owner      bool
+		wantStatus int
+	}{
+		// Other users' private lists aren't returned by GetVisibleList
+		{name: "Another user", viewer: true, wantStatus: http.StatusNotFound},
+		{name: "Anonymous caller", wantStatus: http.StatusNotFound},
+		{name: "Owner", viewer: true, owner: true, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			ownerID := uuid.New()
+			listID := uuid.New()
+
+			req := httptest.NewRequest(http.MethodGet, "/api/lists/"+listID.String(), nil)
+			req.SetPathValue("listID", listID.String())
+			viewerID := uuid.NullUUID{}
+			if tt.viewer {
+				viewerID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+				if tt.owner {
+					viewerID.UUID = ownerID
+				}
+				req = req.WithContext(withUser(req.Context(), viewerID.UUID))
+			}
+
+			rows := sqlmock.NewRows(listColumns)
+			if tt.owner {
+				rows = listRows(listID, ownerID, listVisibilityPrivate)
+			}
+			expectQuery(mock, "GetVisibleList").WithArgs(listID, viewerID).WillReturnRows(rows)
+			if tt.owner {
+				expectQuery(mock, "CountListSubscribers").WillReturnRows(sqlmock.NewRows([]string{"list_id", "subscribers"}))
+			}
+
+			rec := httptest.NewRecorder()
+			getListHandler(cfg)(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
+
+func TestUpdateListVisibility(t *testing.T) {
+	tests := []struct {
+		name               string
+		visibility         string
+		dropsSubscriptions bool
+	}{
+		{name: "Made private", visibility: listVisibilityPrivate, dropsSubscriptions: true},
+		{name: "Kept public", visibility: listVisibilityPublic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			listID := uuid.New()
+
+			expectQuery(mock, "GetVisibleList").WithArgs(listID, uuid.NullUUID{UUID: userID, Valid: true}).
+				WillReturnRows(listRows(listID, userID, listVisibilityPublic))
+			mock.ExpectBegin()
+			expectQuery(mock, "UpdateList").WithArgs(listID, userID, "Cooks", "", tt.visibility).
+				WillReturnRows(listRows(listID, userID, tt.visibility))
+			if tt.dropsSubscriptions {
+				expectExec(mock, "DeleteListSubscriptions").WithArgs(listID).WillReturnResult(sqlmock.NewResult(0, 3))
+			}
+			mock.ExpectCommit()
+			expectQuery(mock, "CountListSubscribers").WillReturnRows(sqlmock.NewRows([]string{"list_id", "subscribers"}))
+
+			req := httptest.NewRequest(http.MethodPut, "/api/lists/"+listID.String(), strings.NewReader(`{"name": "Cooks", "visibility": "`+tt.visibility+`"}`))
+			req.SetPathValue("listID", listID.String())
+			rec := httptest.NewRecorder()
+			updateListHandler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected status %d, got %d %s", http.StatusOK, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+const (
+	accountThrottlePrefix = "account:"
+	ipThrottlePrefix      = "ip:"
+)
+
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
+)
+
+// checkPasswordForUnknownUser burns the same time a real password check
+// would, so response times don't reveal which emails are registered.
+func (cfg *apiConfig) checkPasswordForUnknownUser(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		secret, _ := auth.MakeRefreshToken()
+		dummyPasswordHash, _ = cfg.passwordHasher.Hash(secret)
+	})
+	cfg.passwordHasher.Check(password, dummyPasswordHash)
+}
+
+func accountThrottleKey(email string) string {
+	return accountThrottlePrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return ipThrottlePrefix + host
+}
+
+// loginLockedFor returns the remaining lockout of the most restrictive key.
+func (cfg *apiConfig) loginLockedFor(ctx context.Context, keys ...string) (time.Duration, error) {
+	var remaining time.Duration
+	for _, key := range keys {
+		throttle, err := cfg.dbQueries.GetLoginThrottle(ctx, key)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return 0, err
+		}
+		if !throttle.LockedUntil.Valid {
+			continue
+		}
+		if left := time.Until(throttle.LockedUntil.Time); left > remaining {
+			remaining = left
+		}
+	}
+	return remaining, nil
+}
+
+func (cfg *apiConfig) recordLoginFailure(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		throttle, err := cfg.dbQueries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+			Key:         key,
+			ResetBefore: time.Now().Add(-cfg.lockoutPolicy.Window),
+		})
+		if err != nil {
+			fmt.Printf("Error recording failed login for %s: %v\n", key, err)
+			continue
+		}
+
+		lockout := cfg.lockoutPolicy.LockoutFor(int(throttle.FailedAttempts))
+		if lockout == 0 {
+			continue
+		}
+		err = cfg.dbQueries.LockLoginThrottle(ctx, database.LockLoginThrottleParams{
+			Key:         key,
+			LockedUntil: sql.NullTime{Time: time.Now().Add(lockout), Valid: true},
+		})
+		if err != nil {
+			fmt.Printf("Error locking %s: %v\n", key, err)
+		}
+	}
+}
+
+func respondWithLockout(res http.ResponseWriter, remaining time.Duration) {
+	seconds := int(remaining.Round(time.Second) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	res.Header().Set("Retry-After", strconv.Itoa(seconds))
+	respondWithError(res, http.StatusTooManyRequests, "Too many failed login attempts, try again later")
+}
+
+type LockedAccount struct {
+	Email          string    `json:"email"`
+	FailedAttempts int32     `json:"failed_attempts"`
+	LockedUntil    time.Time `json:"locked_until"`
+}
+
+func getLockedAccountsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		throttles, err := cfg.dbQueries.GetLockedLoginThrottles(req.Context(), accountThrottlePrefix)
+		if err != nil {
+			fmt.Printf("Error getting locked accounts: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting locked accounts")
+			return
+		}
+
+		response := make([]LockedAccount, len(throttles))
+		for i, throttle := range throttles {
+			response[i] = LockedAccount{
+				Email:          strings.TrimPrefix(throttle.Key, accountThrottlePrefix),
+				FailedAttempts: throttle.FailedAttempts,
+				LockedUntil:    throttle.LockedUntil.Time,
+			}
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+func unlockAccountHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		err := cfg.dbQueries.ClearLoginThrottle(req.Context(), accountThrottleKey(req.PathValue("email")))
+		if err != nil {
+			fmt.Printf("Error unlocking account: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error unlocking account")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/ivportilla/chirpy/internal/auth"
 	"github.com/ivportilla/chirpy/internal/database"
 )
@@ -15,28 +16,107 @@ import (
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// "cookie" stores the session in HttpOnly cookies instead of returning the tokens
+	SessionMode string `json:"session_mode,omitempty"`
 }
 
+const (
+	accessTokenExpiresIn  = 3600 * time.Second
+	refreshTokenExpiresIn = 60 * 24 * time.Hour
+)
+
 func (cfg *apiConfig) withAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-		token, err := auth.GetBearerToken(req.Header)
+		token, fromCookie, err := getSessionToken(req, accessTokenCookie)
 		if err != nil {
+			if fromCookie {
+				respondWithError(res, http.StatusForbidden, "Invalid CSRF token")
+				return
+			}
 			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
 
-		userID, err := auth.ValidateJWT(token, cfg.authSecret)
+		claims, ok := cfg.authenticate(res, req, token)
+		if !ok {
+			return
+		}
+
+		ctx := context.WithValue(req.Context(), "user_id", claims.UserID.String())
+
+		next.ServeHTTP(res, req.WithContext(ctx))
+	})
+}
+
+// authenticate validates an access token and checks its session is still
+// active, revoked sessions and accounts pending deletion are rejected. It
+// responds itself when it fails.
+func (cfg *apiConfig) authenticate(res http.ResponseWriter, req *http.Request, token string) (auth.AccessClaims, bool) {
+	claims, err := auth.ValidateJWT(token, cfg.authSecret)
+	if err != nil {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return auth.AccessClaims{}, false
+	}
+
+	active, err := cfg.isSessionActive(req.Context(), claims)
+	if err != nil {
+		fmt.Printf("Error getting session: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting session")
+		return auth.AccessClaims{}, false
+	}
+	if !active {
+		respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+		return auth.AccessClaims{}, false
+	}
+	return claims, true
+}
+
+// isSessionActive reports whether the session of the access token can
+// still be used.
+func (cfg *apiConfig) isSessionActive(ctx context.Context, claims auth.AccessClaims) (bool, error) {
+	session, err := cfg.dbQueries.GetActiveSession(ctx, claims.SessionID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return session.UserID == claims.UserID, nil
+}
+
+// withOptionalAuthMiddleware lets anonymous requests through to read
+// endpoints that return extra per-user data when the caller is logged in.
+// A token that is sent but invalid is still rejected so clients know to
+// refresh it.
+func (cfg *apiConfig) withOptionalAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		token, _, err := getSessionToken(req, accessTokenCookie)
 		if err != nil {
-			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			next.ServeHTTP(res, req)
 			return
 		}
 
-		ctx := context.WithValue(req.Context(), "user_id", userID.String())
+		claims, ok := cfg.authenticate(res, req, token)
+		if !ok {
+			return
+		}
+
+		ctx := context.WithValue(req.Context(), "user_id", claims.UserID.String())
 
 		next.ServeHTTP(res, req.WithContext(ctx))
 	})
 }
 
+// viewerID returns the caller of an endpoint behind withOptionalAuthMiddleware,
+// invalid for anonymous requests.
+func viewerID(req *http.Request) uuid.NullUUID {
+	rawUserID, ok := req.Context().Value("user_id").(string)
+	if !ok {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: uuid.MustParse(rawUserID), Valid: true}
+}
+
 func loginHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 	return func(res http.ResponseWriter, req *http.Request) {
 		var reqBody LoginRequest
@@ -46,48 +126,122 @@ func loginHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 			return
 		}
 
+		accountKey := accountThrottleKey(reqBody.Email)
+		ipKey := ipThrottleKey(req)
+		lockedFor, err := cfg.loginLockedFor(req.Context(), accountKey, ipKey)
+		if err != nil {
+			fmt.Printf("Error checking login throttles: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error checking login attempts")
+			return
+		}
+		if lockedFor > 0 {
+			respondWithLockout(res, lockedFor)
+			return
+		}
+
 		user, err := cfg.dbQueries.GetUser(req.Context(), reqBody.Email)
 		if err != nil {
 			if err == sql.ErrNoRows {
-				respondWithError(res, http.StatusNotFound, "User not found")
+				cfg.checkPasswordForUnknownUser(reqBody.Password)
+				cfg.recordLoginFailure(req.Context(), accountKey, ipKey)
+				respondWithError(res, http.StatusUnauthorized, "Incorrect email or password")
 				return
 			}
 			respondWithError(res, http.StatusInternalServerError, "Error getting user")
 			return
 		}
 
-		err = auth.CheckPasswordHash(reqBody.Password, user.HashedPassword)
+		err = cfg.passwordHasher.Check(reqBody.Password, user.HashedPassword)
 		if err != nil {
+			cfg.recordLoginFailure(req.Context(), accountKey, ipKey)
 			respondWithError(res, http.StatusUnauthorized, "Incorrect email or password")
 			return
 		}
 
-		expiresIn := 3600 * time.Second
-		token, err := auth.MakeJWT(user.ID, cfg.authSecret, expiresIn)
-		if err != nil {
-			fmt.Printf("Error creating JWT token: %v", err)
-			respondWithError(res, http.StatusInternalServerError, "Error creating JWT token")
-			return
+		if cfg.passwordHasher.NeedsRehash(user.HashedPassword) {
+			cfg.rehashPassword(req.Context(), user.ID, reqBody.Password)
 		}
 
-		refreshToken, err := auth.MakeRefreshToken()
+		err = cfg.dbQueries.ClearLoginThrottle(req.Context(), accountKey)
 		if err != nil {
-			fmt.Printf("Error creating refresh token: %v", err)
-			respondWithError(res, http.StatusInternalServerError, "Error creating refresh token")
-			return
+			fmt.Printf("Error clearing login throttle: %v\n", err)
 		}
-		err = cfg.dbQueries.CreateRefreshToken(req.Context(), database.CreateRefreshTokenParams{Token: refreshToken, UserID: user.ID, ExpiresAt: time.Now().Add(60 * 24 * time.Hour)})
+
+		cfg.respondWithSession(res, req, user, reqBody.SessionMode)
+	}
+}
+
+// issueTokens creates a new access/refresh token pair for the user, both
+// belong to a new session.
+// Logging in during the deletion grace period restores the account.
+func (cfg *apiConfig) issueTokens(ctx context.Context, user database.User) (string, string, error) {
+	err := cfg.restoreDeletedUser(ctx, user)
+	if err != nil {
+		return "", "", fmt.Errorf("error restoring user: %w", err)
+	}
+
+	sessionID := uuid.New()
+	token, err := auth.MakeJWT(user.ID, sessionID, cfg.authSecret, accessTokenExpiresIn)
+	if err != nil {
+		return "", "", fmt.Errorf("error creating JWT token: %w", err)
+	}
+
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", "", fmt.Errorf("error creating refresh token: %w", err)
+	}
+	err = cfg.dbQueries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		SessionID: sessionID,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(refreshTokenExpiresIn),
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("error saving refresh token: %w", err)
+	}
+
+	return token, refreshToken, nil
+}
+
+// respondWithSession issues a new access/refresh token pair for the user,
+// returned in the body or as cookies depending on the session mode.
+func (cfg *apiConfig) respondWithSession(res http.ResponseWriter, req *http.Request, user database.User, sessionMode string) {
+	token, refreshToken, err := cfg.issueTokens(req.Context(), user)
+	if err != nil {
+		fmt.Printf("Error creating session: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error creating session")
+		return
+	}
+
+	user.DeletedAt = sql.NullTime{}
+	userResponse := ToResponseUser(user)
+	if sessionMode == sessionModeCookie {
+		err = cfg.setSessionCookies(res, token, refreshToken, refreshTokenExpiresIn)
 		if err != nil {
-			fmt.Printf("Error creating saving refresh token: %v", err)
-			respondWithError(res, http.StatusInternalServerError, "Error creating refresh token")
+			fmt.Printf("Error creating session cookies: %v", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating session")
 			return
 		}
-
-		userResponse := ToResponseUser(user)
+	} else {
 		userResponse.Token = token
 		userResponse.RefreshToken = refreshToken
+	}
+
+	respondWithJSON(res, http.StatusOK, userResponse)
+}
 
-		respondWithJSON(res, http.StatusOK, userResponse)
+// rehashPassword transparently upgrades a legacy or under-cost hash, the
+// login goes on even if the upgrade fails.
+func (cfg *apiConfig) rehashPassword(ctx context.Context, userID uuid.UUID, password string) {
+	hashed, err := cfg.passwordHasher.Hash(password)
+	if err != nil {
+		fmt.Printf("Error rehashing password: %v\n", err)
+		return
+	}
+
+	err = cfg.dbQueries.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{ID: userID, HashedPassword: hashed})
+	if err != nil {
+		fmt.Printf("Error saving rehashed password: %v\n", err)
 	}
 }
 
@@ -97,9 +251,9 @@ type RefreshTokenResponse struct {
 
 func refreshTokenHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 	return func(res http.ResponseWriter, req *http.Request) {
-		token, err := auth.GetBearerToken(req.Header)
+		token, fromCookie, err := getSessionToken(req, refreshTokenCookie)
 		if err != nil {
-			fmt.Printf("Error extracting refresh token from auth header: %v", err)
+			fmt.Printf("Error extracting refresh token from request: %v", err)
 			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
@@ -117,20 +271,26 @@ func refreshTokenHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request
 			return
 		}
 
-		newToken, err := auth.MakeJWT(refreshToken.UserID, cfg.authSecret, 3600*time.Second)
+		newToken, err := auth.MakeJWT(refreshToken.UserID, refreshToken.SessionID, cfg.authSecret, accessTokenExpiresIn)
 		if err != nil {
 			respondWithError(res, http.StatusInternalServerError, "Error creating JWT token")
 			return
 		}
+
+		if fromCookie {
+			http.SetCookie(res, cfg.newSessionCookie(accessTokenCookie, newToken, accessTokenExpiresIn, true))
+			respondWithJSON(res, http.StatusNoContent, nil)
+			return
+		}
 		respondWithJSON(res, http.StatusOK, RefreshTokenResponse{Token: newToken})
 	}
 }
 
 func revokeRefreshToken(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 	return func(res http.ResponseWriter, req *http.Request) {
-		token, err := auth.GetBearerToken(req.Header)
+		token, fromCookie, err := getSessionToken(req, refreshTokenCookie)
 		if err != nil {
-			fmt.Printf("Error extracting refresh token from auth header: %v", err)
+			fmt.Printf("Error extracting refresh token from request: %v", err)
 			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
@@ -141,6 +301,14 @@ func revokeRefreshToken(cfg *apiConfig) func(http.ResponseWriter, *http.Request)
 			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
 			return
 		}
+		// Access tokens of the session stop working, and so do its WebSockets
+		if refreshToken, err := cfg.dbQueries.GetRefreshToken(req.Context(), token); err == nil {
+			cfg.disconnectSession(req.Context(), refreshToken.UserID, refreshToken.SessionID, "session revoked")
+		}
+
+		if fromCookie {
+			cfg.clearSessionCookies(res)
+		}
 
 		respondWithJSON(res, http.StatusNoContent, nil)
 	}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/auth"
+)
+
+var refreshTokenColumns = []string{"token", "created_at", "updated_at", "user_id", "expires_at", "revoked_at", "session_id"}
+
+func sessionRows(userID, sessionID uuid.UUID) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(refreshTokenColumns).AddRow("refresh-token", now, now, userID.String(), now.Add(time.Hour), nil, sessionID.String())
+}
+
+func TestAuthenticateSession(t *testing.T) {
+	userID := uuid.New()
+	sessionID := uuid.New()
+
+	tests := []struct {
+		name       string
+		session    func() *sqlmock.Rows
+		wantStatus int
+	}{
+		{name: "Active session", session: func() *sqlmock.Rows { return sessionRows(userID, sessionID) }, wantStatus: http.StatusOK},
+		// GetActiveSession skips revoked sessions and users pending deletion
+		{name: "Revoked session or deleted user", session: func() *sqlmock.Rows { return sqlmock.NewRows(refreshTokenColumns) }, wantStatus: http.StatusUnauthorized},
+		{name: "Session of another user", session: func() *sqlmock.Rows { return sessionRows(uuid.New(), sessionID) }, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			token, err := auth.MakeJWT(userID, sessionID, cfg.authSecret, time.Minute)
+			if err != nil {
+				t.Fatalf("Error creating token: %v", err)
+			}
+
+			handlers := map[string]http.Handler{
+				"Middleware": cfg.withAuthMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+					res.WriteHeader(http.StatusOK)
+				})),
+				"Optional middleware": cfg.withOptionalAuthMiddleware(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+					res.WriteHeader(http.StatusOK)
+				})),
+			}
+			if tt.wantStatus != http.StatusOK {
+				// The WebSocket handler rejects the session before the upgrade
+				handlers["WebSocket"] = http.HandlerFunc(wsHandler(cfg))
+			}
+			for name, handler := range handlers {
+				expectQuery(mock, "GetActiveSession").WithArgs(sessionID).WillReturnRows(tt.session())
+
+				req := httptest.NewRequest(http.MethodGet, "/api/ws", nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+
+				if rec.Code != tt.wantStatus {
+					t.Errorf("%s: expected status %d, got %d %s", name, tt.wantStatus, rec.Code, rec.Body)
+				}
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/mailer"
+)
+
+const (
+	magicLinkThrottlePrefix = "magic:"
+	magicLinkSendTimeout    = 30 * time.Second
+)
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token       string `json:"token"`
+	SessionMode string `json:"session_mode,omitempty"`
+}
+
+// magicLinkURL points to the page that exchanges the token for a session,
+// clients other than the web app can send it to POST /api/login/magic/verify.
+func (cfg *apiConfig) magicLinkURL(token string) string {
+	return fmt.Sprintf("%s/app/login/magic/?token=%s", cfg.publicURL, url.QueryEscape(token))
+}
+
+func requestMagicLinkHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		var reqBody MagicLinkRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil || reqBody.Email == "" {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, email field expected")
+			return
+		}
+
+		// Every request counts against the email, registered or not, so the
+		// limit doesn't reveal which emails have an account
+		limit, err := cfg.dbQueries.HitRateLimit(req.Context(), database.HitRateLimitParams{
+			Key:              magicLinkThrottlePrefix + accountThrottleKey(reqBody.Email),
+			WindowOverBefore: time.Now().Add(-cfg.magicLinkRateWindow),
+		})
+		if err != nil {
+			fmt.Printf("Error recording magic link request: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error requesting magic link")
+			return
+		}
+		if int(limit.Hits) > cfg.magicLinkRateLimit {
+			retryAfter := time.Until(limit.WindowStart.Add(cfg.magicLinkRateWindow))
+			res.Header().Set("Retry-After", fmt.Sprintf("%d", max(1, int(retryAfter.Round(time.Second)/time.Second))))
+			respondWithError(res, http.StatusTooManyRequests, "Too many magic link requests, try again later")
+			return
+		}
+
+		// The user lookup and the email happen after responding, response
+		// times are the same whether the email has an account or not
+		go cfg.sendMagicLink(context.WithoutCancel(req.Context()), reqBody.Email)
+
+		respondWithJSON(res, http.StatusAccepted, nil)
+	}
+}
+
+// sendMagicLink emails a login link to the user with the email, if any.
+func (cfg *apiConfig) sendMagicLink(ctx context.Context, email string) {
+	ctx, cancel := context.WithTimeout(ctx, magicLinkSendTimeout)
+	defer cancel()
+
+	user, err := cfg.dbQueries.GetUser(ctx, email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			fmt.Printf("Error getting user: %v\n", err)
+		}
+		return
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		fmt.Printf("Error creating magic link token: %v\n", err)
+		return
+	}
+	err = cfg.dbQueries.CreateMagicLinkToken(ctx, database.CreateMagicLinkTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(cfg.magicLinkTTL),
+	})
+	if err != nil {
+		fmt.Printf("Error saving magic link token: %v\n", err)
+		return
+	}
+
+	err = cfg.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Your Chirpy login link",
+		Body: fmt.Sprintf(
+			"Use the link below to log in to Chirpy, it expires in %s and can only be used once.\n\n%s\n\nIf you didn't request it you can ignore this email.",
+			cfg.magicLinkTTL,
+			cfg.magicLinkURL(token),
+		),
+	})
+	if err != nil {
+		fmt.Printf("Error sending magic link: %v\n", err)
+	}
+}
+
+func verifyMagicLinkHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		var reqBody MagicLinkVerifyRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil || reqBody.Token == "" {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, token field expected")
+			return
+		}
+
+		magicLink, err := cfg.dbQueries.ConsumeMagicLinkToken(req.Context(), auth.HashToken(reqBody.Token))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusUnauthorized, "Invalid or expired login link")
+				return
+			}
+			fmt.Printf("Error consuming magic link token: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error verifying login link")
+			return
+		}
+
+		user, err := cfg.dbQueries.GetUserByID(req.Context(), magicLink.UserID)
+		if err != nil {
+			fmt.Printf("Error getting user for magic link: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting user")
+			return
+		}
+
+		cfg.respondWithSession(res, req, user, reqBody.SessionMode)
+	}
+}
@@ -1,23 +1,62 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/ivportilla/chirpy/internal/auth"
 	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/mailer"
+	"github.com/ivportilla/chirpy/internal/oidc"
+	"github.com/ivportilla/chirpy/internal/reaction"
+	"github.com/ivportilla/chirpy/internal/realtime"
+	"github.com/ivportilla/chirpy/internal/unfurl"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
 	fileServerHits atomic.Int32
+	db             *sql.DB
 	dbQueries      *database.Queries
 	platform       string
 	authSecret     string
 	apiKey         string
+	adminKey       string
+	lockoutPolicy  auth.LockoutPolicy
+	passwordPolicy auth.PasswordPolicy
+	passwordHasher auth.PasswordHasher
+	mailer         mailer.Mailer
+	publicURL      string
+	oidcClient     *oidc.Client
+	linkFetcher    *unfurl.Fetcher
+	realtimeHub    *realtime.Hub
+
+	magicLinkTTL        time.Duration
+	magicLinkRateLimit  int
+	magicLinkRateWindow time.Duration
+
+	accountDeletionGrace time.Duration
+
+	exportTTL time.Duration
+
+	chirpEditWindow    time.Duration
+	chirpMaxLengthFree int
+	chirpMaxLengthRed  int
+
+	pinnedChirpsLimitFree int
+	pinnedChirpsLimitRed  int
+
+	groupMaxMembers int
+
+	reactionEmojis reaction.Set
 }
 
 func main() {
@@ -30,38 +69,195 @@ func main() {
 		os.Exit(1)
 	}
 
+	passwordPolicy := auth.DefaultPasswordPolicy
+	passwordPolicy.MinLength = getEnvInt("PASSWORD_MIN_LENGTH", passwordPolicy.MinLength)
+	passwordPolicy.MaxLength = getEnvInt("PASSWORD_MAX_LENGTH", passwordPolicy.MaxLength)
+	if breachedFile := os.Getenv("BREACHED_PASSWORDS_FILE"); breachedFile != "" {
+		passwordPolicy.Breached, err = auth.LoadBreachedPasswords(breachedFile)
+		if err != nil {
+			fmt.Printf("Error loading breached passwords: %v", err)
+			os.Exit(1)
+		}
+	}
+
+	passwordHasher, err := passwordHasherFromEnv()
+	if err != nil {
+		fmt.Printf("Invalid password hashing configuration: %v", err)
+		os.Exit(1)
+	}
+
+	fileMailer, err := mailer.NewFileMailer(getEnvString("MAIL_SINK_DIR", "mail"))
+	if err != nil {
+		fmt.Printf("Error creating mailer: %v", err)
+		os.Exit(1)
+	}
+
+	publicURL := getEnvString("PUBLIC_URL", "http://localhost:8080")
+	var oidcClient *oidc.Client
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		oidcClient = oidc.NewClient(oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  publicURL + "/api/login/oidc/callback",
+		})
+	}
+
 	dbQueries := database.New(db)
 	apiCfg := apiConfig{
-		dbQueries:  dbQueries,
-		platform:   os.Getenv("PLATFORM"),
-		authSecret: os.Getenv("AUTH_SECRET"),
-		apiKey:     os.Getenv("POLKA_KEY"),
+		db:             db,
+		dbQueries:      dbQueries,
+		platform:       os.Getenv("PLATFORM"),
+		authSecret:     os.Getenv("AUTH_SECRET"),
+		apiKey:         os.Getenv("POLKA_KEY"),
+		adminKey:       os.Getenv("ADMIN_KEY"),
+		lockoutPolicy:  auth.DefaultLockoutPolicy,
+		passwordPolicy: passwordPolicy,
+		passwordHasher: passwordHasher,
+		mailer:         fileMailer,
+		publicURL:      publicURL,
+		oidcClient:     oidcClient,
+		linkFetcher:    linkFetcherFromEnv(),
+		realtimeHub:    realtime.NewHub(),
+
+		magicLinkTTL:        getEnvDuration("MAGIC_LINK_TTL", 15*time.Minute),
+		magicLinkRateLimit:  getEnvInt("MAGIC_LINK_RATE_LIMIT", 3),
+		magicLinkRateWindow: getEnvDuration("MAGIC_LINK_RATE_WINDOW", time.Hour),
+
+		accountDeletionGrace: getEnvDuration("ACCOUNT_DELETION_GRACE", 14*24*time.Hour),
+
+		exportTTL: getEnvDuration("EXPORT_TTL", 48*time.Hour),
+
+		chirpEditWindow:    getEnvDuration("CHIRP_EDIT_WINDOW", 30*time.Minute),
+		chirpMaxLengthFree: getEnvInt("CHIRP_MAX_LENGTH", 140),
+		chirpMaxLengthRed:  getEnvInt("CHIRP_MAX_LENGTH_RED", 280),
+
+		pinnedChirpsLimitFree: getEnvInt("PINNED_CHIRPS_LIMIT", 3),
+		pinnedChirpsLimitRed:  getEnvInt("PINNED_CHIRPS_LIMIT_RED", 10),
+
+		groupMaxMembers: getEnvInt("GROUP_MAX_MEMBERS", 50),
+
+		reactionEmojis: reactionEmojisFromEnv(),
 	}
 	mux := http.NewServeMux()
 	port := 8080
 	server := http.Server{
 		Addr:    fmt.Sprintf(":%d", port),
-		Handler: mux,
+		Handler: withMaxBodyMiddleware(mux),
 	}
 
 	mux.Handle("/app/", apiCfg.middlewareMetricsInc(http.StripPrefix("/app", http.FileServer(http.Dir("./")))))
 	mux.HandleFunc("GET /api/healthz", healthCheckHandler)
+	mux.Handle("GET /api/config", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getConfigHandler(&apiCfg))))
 	mux.HandleFunc("GET /admin/metrics", metricsHandler(&apiCfg))
 	mux.Handle("POST /admin/reset", apiCfg.middlewareMetricsReset(http.HandlerFunc(createAllUsersHandler(&apiCfg))))
 	mux.Handle("POST /api/chirps", apiCfg.withAuthMiddleware(http.HandlerFunc(createChirpHandler(&apiCfg))))
 	mux.Handle("DELETE /api/chirps/{chirpID}", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteChirpHandler(&apiCfg))))
+	mux.Handle("PATCH /api/chirps/{chirpID}", apiCfg.withAuthMiddleware(http.HandlerFunc(editChirpHandler(&apiCfg))))
+	mux.Handle("GET /api/chirps/scheduled", apiCfg.withAuthMiddleware(http.HandlerFunc(getScheduledChirpsHandler(&apiCfg))))
+	mux.Handle("PATCH /api/chirps/scheduled/{chirpID}", apiCfg.withAuthMiddleware(http.HandlerFunc(editScheduledChirpHandler(&apiCfg))))
+	mux.Handle("DELETE /api/chirps/scheduled/{chirpID}", apiCfg.withAuthMiddleware(http.HandlerFunc(cancelScheduledChirpHandler(&apiCfg))))
+	mux.Handle("POST /api/drafts", apiCfg.withAuthMiddleware(http.HandlerFunc(createDraftHandler(&apiCfg))))
+	mux.Handle("GET /api/drafts", apiCfg.withAuthMiddleware(http.HandlerFunc(getDraftsHandler(&apiCfg))))
+	mux.Handle("GET /api/drafts/{draftID}", apiCfg.withAuthMiddleware(http.HandlerFunc(getDraftHandler(&apiCfg))))
+	mux.Handle("PUT /api/drafts/{draftID}", apiCfg.withAuthMiddleware(http.HandlerFunc(updateDraftHandler(&apiCfg))))
+	mux.Handle("DELETE /api/drafts/{draftID}", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteDraftHandler(&apiCfg))))
+	mux.Handle("POST /api/drafts/{draftID}/publish", apiCfg.withAuthMiddleware(http.HandlerFunc(publishDraftHandler(&apiCfg))))
 	mux.HandleFunc("POST /api/users", createUserHandler(&apiCfg))
 	mux.Handle("PUT /api/users", apiCfg.withAuthMiddleware(http.HandlerFunc(updateUserHandler(&apiCfg))))
-	mux.HandleFunc("GET /api/chirps", getChirpsHandler(&apiCfg))
-	mux.HandleFunc("GET /api/chirps/{chirpID}", getChirp(&apiCfg))
+	mux.Handle("PUT /api/users/me/preferences", apiCfg.withAuthMiddleware(http.HandlerFunc(updatePreferencesHandler(&apiCfg))))
+	mux.Handle("DELETE /api/users/me", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteAccountHandler(&apiCfg))))
+	mux.Handle("POST /api/users/{userID}/follow", apiCfg.withAuthMiddleware(http.HandlerFunc(followUserHandler(&apiCfg))))
+	mux.Handle("DELETE /api/users/{userID}/follow", apiCfg.withAuthMiddleware(http.HandlerFunc(unfollowUserHandler(&apiCfg))))
+	mux.Handle("POST /api/users/{userID}/block", apiCfg.withAuthMiddleware(http.HandlerFunc(blockUserHandler(&apiCfg))))
+	mux.Handle("DELETE /api/users/{userID}/block", apiCfg.withAuthMiddleware(http.HandlerFunc(unblockUserHandler(&apiCfg))))
+	mux.Handle("GET /api/users/me/blocks", apiCfg.withAuthMiddleware(http.HandlerFunc(getBlockedUsersHandler(&apiCfg))))
+	mux.Handle("POST /api/users/{userID}/mute", apiCfg.withAuthMiddleware(http.HandlerFunc(muteUserHandler(&apiCfg))))
+	mux.Handle("DELETE /api/users/{userID}/mute", apiCfg.withAuthMiddleware(http.HandlerFunc(unmuteUserHandler(&apiCfg))))
+	mux.Handle("GET /api/users/me/mutes", apiCfg.withAuthMiddleware(http.HandlerFunc(getMutedUsersHandler(&apiCfg))))
+	mux.Handle("POST /api/filters", apiCfg.withAuthMiddleware(http.HandlerFunc(createMuteFilterHandler(&apiCfg))))
+	mux.Handle("GET /api/filters", apiCfg.withAuthMiddleware(http.HandlerFunc(getMuteFiltersHandler(&apiCfg))))
+	mux.Handle("PUT /api/filters/{filterID}", apiCfg.withAuthMiddleware(http.HandlerFunc(updateMuteFilterHandler(&apiCfg))))
+	mux.Handle("DELETE /api/filters/{filterID}", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteMuteFilterHandler(&apiCfg))))
+	mux.Handle("POST /api/users/{userID}/conversation", apiCfg.withAuthMiddleware(http.HandlerFunc(startConversationHandler(&apiCfg))))
+	mux.Handle("GET /api/conversations", apiCfg.withAuthMiddleware(http.HandlerFunc(getConversationsHandler(&apiCfg))))
+	mux.Handle("POST /api/conversations", apiCfg.withAuthMiddleware(http.HandlerFunc(createGroupConversationHandler(&apiCfg))))
+	mux.Handle("PATCH /api/conversations/{conversationID}", apiCfg.withAuthMiddleware(http.HandlerFunc(renameConversationHandler(&apiCfg))))
+	mux.Handle("POST /api/conversations/{conversationID}/members", apiCfg.withAuthMiddleware(http.HandlerFunc(addConversationMemberHandler(&apiCfg))))
+	mux.Handle("DELETE /api/conversations/{conversationID}/members/{userID}", apiCfg.withAuthMiddleware(http.HandlerFunc(removeConversationMemberHandler(&apiCfg))))
+	mux.Handle("POST /api/conversations/{conversationID}/leave", apiCfg.withAuthMiddleware(http.HandlerFunc(leaveConversationHandler(&apiCfg))))
+	mux.Handle("GET /api/conversations/{conversationID}/messages", apiCfg.withAuthMiddleware(http.HandlerFunc(getMessagesHandler(&apiCfg))))
+	mux.Handle("POST /api/conversations/{conversationID}/messages", apiCfg.withAuthMiddleware(http.HandlerFunc(sendMessageHandler(&apiCfg))))
+	mux.Handle("PUT /api/conversations/{conversationID}/read", apiCfg.withAuthMiddleware(http.HandlerFunc(markConversationReadHandler(&apiCfg))))
+	mux.Handle("POST /api/lists", apiCfg.withAuthMiddleware(http.HandlerFunc(createListHandler(&apiCfg))))
+	mux.Handle("GET /api/lists", apiCfg.withAuthMiddleware(http.HandlerFunc(getListsHandler(&apiCfg))))
+	mux.Handle("GET /api/lists/subscriptions", apiCfg.withAuthMiddleware(http.HandlerFunc(getSubscribedListsHandler(&apiCfg))))
+	mux.Handle("GET /api/lists/{listID}", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getListHandler(&apiCfg))))
+	mux.Handle("PUT /api/lists/{listID}", apiCfg.withAuthMiddleware(http.HandlerFunc(updateListHandler(&apiCfg))))
+	mux.Handle("DELETE /api/lists/{listID}", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteListHandler(&apiCfg))))
+	mux.Handle("GET /api/lists/{listID}/members", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getListMembersHandler(&apiCfg))))
+	mux.Handle("POST /api/lists/{listID}/members", apiCfg.withAuthMiddleware(http.HandlerFunc(addListMemberHandler(&apiCfg))))
+	mux.Handle("DELETE /api/lists/{listID}/members/{userID}", apiCfg.withAuthMiddleware(http.HandlerFunc(removeListMemberHandler(&apiCfg))))
+	mux.Handle("GET /api/lists/{listID}/chirps", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getListChirpsHandler(&apiCfg))))
+	mux.Handle("POST /api/lists/{listID}/subscription", apiCfg.withAuthMiddleware(http.HandlerFunc(subscribeToListHandler(&apiCfg))))
+	mux.Handle("DELETE /api/lists/{listID}/subscription", apiCfg.withAuthMiddleware(http.HandlerFunc(unsubscribeFromListHandler(&apiCfg))))
+	mux.Handle("POST /api/users/me/export", apiCfg.withAuthMiddleware(http.HandlerFunc(createDataExportHandler(&apiCfg))))
+	mux.Handle("GET /api/users/me/export/{exportID}", apiCfg.withAuthMiddleware(http.HandlerFunc(getDataExportHandler(&apiCfg))))
+	mux.Handle("GET /api/chirps", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getChirpsHandler(&apiCfg))))
+	mux.Handle("GET /api/chirps/{chirpID}", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getChirp(&apiCfg))))
+	mux.Handle("GET /api/chirps/{chirpID}/revisions", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getChirpRevisionsHandler(&apiCfg))))
+	mux.Handle("POST /api/chirps/{chirpID}/pin", apiCfg.withAuthMiddleware(http.HandlerFunc(pinChirpHandler(&apiCfg))))
+	mux.Handle("DELETE /api/chirps/{chirpID}/pin", apiCfg.withAuthMiddleware(http.HandlerFunc(unpinChirpHandler(&apiCfg))))
+	mux.Handle("GET /api/users/{userID}/pinned", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getPinnedChirpsHandler(&apiCfg))))
+	mux.Handle("PUT /api/chirps/{chirpID}/sensitive", apiCfg.withAuthMiddleware(http.HandlerFunc(setChirpSensitiveHandler(&apiCfg))))
+	mux.Handle("POST /api/chirps/{chirpID}/poll/votes", apiCfg.withAuthMiddleware(http.HandlerFunc(votePollHandler(&apiCfg))))
+	mux.Handle("POST /api/chirps/{chirpID}/reactions", apiCfg.withAuthMiddleware(http.HandlerFunc(createReactionHandler(&apiCfg))))
+	mux.Handle("GET /api/chirps/{chirpID}/reactions", apiCfg.withOptionalAuthMiddleware(http.HandlerFunc(getReactionsHandler(&apiCfg))))
+	mux.Handle("DELETE /api/chirps/{chirpID}/reactions/{reactionID}", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteReactionHandler(&apiCfg))))
+	mux.Handle("POST /api/chirps/{chirpID}/bookmark", apiCfg.withAuthMiddleware(http.HandlerFunc(bookmarkChirpHandler(&apiCfg))))
+	mux.Handle("DELETE /api/chirps/{chirpID}/bookmark", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteBookmarkHandler(&apiCfg))))
+	mux.Handle("GET /api/bookmarks", apiCfg.withAuthMiddleware(http.HandlerFunc(getBookmarksHandler(&apiCfg))))
+	mux.Handle("POST /api/bookmarks/folders", apiCfg.withAuthMiddleware(http.HandlerFunc(createBookmarkFolderHandler(&apiCfg))))
+	mux.Handle("GET /api/bookmarks/folders", apiCfg.withAuthMiddleware(http.HandlerFunc(getBookmarkFoldersHandler(&apiCfg))))
+	mux.Handle("DELETE /api/bookmarks/folders/{folderID}", apiCfg.withAuthMiddleware(http.HandlerFunc(deleteBookmarkFolderHandler(&apiCfg))))
 	mux.HandleFunc("POST /api/login", loginHandler(&apiCfg))
+	mux.HandleFunc("POST /api/login/magic", requestMagicLinkHandler(&apiCfg))
+	mux.HandleFunc("POST /api/login/magic/verify", verifyMagicLinkHandler(&apiCfg))
+	mux.HandleFunc("GET /api/login/oidc", oidcLoginHandler(&apiCfg))
+	mux.HandleFunc("GET /api/login/oidc/callback", oidcCallbackHandler(&apiCfg))
+	mux.Handle("POST /api/users/me/identities/oidc", apiCfg.withAuthMiddleware(http.HandlerFunc(oidcLinkHandler(&apiCfg))))
+	mux.HandleFunc("GET /api/ws", wsHandler(&apiCfg))
 	mux.HandleFunc("POST /api/refresh", refreshTokenHandler(&apiCfg))
 	mux.HandleFunc("POST /api/revoke", revokeRefreshToken(&apiCfg))
 	mux.HandleFunc("POST /api/polka/webhooks", handleUserUpgrade(&apiCfg))
+	mux.Handle("GET /admin/lockouts", apiCfg.withAdminMiddleware(http.HandlerFunc(getLockedAccountsHandler(&apiCfg))))
+	mux.Handle("DELETE /admin/lockouts/{email}", apiCfg.withAdminMiddleware(http.HandlerFunc(unlockAccountHandler(&apiCfg))))
+	mux.Handle("PUT /admin/moderators/{userID}", apiCfg.withAdminMiddleware(http.HandlerFunc(setModeratorHandler(&apiCfg, true))))
+	mux.Handle("DELETE /admin/moderators/{userID}", apiCfg.withAdminMiddleware(http.HandlerFunc(setModeratorHandler(&apiCfg, false))))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go runPeriodically(ctx, "account purge", getEnvDuration("ACCOUNT_PURGE_INTERVAL", time.Hour), apiCfg.purgeDeletedUsers)
+	go runPeriodically(ctx, "data exports", getEnvDuration("EXPORT_WORKER_INTERVAL", 30*time.Second), apiCfg.processDataExports)
+	go runPeriodically(ctx, "chirp scheduler", getEnvDuration("CHIRP_SCHEDULER_INTERVAL", 10*time.Second), apiCfg.publishScheduledChirps)
+	go runPeriodically(ctx, "link previews", getEnvDuration("LINK_PREVIEW_INTERVAL", 10*time.Second), apiCfg.processLinkPreviews)
+	go apiCfg.listenRealtime(ctx, dbURL)
+
+	// Shutdown doesn't wait for hijacked connections like WebSockets
+	server.RegisterOnShutdown(func() {
+		apiCfg.realtimeHub.DisconnectAll(wsReasonShutdown)
+	})
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
 
 	fmt.Printf("Server listening on port %d\n", port)
 	err = server.ListenAndServe()
-	if err != nil {
+	if err != nil && err != http.ErrServerClosed {
 		fmt.Printf("Error creating server: %s", err.Error())
 		os.Exit(1)
 	}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/realtime"
+)
+
+// Handler tests run against a mocked database, queries are matched by the
+// name sqlc puts at the start of each one.
+
+var userColumns = []string{"id", "created_at", "updated_at", "email", "hashed_password", "is_chirpy_red", "deleted_at", "is_moderator", "sensitive_content", "dm_privacy"}
+
+func newTestConfig(t *testing.T) (*apiConfig, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating database mock: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+
+	cfg := &apiConfig{
+		db:             db,
+		dbQueries:      database.New(db),
+		platform:       "dev",
+		authSecret:     "test-secret",
+		publicURL:      "http://localhost:8080",
+		passwordHasher: auth.PasswordHasher{Preferred: auth.Argon2idHasher{Params: auth.MinArgon2idParams}},
+		realtimeHub:    realtime.NewHub(),
+	}
+	return cfg, mock
+}
+
+func expectQuery(mock sqlmock.Sqlmock, name string) *sqlmock.ExpectedQuery {
+	return mock.ExpectQuery(regexp.QuoteMeta("-- name: " + name + " "))
+}
+
+func expectExec(mock sqlmock.Sqlmock, name string) *sqlmock.ExpectedExec {
+	return mock.ExpectExec(regexp.QuoteMeta("-- name: " + name + " "))
+}
+
+func newTestUser(email, hashedPassword string) database.User {
+	now := time.Now()
+	return database.User{
+		ID:               uuid.New(),
+		CreatedAt:        now,
+		UpdatedAt:        now,
+		Email:            email,
+		HashedPassword:   hashedPassword,
+		SensitiveContent: "hide",
+		DmPrivacy:        "everyone",
+	}
+}
+
+func userRows(users ...database.User) *sqlmock.Rows {
+	rows := sqlmock.NewRows(userColumns)
+	for _, user := range users {
+		rows.AddRow(user.ID.String(), user.CreatedAt, user.UpdatedAt, user.Email, user.HashedPassword, user.IsChirpyRed, nullTimeValue(user.DeletedAt), user.IsModerator, user.SensitiveContent, user.DmPrivacy)
+	}
+	return rows
+}
+
+func nullTimeValue(target sql.NullTime) any {
+	if !target.Valid {
+		return nil
+	}
+	return target.Time
+}
+
+// withUser sets the caller like withAuthMiddleware does.
+func withUser(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, "user_id", userID.String())
+}
+
+func expectMockDone(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet database expectations: %v", err)
+	}
+}
+
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, out any) {
+	t.Helper()
+	if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+		t.Fatalf("Error decoding response body: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/filter"
+	"github.com/lib/pq"
+)
+
+const (
+	maxMuteFilterPhraseLength = 100
+	maxMuteFilters            = 100
+)
+
+const (
+	muteFilterActionHide = "hide"
+	muteFilterActionWarn = "warn"
+)
+
+type MuteFilter struct {
+	ID              uuid.UUID  `json:"id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	Phrase          string     `json:"phrase"`
+	WholeWord       bool       `json:"whole_word"`
+	OnlyNonFollowed bool       `json:"only_non_followed"`
+	Action          string     `json:"action"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+type MuteFilterRequest struct {
+	Phrase          string     `json:"phrase"`
+	WholeWord       *bool      `json:"whole_word"`
+	OnlyNonFollowed bool       `json:"only_non_followed"`
+	Action          string     `json:"action"`
+	ExpiresAt       *time.Time `json:"expires_at"`
+}
+
+func toMuteFilter(target database.MuteFilter) MuteFilter {
+	return MuteFilter{
+		ID:              target.ID,
+		CreatedAt:       target.CreatedAt,
+		UpdatedAt:       target.UpdatedAt,
+		Phrase:          target.Phrase,
+		WholeWord:       target.WholeWord,
+		OnlyNonFollowed: target.OnlyNonFollowed,
+		Action:          target.Action,
+		ExpiresAt:       nullTimeToPtr(target.ExpiresAt),
+	}
+}
+
+// decodeMuteFilterRequest reads and validates a filter, filling in the
+// defaults: whole words only, hiding the chirps and never expiring.
+func decodeMuteFilterRequest(res http.ResponseWriter, req *http.Request) (database.CreateMuteFilterParams, bool) {
+	var reqBody MuteFilterRequest
+	err := json.NewDecoder(req.Body).Decode(&reqBody)
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Error decoding body, phrase field expected")
+		return database.CreateMuteFilterParams{}, false
+	}
+
+	phrase := filter.Normalize(reqBody.Phrase)
+	if phrase == "" || utf8.RuneCountInString(phrase) > maxMuteFilterPhraseLength {
+		respondWithError(res, http.StatusBadRequest, fmt.Sprintf("Phrase must have between 1 and %d characters", maxMuteFilterPhraseLength))
+		return database.CreateMuteFilterParams{}, false
+	}
+
+	action := reqBody.Action
+	if action == "" {
+		action = muteFilterActionHide
+	}
+	if action != muteFilterActionHide && action != muteFilterActionWarn {
+		respondWithError(res, http.StatusBadRequest, "Invalid action, it must be hide or warn")
+		return database.CreateMuteFilterParams{}, false
+	}
+
+	params := database.CreateMuteFilterParams{
+		Phrase:          phrase,
+		WholeWord:       reqBody.WholeWord == nil || *reqBody.WholeWord,
+		OnlyNonFollowed: reqBody.OnlyNonFollowed,
+		Action:          action,
+	}
+	if reqBody.ExpiresAt != nil {
+		if !reqBody.ExpiresAt.After(time.Now()) {
+			respondWithError(res, http.StatusBadRequest, "expires_at must be in the future")
+			return database.CreateMuteFilterParams{}, false
+		}
+		params.ExpiresAt = sql.NullTime{Time: reqBody.ExpiresAt.Local(), Valid: true}
+	}
+	return params, true
+}
+
+// applyMuteFilters drops the chirps matching one of the user's active
+// filters, or marks them as filtered for filters with the warn action. The
+// user's own chirps are never filtered.
+func (cfg *apiConfig) applyMuteFilters(ctx context.Context, userID uuid.UUID, chirps []Chirp) ([]Chirp, error) {
+	filters, err := cfg.dbQueries.GetActiveMuteFilters(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting mute filters: %w", err)
+	}
+	if len(filters) == 0 || len(chirps) == 0 {
+		return chirps, nil
+	}
+
+	onlyNonFollowed := false
+	for _, muteFilter := range filters {
+		onlyNonFollowed = onlyNonFollowed || muteFilter.OnlyNonFollowed
+	}
+	followed := map[uuid.UUID]bool{}
+	if onlyNonFollowed {
+		authorIDs := make([]uuid.UUID, len(chirps))
+		for i, chirp := range chirps {
+			authorIDs[i] = chirp.UserID
+		}
+		followedIDs, err := cfg.dbQueries.GetFollowedAmong(ctx, database.GetFollowedAmongParams{FollowerID: userID, UserIds: authorIDs})
+		if err != nil {
+			return nil, fmt.Errorf("error getting followed users: %w", err)
+		}
+		for _, followedID := range followedIDs {
+			followed[followedID] = true
+		}
+	}
+
+	result := make([]Chirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		if chirp.UserID == userID {
+			result = append(result, chirp)
+			continue
+		}
+
+		text := chirp.Body
+		if chirp.ContentWarning != nil {
+			text = *chirp.ContentWarning + "\n" + text
+		}
+		hidden := false
+		for _, muteFilter := range filters {
+			if muteFilter.OnlyNonFollowed && followed[chirp.UserID] {
+				continue
+			}
+			if !(filter.Filter{Phrase: muteFilter.Phrase, WholeWord: muteFilter.WholeWord}).Match(text) {
+				continue
+			}
+			if muteFilter.Action == muteFilterActionHide {
+				hidden = true
+				break
+			}
+			chirp.Filtered = append(chirp.Filtered, muteFilter.Phrase)
+			chirp.Collapsed = true
+		}
+		if !hidden {
+			result = append(result, chirp)
+		}
+	}
+	return result, nil
+}
+
+// isUniqueViolation reports whether the query failed on a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func createMuteFilterHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		params, ok := decodeMuteFilterRequest(res, req)
+		if !ok {
+			return
+		}
+		params.UserID = userID
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating filter")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		// Locking the user serializes concurrent creations so the limit holds
+		_, err = queries.LockUser(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error locking user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating filter")
+			return
+		}
+		count, err := queries.CountMuteFilters(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error counting mute filters: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating filter")
+			return
+		}
+		if count >= maxMuteFilters {
+			respondWithError(res, http.StatusBadRequest, fmt.Sprintf("You can't have more than %d filters", maxMuteFilters))
+			return
+		}
+
+		muteFilter, err := queries.CreateMuteFilter(req.Context(), params)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusConflict, "A filter with this phrase already exists")
+				return
+			}
+			fmt.Printf("Error creating mute filter: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating filter")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing mute filter: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating filter")
+			return
+		}
+
+		respondWithJSON(res, http.StatusCreated, toMuteFilter(muteFilter))
+	}
+}
+
+// getMuteFiltersHandler lists all the user's filters, expired ones included
+// so they can be renewed.
+func getMuteFiltersHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		filters, err := cfg.dbQueries.GetMuteFilters(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting mute filters: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting filters")
+			return
+		}
+
+		response := make([]MuteFilter, len(filters))
+		for i, muteFilter := range filters {
+			response[i] = toMuteFilter(muteFilter)
+		}
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+func updateMuteFilterHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		filterID, err := uuid.Parse(req.PathValue("filterID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid filter ID, it must be a UUID")
+			return
+		}
+
+		params, ok := decodeMuteFilterRequest(res, req)
+		if !ok {
+			return
+		}
+
+		_, err = cfg.dbQueries.GetMuteFilter(req.Context(), database.GetMuteFilterParams{ID: filterID, UserID: userID})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Filter not found")
+				return
+			}
+			fmt.Printf("Error getting mute filter: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating filter")
+			return
+		}
+
+		muteFilter, err := cfg.dbQueries.UpdateMuteFilter(req.Context(), database.UpdateMuteFilterParams{
+			ID:              filterID,
+			UserID:          userID,
+			Phrase:          params.Phrase,
+			WholeWord:       params.WholeWord,
+			OnlyNonFollowed: params.OnlyNonFollowed,
+			Action:          params.Action,
+			ExpiresAt:       params.ExpiresAt,
+		})
+		if err != nil {
+			// A concurrent update can pass the NOT EXISTS check and hit the
+			// unique constraint instead
+			if err == sql.ErrNoRows || isUniqueViolation(err) {
+				respondWithError(res, http.StatusConflict, "A filter with this phrase already exists")
+				return
+			}
+			fmt.Printf("Error updating mute filter: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating filter")
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, toMuteFilter(muteFilter))
+	}
+}
+
+func deleteMuteFilterHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		filterID, err := uuid.Parse(req.PathValue("filterID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid filter ID, it must be a UUID")
+			return
+		}
+
+		deleted, err := cfg.dbQueries.DeleteMuteFilter(req.Context(), database.DeleteMuteFilterParams{ID: filterID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error deleting mute filter: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error deleting filter")
+			return
+		}
+		if deleted == 0 {
+			respondWithError(res, http.StatusNotFound, "Filter not found")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var muteFilterColumns = []string{"id", "created_at", "updated_at", "user_id", "phrase", "whole_word", "only_non_followed", "action", "expires_at"}
+
+func muteFilterRows(id, userID uuid.UUID, phrase string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(muteFilterColumns).AddRow(id.String(), now, now, userID.String(), phrase, true, false, muteFilterActionHide, nil)
+}
+
+func TestCreateMuteFilterLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		filters    int
+		wantStatus int
+	}{
+		{name: "Under the limit", filters: maxMuteFilters - 1, wantStatus: http.StatusCreated},
+		{name: "At the limit", filters: maxMuteFilters, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			user := newTestUser("walt@example.com", unusablePasswordHash)
+
+			mock.ExpectBegin()
+			// The user row is locked before counting so concurrent creations can't pass the limit
+			expectQuery(mock, "LockUser").WithArgs(user.ID).WillReturnRows(userRows(user))
+			expectQuery(mock, "CountMuteFilters").WithArgs(user.ID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.filters))
+			if tt.wantStatus == http.StatusCreated {
+				expectQuery(mock, "CreateMuteFilter").WillReturnRows(muteFilterRows(uuid.New(), user.ID, "spoiler"))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/filters", strings.NewReader(`{"phrase": "spoiler"}`))
+			rec := httptest.NewRecorder()
+			createMuteFilterHandler(cfg)(rec, req.WithContext(withUser(req.Context(), user.ID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
+
+func TestUpdateMuteFilterConflict(t *testing.T) {
+	tests := []struct {
+		name      string
+		updateErr error
+	}{
+		{name: "Phrase used by another filter", updateErr: sql.ErrNoRows},
+		{name: "Concurrent update hits the unique constraint", updateErr: &pq.Error{Code: "23505"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			filterID := uuid.New()
+
+			expectQuery(mock, "GetMuteFilter").WithArgs(filterID, userID).WillReturnRows(muteFilterRows(filterID, userID, "spoiler"))
+			expectQuery(mock, "UpdateMuteFilter").WillReturnError(tt.updateErr)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/filters/"+filterID.String(), strings.NewReader(`{"phrase": "finale"}`))
+			req.SetPathValue("filterID", filterID.String())
+			rec := httptest.NewRecorder()
+			updateMuteFilterHandler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+			if rec.Code != http.StatusConflict {
+				t.Fatalf("Expected status %d, got %d %s", http.StatusConflict, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/oidc"
+)
+
+const (
+	oidcFlowCookie = "chirpy_oidc"
+	oidcFlowTTL    = 10 * time.Minute
+
+	// Users created through an identity provider can't log in with a password
+	unusablePasswordHash = "unset"
+)
+
+var (
+	errIdentityEmailTaken  = errors.New("email already registered")
+	errIdentityLinkedOther = errors.New("identity linked to another user")
+	errIdentityUnverified  = errors.New("identity email not verified")
+)
+
+type OIDCLinkResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
+
+// oidcFlow is what travels in the flow cookie between the redirect to the
+// provider and the callback. LinkUserID is only set when a logged in user
+// links the provider to their account.
+type oidcFlow struct {
+	State      string
+	Nonce      string
+	Verifier   string
+	LinkUserID uuid.NullUUID
+}
+
+// startOIDCFlow stores a new flow in a short lived HttpOnly cookie, signed
+// so the user to link can't be tampered with, and returns the provider URL
+// to send the user to.
+func (cfg *apiConfig) startOIDCFlow(res http.ResponseWriter, req *http.Request, linkUserID uuid.NullUUID) (string, bool) {
+	var values [3]string
+	for i := range values {
+		value, err := oidc.RandomString()
+		if err != nil {
+			fmt.Printf("Error starting OIDC login: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error starting external login")
+			return "", false
+		}
+		values[i] = value
+	}
+	flow := oidcFlow{State: values[0], Nonce: values[1], Verifier: values[2], LinkUserID: linkUserID}
+
+	authURL, err := cfg.oidcClient.AuthCodeURL(req.Context(), flow.State, flow.Nonce, flow.Verifier)
+	if err != nil {
+		fmt.Printf("Error building OIDC auth URL: %v\n", err)
+		respondWithError(res, http.StatusBadGateway, "Error contacting identity provider")
+		return "", false
+	}
+
+	rawLinkUserID := ""
+	if linkUserID.Valid {
+		rawLinkUserID = linkUserID.UUID.String()
+	}
+	value := auth.SignValue(strings.Join([]string{flow.State, flow.Nonce, flow.Verifier, rawLinkUserID}, "."), cfg.authSecret)
+	cookie := cfg.newSessionCookie(oidcFlowCookie, value, oidcFlowTTL, true)
+	// The provider redirects back cross-site, Strict cookies wouldn't be sent
+	cookie.SameSite = http.SameSiteLaxMode
+	http.SetCookie(res, cookie)
+	return authURL, true
+}
+
+func (cfg *apiConfig) parseOIDCFlow(value string) (oidcFlow, error) {
+	value, err := auth.VerifySignedValue(value, cfg.authSecret)
+	if err != nil {
+		return oidcFlow{}, err
+	}
+	values := strings.Split(value, ".")
+	if len(values) != 4 {
+		return oidcFlow{}, fmt.Errorf("invalid flow cookie")
+	}
+
+	flow := oidcFlow{State: values[0], Nonce: values[1], Verifier: values[2]}
+	if values[3] != "" {
+		linkUserID, err := uuid.Parse(values[3])
+		if err != nil {
+			return oidcFlow{}, fmt.Errorf("invalid user to link: %w", err)
+		}
+		flow.LinkUserID = uuid.NullUUID{UUID: linkUserID, Valid: true}
+	}
+	return flow, nil
+}
+
+// oidcLoginHandler starts the authorization code flow with PKCE.
+func oidcLoginHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if cfg.oidcClient == nil {
+			respondWithError(res, http.StatusNotFound, "External login is not configured")
+			return
+		}
+
+		authURL, ok := cfg.startOIDCFlow(res, req, uuid.NullUUID{})
+		if !ok {
+			return
+		}
+		http.Redirect(res, req, authURL, http.StatusFound)
+	}
+}
+
+// oidcLinkHandler starts a flow that links the provider identity to the
+// caller's account. The client navigates to the returned URL, the callback
+// redirects back to the app once the identity is linked.
+func oidcLinkHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if cfg.oidcClient == nil {
+			respondWithError(res, http.StatusNotFound, "External login is not configured")
+			return
+		}
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		authURL, ok := cfg.startOIDCFlow(res, req, uuid.NullUUID{UUID: userID, Valid: true})
+		if !ok {
+			return
+		}
+		respondWithJSON(res, http.StatusOK, OIDCLinkResponse{AuthorizationURL: authURL})
+	}
+}
+
+func oidcCallbackHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		if cfg.oidcClient == nil {
+			respondWithError(res, http.StatusNotFound, "External login is not configured")
+			return
+		}
+
+		flowCookie, err := req.Cookie(oidcFlowCookie)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "External login expired, please try again")
+			return
+		}
+		clearCookie := cfg.newSessionCookie(oidcFlowCookie, "", 0, true)
+		clearCookie.MaxAge = -1
+		http.SetCookie(res, clearCookie)
+
+		flow, err := cfg.parseOIDCFlow(flowCookie.Value)
+		query := req.URL.Query()
+		if err != nil || query.Get("state") != flow.State {
+			respondWithError(res, http.StatusBadRequest, "Invalid external login state")
+			return
+		}
+		if query.Get("error") != "" {
+			respondWithError(res, http.StatusUnauthorized, "External login was denied")
+			return
+		}
+
+		rawIDToken, err := cfg.oidcClient.Exchange(req.Context(), query.Get("code"), flow.Verifier)
+		if err != nil {
+			fmt.Printf("Error exchanging OIDC code: %v\n", err)
+			respondWithError(res, http.StatusUnauthorized, "External login failed")
+			return
+		}
+		claims, err := cfg.oidcClient.VerifyIDToken(req.Context(), rawIDToken, flow.Nonce)
+		if err != nil {
+			fmt.Printf("Error verifying OIDC id token: %v\n", err)
+			respondWithError(res, http.StatusUnauthorized, "External login failed")
+			return
+		}
+
+		if flow.LinkUserID.Valid {
+			err = cfg.linkIdentity(req.Context(), flow.LinkUserID.UUID, claims)
+			if err != nil {
+				if errors.Is(err, errIdentityLinkedOther) {
+					respondWithError(res, http.StatusConflict, "This external account is already linked to another user")
+					return
+				}
+				fmt.Printf("Error linking OIDC identity: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error linking external account")
+				return
+			}
+			http.Redirect(res, req, cfg.publicURL+"/app/", http.StatusFound)
+			return
+		}
+
+		user, err := cfg.getOrCreateUserForIdentity(req.Context(), claims)
+		if err != nil {
+			if errors.Is(err, errIdentityEmailTaken) {
+				respondWithError(res, http.StatusConflict, "An account with this email already exists, log in to it and link this external account from your account settings")
+				return
+			}
+			if errors.Is(err, errIdentityUnverified) {
+				respondWithError(res, http.StatusForbidden, "The identity provider hasn't verified your email")
+				return
+			}
+			fmt.Printf("Error getting user for OIDC identity: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting user")
+			return
+		}
+
+		token, refreshToken, err := cfg.issueTokens(req.Context(), user)
+		if err != nil {
+			fmt.Printf("Error creating session: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating session")
+			return
+		}
+		err = cfg.setSessionCookies(res, token, refreshToken, refreshTokenExpiresIn)
+		if err != nil {
+			fmt.Printf("Error creating session cookies: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error creating session")
+			return
+		}
+
+		http.Redirect(res, req, cfg.publicURL+"/app/", http.StatusFound)
+	}
+}
+
+// getOrCreateUserForIdentity returns the user linked to the external
+// identity, creating one on first login. Identities are never linked to
+// existing accounts here, even when the provider verified the email, the
+// account owner has to link them explicitly. Unverified emails never create
+// accounts nor replace the stored email of an identity.
+func (cfg *apiConfig) getOrCreateUserForIdentity(ctx context.Context, claims *oidc.IDTokenClaims) (database.User, error) {
+	issuer := cfg.oidcClient.Issuer()
+	user, err := cfg.dbQueries.GetUserByIdentity(ctx, database.GetUserByIdentityParams{Issuer: issuer, Subject: claims.Subject})
+	if err == nil {
+		if claims.Email != "" && claims.EmailVerified {
+			err = cfg.dbQueries.UpdateUserIdentityEmail(ctx, database.UpdateUserIdentityEmailParams{Issuer: issuer, Subject: claims.Subject, Email: claims.Email})
+			if err != nil {
+				fmt.Printf("Error updating identity email: %v\n", err)
+			}
+		}
+		return user, nil
+	}
+	if err != sql.ErrNoRows {
+		return database.User{}, err
+	}
+
+	if claims.Email == "" {
+		return database.User{}, fmt.Errorf("identity provider didn't return an email")
+	}
+	if !claims.EmailVerified {
+		return database.User{}, errIdentityUnverified
+	}
+
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	_, err = queries.GetUser(ctx, claims.Email)
+	if err == nil {
+		return database.User{}, errIdentityEmailTaken
+	}
+	if err != sql.ErrNoRows {
+		return database.User{}, err
+	}
+	user, err = queries.CreateUser(ctx, database.CreateUserParams{Email: claims.Email, HashedPassword: unusablePasswordHash})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	_, err = queries.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:  user.ID,
+		Issuer:  issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	})
+	if err != nil {
+		return database.User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+// linkIdentity links the external identity to the user, linking it again
+// to the same user is a no-op.
+func (cfg *apiConfig) linkIdentity(ctx context.Context, userID uuid.UUID, claims *oidc.IDTokenClaims) error {
+	issuer := cfg.oidcClient.Issuer()
+	linked, err := cfg.dbQueries.GetUserByIdentity(ctx, database.GetUserByIdentityParams{Issuer: issuer, Subject: claims.Subject})
+	if err == nil {
+		if linked.ID != userID {
+			return errIdentityLinkedOther
+		}
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	user, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error getting user: %w", err)
+	}
+	if user.DeletedAt.Valid {
+		return fmt.Errorf("user was deleted")
+	}
+
+	_, err = cfg.dbQueries.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:  userID,
+		Issuer:  issuer,
+		Subject: claims.Subject,
+		Email:   claims.Email,
+	})
+	return err
+}
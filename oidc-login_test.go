@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/oidc/oidctest"
+)
+
+var identityColumns = []string{"id", "created_at", "updated_at", "user_id", "issuer", "subject", "email"}
+
+func identityRows(userID uuid.UUID, issuer, subject, email string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(identityColumns).AddRow(uuid.NewString(), now, now, userID.String(), issuer, subject, email)
+}
+
+func newOIDCTestConfig(t *testing.T) (*apiConfig, sqlmock.Sqlmock, *oidctest.Provider) {
+	cfg, mock := newTestConfig(t)
+	provider := oidctest.NewProvider(t)
+	cfg.oidcClient = provider.NewClient(cfg.publicURL + "/api/login/oidc/callback")
+	return cfg, mock, provider
+}
+
+// startOIDCFlow runs the handler starting the flow and returns the URL of
+// the provider plus the flow cookie.
+func startOIDCFlow(t *testing.T, handler http.HandlerFunc, req *http.Request) (string, *http.Cookie) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	var authURL string
+	switch rec.Code {
+	case http.StatusFound:
+		authURL = rec.Header().Get("Location")
+	case http.StatusOK:
+		var body OIDCLinkResponse
+		decodeBody(t, rec, &body)
+		authURL = body.AuthorizationURL
+	default:
+		t.Fatalf("Unexpected status starting the flow: %d %s", rec.Code, rec.Body)
+	}
+
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == oidcFlowCookie {
+			return authURL, cookie
+		}
+	}
+	t.Fatalf("Flow cookie not set")
+	return "", nil
+}
+
+// finishOIDCFlow approves the login at the provider and calls the callback.
+func finishOIDCFlow(t *testing.T, cfg *apiConfig, provider *oidctest.Provider, authURL string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("Error parsing auth URL: %v", err)
+	}
+	query := url.Values{}
+	query.Set("state", parsed.Query().Get("state"))
+	query.Set("code", provider.Authorize(authURL))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/login/oidc/callback?"+query.Encode(), nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	oidcCallbackHandler(cfg)(rec, req)
+	return rec
+}
+
+func hasCookie(rec *httptest.ResponseRecorder, name string) bool {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == name && cookie.MaxAge >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOIDCLogin(t *testing.T) {
+	t.Run("First login creates a user", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		user := newTestUser(provider.Email, unusablePasswordHash)
+
+		expectQuery(mock, "GetUserByIdentity").WillReturnRows(userRows())
+		mock.ExpectBegin()
+		expectQuery(mock, "GetUser").WithArgs(provider.Email).WillReturnRows(userRows())
+		expectQuery(mock, "CreateUser").WithArgs(provider.Email, unusablePasswordHash).WillReturnRows(userRows(user))
+		expectQuery(mock, "CreateUserIdentity").
+			WithArgs(user.ID, provider.Issuer(), provider.Subject, provider.Email).
+			WillReturnRows(identityRows(user.ID, provider.Issuer(), provider.Subject, provider.Email))
+		mock.ExpectCommit()
+		expectExec(mock, "CreateRefreshToken").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		authURL, cookie := startOIDCFlow(t, oidcLoginHandler(cfg), httptest.NewRequest(http.MethodGet, "/api/login/oidc", nil))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != cfg.publicURL+"/app/" {
+			t.Fatalf("Expected a redirect to the app, got %d %s", rec.Code, rec.Body)
+		}
+		if !hasCookie(rec, accessTokenCookie) || !hasCookie(rec, refreshTokenCookie) {
+			t.Errorf("Expected session cookies to be set")
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Linked identity logs in its user", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		user := newTestUser("old@example.com", unusablePasswordHash)
+
+		expectQuery(mock, "GetUserByIdentity").WithArgs(provider.Issuer(), provider.Subject).WillReturnRows(userRows(user))
+		expectExec(mock, "UpdateUserIdentityEmail").WithArgs(provider.Issuer(), provider.Subject, provider.Email).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectExec(mock, "CreateRefreshToken").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), user.ID, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		authURL, cookie := startOIDCFlow(t, oidcLoginHandler(cfg), httptest.NewRequest(http.MethodGet, "/api/login/oidc", nil))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusFound {
+			t.Fatalf("Expected a redirect, got %d %s", rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Verified email of an existing account isn't linked silently", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		provider.EmailVerified = true
+		existing := newTestUser(provider.Email, "$argon2id$hash")
+
+		expectQuery(mock, "GetUserByIdentity").WillReturnRows(userRows())
+		mock.ExpectBegin()
+		expectQuery(mock, "GetUser").WithArgs(provider.Email).WillReturnRows(userRows(existing))
+		mock.ExpectRollback()
+
+		authURL, cookie := startOIDCFlow(t, oidcLoginHandler(cfg), httptest.NewRequest(http.MethodGet, "/api/login/oidc", nil))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusConflict, rec.Code, rec.Body)
+		}
+		if !strings.Contains(rec.Body.String(), "link") {
+			t.Errorf("Expected the error to point to linking, got %s", rec.Body)
+		}
+		if hasCookie(rec, accessTokenCookie) {
+			t.Errorf("Expected no session cookies")
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Unverified email doesn't create a user", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		provider.EmailVerified = false
+
+		expectQuery(mock, "GetUserByIdentity").WillReturnRows(userRows())
+
+		authURL, cookie := startOIDCFlow(t, oidcLoginHandler(cfg), httptest.NewRequest(http.MethodGet, "/api/login/oidc", nil))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusForbidden, rec.Code, rec.Body)
+		}
+		if hasCookie(rec, accessTokenCookie) {
+			t.Errorf("Expected no session cookies")
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Unverified email isn't stored for a linked identity", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		provider.EmailVerified = false
+		user := newTestUser("old@example.com", unusablePasswordHash)
+
+		expectQuery(mock, "GetUserByIdentity").WithArgs(provider.Issuer(), provider.Subject).WillReturnRows(userRows(user))
+		expectExec(mock, "CreateRefreshToken").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), user.ID, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		authURL, cookie := startOIDCFlow(t, oidcLoginHandler(cfg), httptest.NewRequest(http.MethodGet, "/api/login/oidc", nil))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusFound {
+			t.Fatalf("Expected a redirect, got %d %s", rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Flow cookie from another flow is rejected", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+
+		authURL, _ := startOIDCFlow(t, oidcLoginHandler(cfg), httptest.NewRequest(http.MethodGet, "/api/login/oidc", nil))
+		_, otherCookie := startOIDCFlow(t, oidcLoginHandler(cfg), httptest.NewRequest(http.MethodGet, "/api/login/oidc", nil))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, otherCookie)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusBadRequest, rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+}
+
+func TestOIDCLink(t *testing.T) {
+	newLinkRequest := func(userID uuid.UUID) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/users/me/identities/oidc", nil)
+		return req.WithContext(withUser(req.Context(), userID))
+	}
+
+	t.Run("Links the identity to the caller", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		user := newTestUser("walter@example.com", "$argon2id$hash")
+
+		expectQuery(mock, "GetUserByIdentity").WillReturnRows(userRows())
+		expectQuery(mock, "GetUserByID").WithArgs(user.ID).WillReturnRows(userRows(user))
+		expectQuery(mock, "CreateUserIdentity").
+			WithArgs(user.ID, provider.Issuer(), provider.Subject, provider.Email).
+			WillReturnRows(identityRows(user.ID, provider.Issuer(), provider.Subject, provider.Email))
+
+		authURL, cookie := startOIDCFlow(t, oidcLinkHandler(cfg), newLinkRequest(user.ID))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusFound || rec.Header().Get("Location") != cfg.publicURL+"/app/" {
+			t.Fatalf("Expected a redirect to the app, got %d %s", rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Identity linked to another user", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		user := newTestUser("walter@example.com", "$argon2id$hash")
+		other := newTestUser(provider.Email, unusablePasswordHash)
+
+		expectQuery(mock, "GetUserByIdentity").WillReturnRows(userRows(other))
+
+		authURL, cookie := startOIDCFlow(t, oidcLinkHandler(cfg), newLinkRequest(user.ID))
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusConflict, rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Tampered user to link is rejected", func(t *testing.T) {
+		cfg, mock, provider := newOIDCTestConfig(t)
+		user := newTestUser("walter@example.com", "$argon2id$hash")
+
+		authURL, cookie := startOIDCFlow(t, oidcLinkHandler(cfg), newLinkRequest(user.ID))
+		cookie.Value = strings.Replace(cookie.Value, user.ID.String(), uuid.NewString(), 1)
+		rec := finishOIDCFlow(t, cfg, provider, authURL, cookie)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("Expected status %d, got %d %s", http.StatusBadRequest, rec.Code, rec.Body)
+		}
+		expectMockDone(t, mock)
+	})
+
+	t.Run("Not configured", func(t *testing.T) {
+		cfg, _ := newTestConfig(t)
+		rec := httptest.NewRecorder()
+		oidcLinkHandler(cfg)(rec, newLinkRequest(uuid.New()).WithContext(withUser(context.Background(), uuid.New())))
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status %d, got %d", http.StatusNotFound, rec.Code)
+		}
+	})
+}
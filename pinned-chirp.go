@@ -0,0 +1,146 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) pinnedChirpsLimit(user database.User) int {
+	if user.IsChirpyRed {
+		return cfg.pinnedChirpsLimitRed
+	}
+	return cfg.pinnedChirpsLimitFree
+}
+
+// pinChirpHandler pins one of the caller's chirps to their profile. Pins are
+// removed along with the chirp through ON DELETE CASCADE.
+func pinChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		chirp, err := cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID(req)})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			fmt.Printf("Error getting chirp from DB: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirp information")
+			return
+		}
+		if chirp.UserID != userID {
+			respondWithError(res, http.StatusForbidden, "Only your own chirps can be pinned")
+			return
+		}
+
+		tx, err := cfg.db.BeginTx(req.Context(), nil)
+		if err != nil {
+			fmt.Printf("Error starting transaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error pinning chirp")
+			return
+		}
+		defer tx.Rollback()
+		queries := cfg.dbQueries.WithTx(tx)
+
+		// Locking the user serializes concurrent pins so the limit holds
+		user, err := queries.LockUser(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error locking user: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error pinning chirp")
+			return
+		}
+
+		pinned, err := queries.IsChirpPinned(req.Context(), database.IsChirpPinnedParams{UserID: userID, ChirpID: chirpID})
+		if err != nil {
+			fmt.Printf("Error checking pinned chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error pinning chirp")
+			return
+		}
+		if pinned {
+			respondWithJSON(res, http.StatusNoContent, nil)
+			return
+		}
+
+		count, err := queries.CountPinnedChirps(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error counting pinned chirps: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error pinning chirp")
+			return
+		}
+		if limit := cfg.pinnedChirpsLimit(user); int(count) >= limit {
+			respondWithError(res, http.StatusConflict, fmt.Sprintf("You can pin up to %d chirps", limit))
+			return
+		}
+
+		err = queries.PinChirp(req.Context(), database.PinChirpParams{UserID: userID, ChirpID: chirpID})
+		if err != nil {
+			fmt.Printf("Error pinning chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error pinning chirp")
+			return
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			fmt.Printf("Error committing pin: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error pinning chirp")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func unpinChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		err = cfg.dbQueries.UnpinChirp(req.Context(), database.UnpinChirpParams{UserID: userID, ChirpID: chirpID})
+		if err != nil {
+			fmt.Printf("Error unpinning chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error unpinning chirp")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+func getPinnedChirpsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID, err := uuid.Parse(req.PathValue("userID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid user ID, it must be a UUID")
+			return
+		}
+
+		chirps, err := cfg.dbQueries.GetPinnedChirps(req.Context(), database.GetPinnedChirpsParams{UserID: userID, ViewerID: viewerID(req)})
+		if err != nil {
+			fmt.Printf("Error getting pinned chirps: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting pinned chirps")
+			return
+		}
+
+		response, err := cfg.toChirpsForViewer(req, chirps)
+		if err != nil {
+			fmt.Printf("Error getting pinned chirps viewer data: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting pinned chirps")
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestPinChirpLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		chirpyRed  bool
+		pinned     int
+		wantStatus int
+	}{
+		{name: "Under the free limit", pinned: 0, wantStatus: http.StatusNoContent},
+		{name: "At the free limit", pinned: 1, wantStatus: http.StatusConflict},
+		{name: "Chirpy Red raises the limit", chirpyRed: true, pinned: 1, wantStatus: http.StatusNoContent},
+		{name: "At the Chirpy Red limit", chirpyRed: true, pinned: 3, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			cfg.pinnedChirpsLimitFree = 1
+			cfg.pinnedChirpsLimitRed = 3
+			user := newTestUser("walt@example.com", unusablePasswordHash)
+			user.IsChirpyRed = tt.chirpyRed
+			chirpID := uuid.New()
+
+			expectQuery(mock, "GetChirp").WithArgs(chirpID, uuid.NullUUID{UUID: user.ID, Valid: true}).
+				WillReturnRows(chirpRow(chirpID, user.ID, time.Now(), "hello"))
+			mock.ExpectBegin()
+			// The user row is locked before counting so concurrent pins can't pass the limit
+			expectQuery(mock, "LockUser").WithArgs(user.ID).WillReturnRows(userRows(user))
+			expectQuery(mock, "IsChirpPinned").WithArgs(user.ID, chirpID).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			expectQuery(mock, "CountPinnedChirps").WithArgs(user.ID).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.pinned))
+			if tt.wantStatus == http.StatusNoContent {
+				expectExec(mock, "PinChirp").WithArgs(user.ID, chirpID).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/chirps/"+chirpID.String()+"/pin", nil)
+			req.SetPathValue("chirpID", chirpID.String())
+			rec := httptest.NewRecorder()
+			pinChirpHandler(cfg)(rec, req.WithContext(withUser(req.Context(), user.ID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
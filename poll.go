@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/poll"
+)
+
+type PollRequest struct {
+	Options  []string  `json:"options"`
+	ClosesAt time.Time `json:"closes_at"`
+}
+
+type PollVoteRequest struct {
+	OptionID uuid.UUID `json:"option_id"`
+}
+
+// Poll results are only filled in once the caller voted or the poll closed,
+// so they can't influence the vote.
+type Poll struct {
+	ClosesAt      time.Time    `json:"closes_at"`
+	Closed        bool         `json:"closed"`
+	Options       []PollOption `json:"options"`
+	TotalVotes    *int64       `json:"total_votes,omitempty"`
+	VotedOptionID *uuid.UUID   `json:"voted_option_id,omitempty"`
+}
+
+type PollOption struct {
+	ID         uuid.UUID `json:"id"`
+	Text       string    `json:"text"`
+	Votes      *int64    `json:"votes,omitempty"`
+	Percentage *float64  `json:"percentage,omitempty"`
+}
+
+type ExportPollVote struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	OptionID  uuid.UUID `json:"option_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// validatePollRequest checks the poll of a chirp published at publishedAt
+// and returns its cleaned options.
+func validatePollRequest(res http.ResponseWriter, pollRequest PollRequest, publishedAt time.Time) ([]string, bool) {
+	options, err := poll.ValidateOptions(pollRequest.Options)
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	err = poll.ValidateClosesAt(pollRequest.ClosesAt, publishedAt)
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return options, true
+}
+
+func createPoll(ctx context.Context, queries *database.Queries, chirpID uuid.UUID, options []string, closesAt time.Time) error {
+	err := queries.CreatePoll(ctx, database.CreatePollParams{ChirpID: chirpID, ClosesAt: closesAt.Local()})
+	if err != nil {
+		return fmt.Errorf("error creating poll: %w", err)
+	}
+	for i, option := range options {
+		err = queries.CreatePollOption(ctx, database.CreatePollOptionParams{ChirpID: chirpID, Position: int32(i), Text: option})
+		if err != nil {
+			return fmt.Errorf("error creating poll option: %w", err)
+		}
+	}
+	return nil
+}
+
+// setPolls embeds the poll of the chirps that have one. The viewer is
+// optional, anonymous callers only see the results of closed polls.
+func (cfg *apiConfig) setPolls(ctx context.Context, viewerID uuid.NullUUID, chirps []Chirp) error {
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	polls, err := cfg.dbQueries.GetPolls(ctx, chirpIDs)
+	if err != nil {
+		return fmt.Errorf("error getting polls: %w", err)
+	}
+	if len(polls) == 0 {
+		return nil
+	}
+
+	options, err := cfg.dbQueries.GetPollOptionsWithVotes(ctx, chirpIDs)
+	if err != nil {
+		return fmt.Errorf("error getting poll options: %w", err)
+	}
+	optionsByChirp := map[uuid.UUID][]database.GetPollOptionsWithVotesRow{}
+	for _, option := range options {
+		optionsByChirp[option.ChirpID] = append(optionsByChirp[option.ChirpID], option)
+	}
+
+	votedOptions := map[uuid.UUID]uuid.UUID{}
+	if viewerID.Valid {
+		votes, err := cfg.dbQueries.GetUserPollVotes(ctx, database.GetUserPollVotesParams{UserID: viewerID.UUID, ChirpIds: chirpIDs})
+		if err != nil {
+			return fmt.Errorf("error getting poll votes: %w", err)
+		}
+		for _, vote := range votes {
+			votedOptions[vote.ChirpID] = vote.OptionID
+		}
+	}
+
+	pollsByChirp := make(map[uuid.UUID]*Poll, len(polls))
+	for _, chirpPoll := range polls {
+		votedOptionID, voted := votedOptions[chirpPoll.ChirpID]
+		pollsByChirp[chirpPoll.ChirpID] = toPoll(chirpPoll, optionsByChirp[chirpPoll.ChirpID], votedOptionID, voted)
+	}
+	for i := range chirps {
+		chirps[i].Poll = pollsByChirp[chirps[i].ID]
+	}
+	return nil
+}
+
+func toPoll(target database.Poll, options []database.GetPollOptionsWithVotesRow, votedOptionID uuid.UUID, voted bool) *Poll {
+	chirpPoll := &Poll{
+		ClosesAt: target.ClosesAt,
+		Closed:   !time.Now().Before(target.ClosesAt),
+		Options:  make([]PollOption, len(options)),
+	}
+	for i, option := range options {
+		chirpPoll.Options[i] = PollOption{ID: option.ID, Text: option.Text}
+	}
+	if voted {
+		chirpPoll.VotedOptionID = &votedOptionID
+	}
+	if !voted && !chirpPoll.Closed {
+		return chirpPoll
+	}
+
+	votes := make([]int64, len(options))
+	var total int64
+	for i, option := range options {
+		votes[i] = option.Votes
+		total += option.Votes
+	}
+	percentages := poll.Percentages(votes)
+	for i := range chirpPoll.Options {
+		chirpPoll.Options[i].Votes = &votes[i]
+		chirpPoll.Options[i].Percentage = &percentages[i]
+	}
+	chirpPoll.TotalVotes = &total
+	return chirpPoll
+}
+
+// votePollHandler records the caller's vote, votes are final. It responds
+// with the poll including its results.
+func votePollHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		var reqBody PollVoteRequest
+		err = json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, option_id field expected")
+			return
+		}
+
+		chirp, err := cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID(req)})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Chirp not found")
+				return
+			}
+			fmt.Printf("Error getting chirp from DB: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting chirp information")
+			return
+		}
+
+		chirpPoll, err := cfg.dbQueries.GetPoll(req.Context(), chirpID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Chirp has no poll")
+				return
+			}
+			fmt.Printf("Error getting poll: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error voting")
+			return
+		}
+		if !time.Now().Before(chirpPoll.ClosesAt) {
+			respondWithError(res, http.StatusConflict, "Poll is closed")
+			return
+		}
+
+		_, err = cfg.dbQueries.GetPollOption(req.Context(), database.GetPollOptionParams{ID: reqBody.OptionID, ChirpID: chirpID})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusBadRequest, "Unknown poll option")
+				return
+			}
+			fmt.Printf("Error getting poll option: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error voting")
+			return
+		}
+
+		voted, err := cfg.dbQueries.CreatePollVote(req.Context(), database.CreatePollVoteParams{ChirpID: chirpID, UserID: userID, OptionID: reqBody.OptionID})
+		if err != nil {
+			fmt.Printf("Error saving poll vote: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error voting")
+			return
+		}
+		if voted == 0 {
+			respondWithError(res, http.StatusConflict, "You already voted in this poll")
+			return
+		}
+
+		response := []Chirp{toChirp(chirp)}
+		err = cfg.setPolls(req.Context(), uuid.NullUUID{UUID: userID, Valid: true}, response)
+		if err != nil {
+			fmt.Printf("Error getting poll results: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting poll results")
+			return
+		}
+
+		respondWithJSON(res, http.StatusCreated, response[0].Poll)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/pagination"
+	"golang.org/x/text/unicode/norm"
+)
+
+type Reaction struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Emoji     string    `json:"emoji"`
+}
+
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int64  `json:"count"`
+	// The reader's reaction with this emoji, only set for authenticated callers who reacted
+	ReactionID *uuid.UUID `json:"reaction_id,omitempty"`
+}
+
+type ReactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+type ReactionsPage struct {
+	Reactions  []Reaction `json:"reactions"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+func toReaction(target database.Reaction) Reaction {
+	return Reaction{
+		ID:        target.ID,
+		CreatedAt: target.CreatedAt,
+		ChirpID:   target.ChirpID,
+		UserID:    target.UserID,
+		Emoji:     target.Emoji,
+	}
+}
+
+// setReactions adds the reaction counts to the chirps. Reactions with
+// emojis no longer in the configured set aren't counted.
+func (cfg *apiConfig) setReactions(ctx context.Context, viewer uuid.NullUUID, chirps []Chirp) error {
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	counts, err := cfg.dbQueries.GetReactionCounts(ctx, database.GetReactionCountsParams{ChirpIds: chirpIDs, Emojis: cfg.reactionEmojis})
+	if err != nil {
+		return fmt.Errorf("error getting reaction counts: %w", err)
+	}
+	chirpCounts := map[uuid.UUID]map[string]int64{}
+	for _, count := range counts {
+		if chirpCounts[count.ChirpID] == nil {
+			chirpCounts[count.ChirpID] = map[string]int64{}
+		}
+		chirpCounts[count.ChirpID][count.Emoji] = count.Reactions
+	}
+
+	viewerReactions := map[uuid.UUID]map[string]uuid.UUID{}
+	if viewer.Valid {
+		rows, err := cfg.dbQueries.GetViewerReactions(ctx, database.GetViewerReactionsParams{UserID: viewer.UUID, ChirpIds: chirpIDs})
+		if err != nil {
+			return fmt.Errorf("error getting viewer reactions: %w", err)
+		}
+		for _, row := range rows {
+			if viewerReactions[row.ChirpID] == nil {
+				viewerReactions[row.ChirpID] = map[string]uuid.UUID{}
+			}
+			viewerReactions[row.ChirpID][row.Emoji] = row.ID
+		}
+	}
+
+	for i := range chirps {
+		for _, emoji := range cfg.reactionEmojis {
+			count := chirpCounts[chirps[i].ID][emoji]
+			if count == 0 {
+				continue
+			}
+			reactionCount := ReactionCount{Emoji: emoji, Count: count}
+			if reactionID, ok := viewerReactions[chirps[i].ID][emoji]; ok {
+				reactionCount.ReactionID = &reactionID
+			}
+			chirps[i].Reactions = append(chirps[i].Reactions, reactionCount)
+		}
+	}
+	return nil
+}
+
+// getReactionChirp gets the chirp in the path, chirps the caller can't read
+// are reported as not found.
+func (cfg *apiConfig) getReactionChirp(res http.ResponseWriter, req *http.Request) (database.Chirp, bool) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+		return database.Chirp{}, false
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(req.Context(), database.GetChirpParams{ID: chirpID, ViewerID: viewerID(req)})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			respondWithError(res, http.StatusNotFound, "Chirp not found")
+			return database.Chirp{}, false
+		}
+		fmt.Printf("Error getting chirp from DB: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error getting chirp information")
+		return database.Chirp{}, false
+	}
+	return chirp, true
+}
+
+func (cfg *apiConfig) invalidEmojiMessage() string {
+	return fmt.Sprintf("Invalid emoji, it must be one of %s", strings.Join(cfg.reactionEmojis, " "))
+}
+
+// createReactionHandler reacts to a chirp with one of the configured
+// emojis, users can react once with each emoji.
+func createReactionHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		var reqBody ReactionRequest
+		err := json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding body, emoji field expected")
+			return
+		}
+		emoji := norm.NFC.String(strings.TrimSpace(reqBody.Emoji))
+		if !cfg.reactionEmojis.Contains(emoji) {
+			respondWithError(res, http.StatusBadRequest, cfg.invalidEmojiMessage())
+			return
+		}
+
+		chirp, ok := cfg.getReactionChirp(res, req)
+		if !ok {
+			return
+		}
+
+		created, err := cfg.dbQueries.CreateReaction(req.Context(), database.CreateReactionParams{ChirpID: chirp.ID, UserID: userID, Emoji: emoji})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusConflict, "You already reacted with this emoji")
+				return
+			}
+			fmt.Printf("Error creating reaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error reacting to chirp")
+			return
+		}
+
+		if chirp.UserID != userID {
+			cfg.notify(req.Context(), userID, []uuid.UUID{chirp.UserID}, Notification{Kind: "reaction", UserID: userID, ChirpID: &chirp.ID})
+		}
+
+		respondWithJSON(res, http.StatusCreated, toReaction(created))
+	}
+}
+
+// deleteReactionHandler removes a reaction, only the user who reacted can
+// remove it. The chirp isn't checked for visibility, users keep being able
+// to remove their reactions after losing access to the chirp.
+func deleteReactionHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+		reactionID, err := uuid.Parse(req.PathValue("reactionID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid reaction ID, it must be a UUID")
+			return
+		}
+
+		target, err := cfg.dbQueries.GetReaction(req.Context(), database.GetReactionParams{ID: reactionID, ChirpID: chirpID})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Reaction not found")
+				return
+			}
+			fmt.Printf("Error getting reaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting reaction")
+			return
+		}
+
+		if target.UserID != userID {
+			respondWithError(res, http.StatusForbidden, "Forbidden")
+			return
+		}
+
+		deleted, err := cfg.dbQueries.DeleteReaction(req.Context(), database.DeleteReactionParams{ID: target.ID, ChirpID: chirpID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error deleting reaction: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error deleting reaction")
+			return
+		}
+		if deleted == 0 {
+			respondWithError(res, http.StatusNotFound, "Reaction not found")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+// getReactionsHandler lists who reacted to a chirp, last reactions first,
+// optionally with a single emoji.
+func getReactionsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		query := req.URL.Query()
+
+		limit, err := pagination.ParseLimit(query.Get("limit"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid limit, it must be a positive number")
+			return
+		}
+
+		params := database.GetReactionsParams{Emojis: cfg.reactionEmojis, PageLimit: int32(limit + 1)}
+		if emoji := query.Get("emoji"); emoji != "" {
+			emoji = norm.NFC.String(emoji)
+			if !cfg.reactionEmojis.Contains(emoji) {
+				respondWithError(res, http.StatusBadRequest, cfg.invalidEmojiMessage())
+				return
+			}
+			params.Emojis = []string{emoji}
+		}
+		if rawCursor := query.Get("cursor"); rawCursor != "" {
+			cursor, err := pagination.DecodeCursor(rawCursor)
+			if err != nil {
+				respondWithError(res, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			params.CursorTime = sql.NullTime{Time: cursor.Time, Valid: true}
+			params.CursorID = uuid.NullUUID{UUID: cursor.ID, Valid: true}
+		}
+
+		chirp, ok := cfg.getReactionChirp(res, req)
+		if !ok {
+			return
+		}
+		params.ChirpID = chirp.ID
+
+		rows, err := cfg.dbQueries.GetReactions(req.Context(), params)
+		if err != nil {
+			fmt.Printf("Error getting reactions: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting reactions")
+			return
+		}
+
+		page := ReactionsPage{}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			last := rows[len(rows)-1]
+			page.NextCursor = pagination.Cursor{Time: last.CreatedAt, ID: last.ID}.Encode()
+		}
+		page.Reactions = make([]Reaction, len(rows))
+		for i, row := range rows {
+			page.Reactions[i] = toReaction(row)
+		}
+
+		respondWithJSON(res, http.StatusOK, page)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+var reactionColumns = []string{"id", "created_at", "chirp_id", "user_id", "emoji"}
+
+func TestDeleteReaction(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		reactorID  uuid.UUID
+		found      bool
+		wantStatus int
+	}{
+		// No visibility check, the chirp may no longer be readable
+		{name: "Own reaction", reactorID: userID, found: true, wantStatus: http.StatusNoContent},
+		{name: "Reaction of another user", reactorID: uuid.New(), found: true, wantStatus: http.StatusForbidden},
+		{name: "Reaction not found", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			chirpID := uuid.New()
+			reactionID := uuid.New()
+
+			rows := sqlmock.NewRows(reactionColumns)
+			if tt.found {
+				rows.AddRow(reactionID.String(), time.Now(), chirpID.String(), tt.reactorID.String(), "👍")
+			}
+			expectQuery(mock, "GetReaction").WithArgs(reactionID, chirpID).WillReturnRows(rows)
+			if tt.wantStatus == http.StatusNoContent {
+				expectExec(mock, "DeleteReaction").WithArgs(reactionID, chirpID, userID).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/chirps/"+chirpID.String()+"/reactions/"+reactionID.String(), nil)
+			req.SetPathValue("chirpID", chirpID.String())
+			req.SetPathValue("reactionID", reactionID.String())
+			rec := httptest.NewRecorder()
+			deleteReactionHandler(cfg)(rec, req.WithContext(withUser(req.Context(), userID)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d %s", tt.wantStatus, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
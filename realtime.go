@@ -0,0 +1,490 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/ivportilla/chirpy/internal/auth"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/realtime"
+	"github.com/lib/pq"
+)
+
+// Events are published through Postgres NOTIFY so every server replica
+// delivers them to the connections it holds. Payloads must be shorter than
+// realtimeMaxPayload bytes.
+const (
+	realtimeChannel    = "realtime"
+	realtimeMaxPayload = 8000
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingInterval   = wsPongWait * 9 / 10
+	wsMaxMessageSize = 4096
+
+	wsReasonShutdown = "server shutting down"
+
+	// Each connection sends at most one typing event per conversation every
+	// typingInterval, and checks membership again after typingMembersTTL.
+	// Up to typingMaxConversations memberships are cached per connection
+	typingInterval         = 3 * time.Second
+	typingMembersTTL       = 30 * time.Second
+	typingMaxConversations = 100
+)
+
+// Event types sent to clients.
+const (
+	eventMessage      = "message"
+	eventNotification = "notification"
+	eventTyping       = "typing"
+	eventPresence     = "presence"
+)
+
+// Messages clients can send, auth renews the connection with a fresh
+// access token before the current one expires.
+const (
+	clientMessageAuth   = "auth"
+	clientMessageTyping = "typing"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+type ClientMessage struct {
+	Type           string    `json:"type"`
+	Token          string    `json:"token"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+}
+
+type Notification struct {
+	Kind    string     `json:"kind"`
+	UserID  uuid.UUID  `json:"user_id"`
+	ChirpID *uuid.UUID `json:"chirp_id,omitempty"`
+}
+
+type TypingEvent struct {
+	ConversationID uuid.UUID `json:"conversation_id"`
+	UserID         uuid.UUID `json:"user_id"`
+}
+
+type PresenceEvent struct {
+	UserID uuid.UUID `json:"user_id"`
+	Online bool      `json:"online"`
+}
+
+// publish sends an event to every connection of the users. Delivery is
+// best effort, failures are only logged.
+func (cfg *apiConfig) publish(ctx context.Context, userIDs []uuid.UUID, eventType string, data any) {
+	if len(userIDs) == 0 {
+		return
+	}
+	envelope, err := realtime.NewEnvelope(userIDs, eventType, data)
+	if err != nil {
+		fmt.Printf("Error building %s event: %v\n", eventType, err)
+		return
+	}
+	cfg.notifyRealtime(ctx, envelope)
+}
+
+// disconnectUser closes all the user's connections, clients have to
+// reconnect with a valid access token.
+func (cfg *apiConfig) disconnectUser(ctx context.Context, userID uuid.UUID, reason string) {
+	cfg.notifyRealtime(ctx, realtime.Envelope{
+		UserIDs:    []uuid.UUID{userID},
+		Event:      realtime.Event{Type: reason},
+		Disconnect: true,
+	})
+}
+
+// disconnectSession closes the user's connections opened with the session.
+func (cfg *apiConfig) disconnectSession(ctx context.Context, userID, sessionID uuid.UUID, reason string) {
+	cfg.notifyRealtime(ctx, realtime.Envelope{
+		UserIDs:    []uuid.UUID{userID},
+		Event:      realtime.Event{Type: reason},
+		Disconnect: true,
+		SessionID:  &sessionID,
+	})
+}
+
+func (cfg *apiConfig) notifyRealtime(ctx context.Context, envelope realtime.Envelope) {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		fmt.Printf("Error encoding realtime envelope: %v\n", err)
+		return
+	}
+	if len(payload) >= realtimeMaxPayload {
+		fmt.Printf("Error publishing realtime event %s: payload of %d bytes is too large\n", envelope.Event.Type, len(payload))
+		return
+	}
+	err = cfg.dbQueries.NotifyRealtime(ctx, string(payload))
+	if err != nil {
+		fmt.Printf("Error publishing realtime event: %v\n", err)
+	}
+}
+
+// publishMessage sends a conversation message to its members, plus the
+// extra users, like members that were just removed. Only the message ID
+// goes through NOTIFY, bodies can be larger than what it allows.
+func (cfg *apiConfig) publishMessage(ctx context.Context, message database.Message, extraUserIDs ...uuid.UUID) {
+	members, err := cfg.dbQueries.GetConversationMembers(ctx, []uuid.UUID{message.ConversationID})
+	if err != nil {
+		fmt.Printf("Error getting conversation members: %v\n", err)
+		return
+	}
+	userIDs := extraUserIDs
+	for _, member := range members {
+		userIDs = append(userIDs, member.UserID)
+	}
+	cfg.notifyRealtime(ctx, realtime.Envelope{
+		UserIDs: userIDs,
+		Event:   realtime.Event{Type: eventMessage},
+		Message: &realtime.MessageRef{ID: message.ID, ConversationID: message.ConversationID},
+	})
+}
+
+// anyOnline reports whether one of the users has a connection to this
+// replica.
+func (cfg *apiConfig) anyOnline(userIDs []uuid.UUID) bool {
+	for _, userID := range userIDs {
+		if cfg.realtimeHub.Online(userID) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadEnvelopeMessage fills the event of an envelope referencing a
+// message, it returns false when the message no longer exists.
+func (cfg *apiConfig) loadEnvelopeMessage(ctx context.Context, envelope *realtime.Envelope) (bool, error) {
+	message, err := cfg.dbQueries.GetMessage(ctx, database.GetMessageParams{ID: envelope.Message.ID, ConversationID: envelope.Message.ConversationID})
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	envelope.Event.Data, err = json.Marshal(toMessage(message))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// notifyMentions tells the mentioned users about published chirps.
+func (cfg *apiConfig) notifyMentions(ctx context.Context, chirps []database.Chirp) {
+	if len(chirps) == 0 {
+		return
+	}
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	mentions, err := cfg.dbQueries.GetChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		fmt.Printf("Error getting mentions: %v\n", err)
+		return
+	}
+	mentioned := map[uuid.UUID][]uuid.UUID{}
+	for _, mention := range mentions {
+		mentioned[mention.ChirpID] = append(mentioned[mention.ChirpID], mention.UserID)
+	}
+	for _, chirp := range chirps {
+		if len(mentioned[chirp.ID]) == 0 {
+			continue
+		}
+		chirpID := chirp.ID
+		cfg.notify(ctx, chirp.UserID, mentioned[chirp.ID], Notification{Kind: "mention", UserID: chirp.UserID, ChirpID: &chirpID})
+	}
+}
+
+// notify publishes a notification about something the author did, leaving
+// out the recipients that muted or blocked the author or were blocked by them.
+func (cfg *apiConfig) notify(ctx context.Context, authorID uuid.UUID, recipientIDs []uuid.UUID, notification Notification) {
+	recipientIDs, err := cfg.dbQueries.GetNotificationRecipients(ctx, database.GetNotificationRecipientsParams{
+		RecipientIds: recipientIDs,
+		AuthorID:     authorID,
+	})
+	if err != nil {
+		fmt.Printf("Error getting notification recipients: %v\n", err)
+		return
+	}
+	cfg.publish(ctx, recipientIDs, eventNotification, notification)
+}
+
+// publishPresence tells the users sharing a conversation with the user
+// that they came online or went offline.
+func (cfg *apiConfig) publishPresence(ctx context.Context, userID uuid.UUID, online bool) {
+	partners, err := cfg.dbQueries.GetConversationPartners(ctx, userID)
+	if err != nil {
+		fmt.Printf("Error getting conversation partners: %v\n", err)
+		return
+	}
+	cfg.publish(ctx, partners, eventPresence, PresenceEvent{UserID: userID, Online: online})
+}
+
+// listenRealtime delivers the events published by any replica to the
+// connections of this one until the context is cancelled.
+func (cfg *apiConfig) listenRealtime(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			fmt.Printf("Error in realtime listener: %v\n", err)
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(realtimeChannel)
+	if err != nil {
+		fmt.Printf("Error listening to realtime events: %v\n", err)
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-listener.Notify:
+			// nil after a reconnection, events sent in between are lost
+			if notification == nil {
+				continue
+			}
+			var envelope realtime.Envelope
+			err := json.Unmarshal([]byte(notification.Extra), &envelope)
+			if err != nil {
+				fmt.Printf("Error decoding realtime envelope: %v\n", err)
+				continue
+			}
+			if envelope.Message != nil {
+				if !cfg.anyOnline(envelope.UserIDs) {
+					continue
+				}
+				found, err := cfg.loadEnvelopeMessage(ctx, &envelope)
+				if err != nil {
+					fmt.Printf("Error loading realtime message: %v\n", err)
+				}
+				if !found {
+					continue
+				}
+			}
+			cfg.realtimeHub.Deliver(envelope)
+		case <-time.After(90 * time.Second):
+			go listener.Ping()
+		}
+	}
+}
+
+// wsHandler upgrades to a WebSocket that streams the user's events. It
+// accepts the same access tokens as withAuthMiddleware and closes the
+// connection when the token expires unless the client sends a fresh one of
+// the same session in an auth message first.
+func wsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		token, _, err := getSessionToken(req, accessTokenCookie)
+		if err != nil {
+			respondWithError(res, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		claims, ok := cfg.authenticate(res, req, token)
+		if !ok {
+			return
+		}
+		userID := claims.UserID
+
+		// The upgrader responds itself when the handshake fails
+		conn, err := upgrader.Upgrade(res, req, nil)
+		if err != nil {
+			fmt.Printf("Error upgrading connection: %v\n", err)
+			return
+		}
+		defer conn.Close()
+
+		ctx := req.Context()
+		client, first := cfg.realtimeHub.Register(userID, claims.SessionID)
+		if first {
+			cfg.publishPresence(ctx, userID, true)
+		}
+		defer func() {
+			if cfg.realtimeHub.Unregister(client) {
+				cfg.publishPresence(ctx, userID, false)
+			}
+		}()
+
+		renewals := make(chan time.Time)
+		readDone := make(chan struct{})
+		writeDone := make(chan struct{})
+		defer close(writeDone)
+		go cfg.readClientMessages(ctx, conn, claims, renewals, readDone, writeDone)
+
+		ping := time.NewTicker(wsPingInterval)
+		defer ping.Stop()
+		expiry := time.NewTimer(time.Until(claims.ExpiresAt))
+		defer expiry.Stop()
+
+		for {
+			select {
+			case event := <-client.Events():
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case <-ping.C:
+				conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			case expiresAt := <-renewals:
+				expiry.Reset(time.Until(expiresAt))
+			case <-expiry.C:
+				closeConnection(conn, websocket.ClosePolicyViolation, "token expired")
+				return
+			case <-client.Done():
+				code := websocket.ClosePolicyViolation
+				if client.Reason() == wsReasonShutdown {
+					code = websocket.CloseGoingAway
+				}
+				closeConnection(conn, code, client.Reason())
+				return
+			case <-readDone:
+				return
+			}
+		}
+	}
+}
+
+func closeConnection(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(wsWriteWait))
+}
+
+// readClientMessages handles the messages sent by the client until the
+// connection fails or stops answering pings.
+func (cfg *apiConfig) readClientMessages(ctx context.Context, conn *websocket.Conn, claims auth.AccessClaims, renewals chan<- time.Time, done chan<- struct{}, writeDone <-chan struct{}) {
+	defer close(done)
+	userID := claims.UserID
+
+	typing := typingCache{}
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var message ClientMessage
+		if json.Unmarshal(data, &message) != nil {
+			continue
+		}
+
+		switch message.Type {
+		case clientMessageAuth:
+			// Renewals must come from the same session, and it must still be active
+			renewed, err := auth.ValidateJWT(message.Token, cfg.authSecret)
+			if err != nil || renewed.UserID != userID || renewed.SessionID != claims.SessionID {
+				continue
+			}
+			active, err := cfg.isSessionActive(ctx, renewed)
+			if err != nil {
+				fmt.Printf("Error getting session: %v\n", err)
+				continue
+			}
+			if !active {
+				continue
+			}
+			select {
+			case renewals <- renewed.ExpiresAt:
+			case <-writeDone:
+				return
+			}
+		case clientMessageTyping:
+			cfg.publishTyping(ctx, typing, userID, message.ConversationID)
+		}
+	}
+}
+
+// typingCache keeps, per conversation of a connection, who receives the
+// user's typing events and when the last one was sent.
+type typingCache map[uuid.UUID]*typingConversation
+
+type typingConversation struct {
+	// Empty when the user isn't a member
+	recipients []uuid.UUID
+	loadedAt   time.Time
+	sentAt     time.Time
+}
+
+// removeExpired drops the conversations whose members must be checked
+// again.
+func (c typingCache) removeExpired(now time.Time) {
+	for conversationID, conversation := range c {
+		if now.Sub(conversation.loadedAt) >= typingMembersTTL {
+			delete(c, conversationID)
+		}
+	}
+}
+
+// publishTyping tells the other members of a conversation the user is
+// typing, it is ignored if the user isn't a member or sent one less than
+// typingInterval ago.
+func (cfg *apiConfig) publishTyping(ctx context.Context, cache typingCache, userID, conversationID uuid.UUID) {
+	now := time.Now()
+	conversation, ok := cache[conversationID]
+	if !ok || now.Sub(conversation.loadedAt) >= typingMembersTTL {
+		if len(cache) >= typingMaxConversations {
+			cache.removeExpired(now)
+			if len(cache) >= typingMaxConversations {
+				return
+			}
+		}
+		recipients, err := cfg.typingRecipients(ctx, userID, conversationID)
+		if err != nil {
+			fmt.Printf("Error getting typing recipients: %v\n", err)
+			return
+		}
+		conversation = &typingConversation{recipients: recipients, loadedAt: now}
+		cache[conversationID] = conversation
+	}
+
+	if len(conversation.recipients) == 0 || now.Sub(conversation.sentAt) < typingInterval {
+		return
+	}
+	conversation.sentAt = now
+	cfg.publish(ctx, conversation.recipients, eventTyping, TypingEvent{ConversationID: conversationID, UserID: userID})
+}
+
+// typingRecipients returns the other members of the conversation, none if
+// the user isn't a member.
+func (cfg *apiConfig) typingRecipients(ctx context.Context, userID, conversationID uuid.UUID) ([]uuid.UUID, error) {
+	_, err := cfg.dbQueries.GetConversationForMember(ctx, database.GetConversationForMemberParams{ID: conversationID, UserID: userID})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	members, err := cfg.dbQueries.GetConversationMembers(ctx, []uuid.UUID{conversationID})
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []uuid.UUID{}
+	for _, member := range members {
+		if member.UserID != userID {
+			userIDs = append(userIDs, member.UserID)
+		}
+	}
+	return userIDs, nil
+}
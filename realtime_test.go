@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/chirptext"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/realtime"
+)
+
+var messageColumns = []string{"id", "created_at", "conversation_id", "sender_id", "body", "event", "user_id"}
+
+// payloadMatcher checks the NOTIFY payload of a realtime event.
+type payloadMatcher func(payload string) bool
+
+func (m payloadMatcher) Match(value driver.Value) bool {
+	payload, ok := value.(string)
+	return ok && m(payload)
+}
+
+func TestPublishMessage(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	senderID := uuid.New()
+	message := database.Message{
+		ID:             uuid.New(),
+		CreatedAt:      time.Now(),
+		ConversationID: uuid.New(),
+		SenderID:       uuid.NullUUID{UUID: senderID, Valid: true},
+		Body:           strings.Repeat("ж", chirptext.MaxBytes/2),
+	}
+
+	expectQuery(mock, "GetConversationMembers").
+		WillReturnRows(sqlmock.NewRows(conversationMemberColumns).AddRow(message.ConversationID.String(), senderID.String(), time.Now(), nil))
+	expectExec(mock, "NotifyRealtime").
+		WithArgs(payloadMatcher(func(payload string) bool {
+			return len(payload) < realtimeMaxPayload && !strings.Contains(payload, "ж")
+		})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	cfg.publishMessage(context.Background(), message)
+	expectMockDone(t, mock)
+}
+
+func TestLoadEnvelopeMessage(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	messageID := uuid.New()
+	conversationID := uuid.New()
+	senderID := uuid.New()
+
+	expectQuery(mock, "GetMessage").WithArgs(messageID, conversationID).
+		WillReturnRows(sqlmock.NewRows(messageColumns).AddRow(messageID.String(), time.Now(), conversationID.String(), senderID.String(), "hi", nil, nil))
+
+	envelope := realtime.Envelope{
+		UserIDs: []uuid.UUID{senderID},
+		Event:   realtime.Event{Type: eventMessage},
+		Message: &realtime.MessageRef{ID: messageID, ConversationID: conversationID},
+	}
+	found, err := cfg.loadEnvelopeMessage(context.Background(), &envelope)
+	if err != nil || !found {
+		t.Fatalf("loadEnvelopeMessage() = %v, %v", found, err)
+	}
+
+	var got Message
+	err = json.Unmarshal(envelope.Event.Data, &got)
+	if err != nil {
+		t.Fatalf("Error decoding event data: %v", err)
+	}
+	if got.ID != messageID || got.Body != "hi" {
+		t.Errorf("Expected message %s with its body, got %+v", messageID, got)
+	}
+	expectMockDone(t, mock)
+}
+
+func TestNotifyRealtimeDropsLargePayloads(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+
+	cfg.publish(context.Background(), []uuid.UUID{uuid.New()}, eventNotification, map[string]string{"body": strings.Repeat("a", realtimeMaxPayload)})
+	expectMockDone(t, mock)
+}
+
+func TestPublishTyping(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	userID := uuid.New()
+	memberID := uuid.New()
+	conversationID := uuid.New()
+	otherConversationID := uuid.New()
+	now := time.Now()
+
+	expectQuery(mock, "GetConversationForMember").WithArgs(conversationID, userID).
+		WillReturnRows(sqlmock.NewRows(conversationColumns).AddRow(conversationID.String(), now, now, "key", conversationKindDirect, nil, nil))
+	expectQuery(mock, "GetConversationMembers").
+		WillReturnRows(sqlmock.NewRows(conversationMemberColumns).
+			AddRow(conversationID.String(), userID.String(), now, nil).
+			AddRow(conversationID.String(), memberID.String(), now, nil))
+	expectExec(mock, "NotifyRealtime").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectQuery(mock, "GetConversationForMember").WithArgs(otherConversationID, userID).
+		WillReturnRows(sqlmock.NewRows(conversationColumns))
+
+	cache := typingCache{}
+	// Repeated events within typingInterval are dropped and membership is
+	// checked once, also for conversations the user isn't in
+	for range 3 {
+		cfg.publishTyping(context.Background(), cache, userID, conversationID)
+		cfg.publishTyping(context.Background(), cache, userID, otherConversationID)
+	}
+	expectMockDone(t, mock)
+
+	cache[conversationID].sentAt = now.Add(-typingInterval)
+	expectExec(mock, "NotifyRealtime").WillReturnResult(sqlmock.NewResult(0, 0))
+	cfg.publishTyping(context.Background(), cache, userID, conversationID)
+	expectMockDone(t, mock)
+}
+
+func TestNotifyMentions(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	authorID := uuid.New()
+	friendID := uuid.New()
+	blockerID := uuid.New()
+	chirp := database.Chirp{ID: uuid.New(), UserID: authorID}
+
+	expectQuery(mock, "GetChirpMentions").
+		WillReturnRows(sqlmock.NewRows([]string{"chirp_id", "user_id"}).
+			AddRow(chirp.ID.String(), friendID.String()).
+			AddRow(chirp.ID.String(), blockerID.String()))
+	expectQuery(mock, "GetNotificationRecipients").WithArgs(sqlmock.AnyArg(), authorID).
+		WillReturnRows(sqlmock.NewRows([]string{"recipient_id"}).AddRow(friendID.String()))
+	expectExec(mock, "NotifyRealtime").
+		WithArgs(payloadMatcher(func(payload string) bool {
+			var envelope realtime.Envelope
+			err := json.Unmarshal([]byte(payload), &envelope)
+			return err == nil && len(envelope.UserIDs) == 1 && envelope.UserIDs[0] == friendID
+		})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	cfg.notifyMentions(context.Background(), []database.Chirp{chirp})
+	expectMockDone(t, mock)
+}
+
+func TestNotifyHiddenRecipients(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	authorID := uuid.New()
+
+	// Nothing is published when every recipient muted or blocked the author
+	expectQuery(mock, "GetNotificationRecipients").WithArgs(sqlmock.AnyArg(), authorID).
+		WillReturnRows(sqlmock.NewRows([]string{"recipient_id"}))
+
+	cfg.notify(context.Background(), authorID, []uuid.UUID{uuid.New()}, Notification{Kind: "reaction", UserID: authorID})
+	expectMockDone(t, mock)
+}
@@ -9,6 +9,17 @@ type JsonErrorResponse struct {
 	Error string `json:"error"`
 }
 
+type ValidationError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type JsonValidationErrorResponse struct {
+	Error   string            `json:"error"`
+	Details []ValidationError `json:"details"`
+}
+
 func respondWithError(res http.ResponseWriter, code int, msg string) {
 	data, _ := json.Marshal(JsonErrorResponse{Error: msg})
 	res.Header().Set("Content-Type", "application/json")
@@ -16,6 +27,10 @@ func respondWithError(res http.ResponseWriter, code int, msg string) {
 	res.Write([]byte(data))
 }
 
+func respondWithValidationError(res http.ResponseWriter, msg string, details []ValidationError) {
+	respondWithJSON(res, http.StatusUnprocessableEntity, JsonValidationErrorResponse{Error: msg, Details: details})
+}
+
 func respondWithJSON(res http.ResponseWriter, code int, payload interface{}) {
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ivportilla/chirpy/internal/database"
+	"github.com/ivportilla/chirpy/internal/poll"
+)
+
+const chirpStatusScheduled = "scheduled"
+
+func getScheduledChirpsHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+
+		chirps, err := cfg.dbQueries.GetScheduledChirpsByAuthor(req.Context(), userID)
+		if err != nil {
+			fmt.Printf("Error getting scheduled chirps: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting scheduled chirps")
+			return
+		}
+
+		response, err := cfg.toChirpsForViewer(req, chirps)
+		if err != nil {
+			fmt.Printf("Error getting scheduled chirps viewer data: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting scheduled chirps")
+			return
+		}
+
+		respondWithJSON(res, http.StatusOK, response)
+	}
+}
+
+// editScheduledChirpHandler changes the body and/or the publication time of
+// a chirp that hasn't been published yet.
+func editScheduledChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		defer req.Body.Close()
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		var reqBody RequestParams
+		err = json.NewDecoder(req.Body).Decode(&reqBody)
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Error decoding request")
+			return
+		}
+
+		chirp, err := cfg.dbQueries.GetScheduledChirp(req.Context(), database.GetScheduledChirpParams{ID: chirpID, UserID: userID})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Scheduled chirp not found")
+				return
+			}
+			fmt.Printf("Error getting scheduled chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error getting scheduled chirp")
+			return
+		}
+
+		body := chirp.Body
+		if reqBody.Body != "" {
+			validBody, ok := cfg.validateChirpBody(res, req, userID, reqBody.Body)
+			if !ok {
+				return
+			}
+			body = sanitizeChirp(validBody)
+		}
+
+		publishAt := chirp.PublishAt
+		if reqBody.PublishAt != nil {
+			if !reqBody.PublishAt.After(time.Now()) {
+				respondWithError(res, http.StatusBadRequest, "publish_at must be in the future")
+				return
+			}
+			publishAt = sql.NullTime{Time: reqBody.PublishAt.Local(), Valid: true}
+
+			chirpPoll, err := cfg.dbQueries.GetPoll(req.Context(), chirpID)
+			if err != nil && err != sql.ErrNoRows {
+				fmt.Printf("Error getting poll: %v\n", err)
+				respondWithError(res, http.StatusInternalServerError, "Error updating scheduled chirp")
+				return
+			}
+			if err == nil && poll.ValidateClosesAt(chirpPoll.ClosesAt, publishAt.Time) != nil {
+				respondWithError(res, http.StatusBadRequest, "The new publish_at doesn't fit the poll closing time")
+				return
+			}
+		}
+
+		chirp, err = cfg.dbQueries.UpdateScheduledChirp(req.Context(), database.UpdateScheduledChirpParams{
+			ID:        chirpID,
+			UserID:    userID,
+			Body:      body,
+			PublishAt: publishAt,
+		})
+		if err != nil {
+			// The scheduler may have published it in the meantime
+			if err == sql.ErrNoRows {
+				respondWithError(res, http.StatusNotFound, "Scheduled chirp not found")
+				return
+			}
+			fmt.Printf("Error updating scheduled chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating scheduled chirp")
+			return
+		}
+
+		err = saveChirpLinks(req.Context(), cfg.dbQueries, chirp.ID, chirp.Body)
+		if err != nil {
+			fmt.Printf("Error saving chirp links: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error updating scheduled chirp")
+			return
+		}
+
+		cfg.respondWithChirp(res, req, http.StatusOK, chirp)
+	}
+}
+
+func cancelScheduledChirpHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
+	return func(res http.ResponseWriter, req *http.Request) {
+		userID := uuid.MustParse(req.Context().Value("user_id").(string))
+		chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+		if err != nil {
+			respondWithError(res, http.StatusBadRequest, "Invalid chirp ID, it must be a UUID")
+			return
+		}
+
+		deleted, err := cfg.dbQueries.DeleteScheduledChirp(req.Context(), database.DeleteScheduledChirpParams{ID: chirpID, UserID: userID})
+		if err != nil {
+			fmt.Printf("Error cancelling scheduled chirp: %v\n", err)
+			respondWithError(res, http.StatusInternalServerError, "Error cancelling scheduled chirp")
+			return
+		}
+		if deleted == 0 {
+			respondWithError(res, http.StatusNotFound, "Scheduled chirp not found")
+			return
+		}
+
+		respondWithJSON(res, http.StatusNoContent, nil)
+	}
+}
+
+// publishScheduledChirps publishes every chirp whose time has come. The
+// status change happens in a single UPDATE, so with several replicas
+// running the scheduler each chirp is still published exactly once, by
+// the replica that gets it back from the query.
+func (cfg *apiConfig) publishScheduledChirps(ctx context.Context) error {
+	chirps, err := cfg.dbQueries.PublishDueChirps(ctx)
+	if err != nil {
+		return fmt.Errorf("error publishing scheduled chirps: %w", err)
+	}
+
+	if len(chirps) > 0 {
+		fmt.Printf("Published %d scheduled chirps\n", len(chirps))
+	}
+	cfg.notifyMentions(ctx, chirps)
+	return nil
+}
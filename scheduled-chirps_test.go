@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func scheduledChirpRow(id, userID uuid.UUID, publishAt time.Time) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(chirpColumns).AddRow(id.String(), now, now, "hello", userID.String(), nil, chirpStatusScheduled, publishAt, chirpVisibilityPublic, nil, false)
+}
+
+func TestPublishScheduledChirps(t *testing.T) {
+	cfg, mock := newTestConfig(t)
+	chirpID := uuid.New()
+	authorID := uuid.New()
+	mentionedID := uuid.New()
+
+	// Two replicas run the scheduler, only the first gets the chirp back
+	expectQuery(mock, "PublishDueChirps").WillReturnRows(chirpRow(chirpID, authorID, time.Now(), "hello"))
+	expectQuery(mock, "GetChirpMentions").
+		WillReturnRows(sqlmock.NewRows([]string{"chirp_id", "user_id"}).AddRow(chirpID.String(), mentionedID.String()))
+	expectQuery(mock, "GetNotificationRecipients").
+		WillReturnRows(sqlmock.NewRows([]string{"recipient_id"}).AddRow(mentionedID.String()))
+	expectExec(mock, "NotifyRealtime").WillReturnResult(sqlmock.NewResult(0, 0))
+	expectQuery(mock, "PublishDueChirps").WillReturnRows(sqlmock.NewRows(chirpColumns))
+
+	for range 2 {
+		err := cfg.publishScheduledChirps(context.Background())
+		if err != nil {
+			t.Fatalf("publishScheduledChirps() error = %v", err)
+		}
+	}
+	expectMockDone(t, mock)
+}
+
+func TestScheduledChirpAfterPublish(t *testing.T) {
+	newScheduledRequest := func(method string, userID, chirpID uuid.UUID, body string) *http.Request {
+		req := httptest.NewRequest(method, "/api/chirps/scheduled/"+chirpID.String(), strings.NewReader(body))
+		req.SetPathValue("chirpID", chirpID.String())
+		return req.WithContext(withUser(req.Context(), userID))
+	}
+
+	tests := []struct {
+		name    string
+		handler func(*apiConfig) func(http.ResponseWriter, *http.Request)
+		method  string
+		expect  func(mock sqlmock.Sqlmock, userID, chirpID uuid.UUID)
+	}{
+		{
+			name:    "Edit after publish",
+			handler: editScheduledChirpHandler,
+			method:  http.MethodPatch,
+			expect: func(mock sqlmock.Sqlmock, userID, chirpID uuid.UUID) {
+				expectQuery(mock, "GetScheduledChirp").WithArgs(chirpID, userID).WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "Published while editing",
+			handler: editScheduledChirpHandler,
+			method:  http.MethodPatch,
+			expect: func(mock sqlmock.Sqlmock, userID, chirpID uuid.UUID) {
+				expectQuery(mock, "GetScheduledChirp").WithArgs(chirpID, userID).WillReturnRows(scheduledChirpRow(chirpID, userID, time.Now().Add(time.Minute)))
+				expectQuery(mock, "UpdateScheduledChirp").WillReturnError(sql.ErrNoRows)
+			},
+		},
+		{
+			name:    "Cancel after publish",
+			handler: cancelScheduledChirpHandler,
+			method:  http.MethodDelete,
+			expect: func(mock sqlmock.Sqlmock, userID, chirpID uuid.UUID) {
+				expectExec(mock, "DeleteScheduledChirp").WithArgs(chirpID, userID).WillReturnResult(sqlmock.NewResult(0, 0))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, mock := newTestConfig(t)
+			userID := uuid.New()
+			chirpID := uuid.New()
+			tt.expect(mock, userID, chirpID)
+
+			rec := httptest.NewRecorder()
+			tt.handler(cfg)(rec, newScheduledRequest(tt.method, userID, chirpID, `{}`))
+
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("Expected status %d, got %d %s", http.StatusNotFound, rec.Code, rec.Body)
+			}
+			expectMockDone(t, mock)
+		})
+	}
+}
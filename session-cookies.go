@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/ivportilla/chirpy/internal/auth"
+)
+
+const (
+	accessTokenCookie  = "chirpy_access"
+	refreshTokenCookie = "chirpy_refresh"
+	csrfTokenCookie    = "chirpy_csrf"
+
+	sessionModeCookie = "cookie"
+)
+
+func (cfg *apiConfig) newSessionCookie(name, value string, maxAge time.Duration, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(maxAge / time.Second),
+		HttpOnly: httpOnly,
+		Secure:   cfg.platform != "dev",
+		SameSite: http.SameSiteStrictMode,
+	}
+}
+
+// setSessionCookies stores the token pair in HttpOnly cookies, plus a CSRF
+// token readable from JavaScript that must be echoed in the X-CSRF-Token
+// header of every state-changing request.
+func (cfg *apiConfig) setSessionCookies(res http.ResponseWriter, accessToken, refreshToken string, refreshExpiresIn time.Duration) error {
+	csrfToken, err := auth.MakeCSRFToken()
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(res, cfg.newSessionCookie(accessTokenCookie, accessToken, accessTokenExpiresIn, true))
+	http.SetCookie(res, cfg.newSessionCookie(refreshTokenCookie, refreshToken, refreshExpiresIn, true))
+	http.SetCookie(res, cfg.newSessionCookie(csrfTokenCookie, csrfToken, refreshExpiresIn, false))
+	return nil
+}
+
+func (cfg *apiConfig) clearSessionCookies(res http.ResponseWriter) {
+	for _, name := range []string{accessTokenCookie, refreshTokenCookie, csrfTokenCookie} {
+		cookie := cfg.newSessionCookie(name, "", 0, name != csrfTokenCookie)
+		cookie.MaxAge = -1
+		http.SetCookie(res, cookie)
+	}
+}
+
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// getSessionToken returns the token sent in the Authorization header or,
+// failing that, in the given cookie. Cookie authenticated state-changing
+// requests must pass the double-submit CSRF check.
+func getSessionToken(req *http.Request, cookieName string) (token string, fromCookie bool, err error) {
+	token, err = auth.GetBearerToken(req.Header)
+	if err == nil {
+		return token, false, nil
+	}
+
+	cookie, cookieErr := req.Cookie(cookieName)
+	if cookieErr != nil || cookie.Value == "" {
+		return "", false, err
+	}
+
+	if !isSafeMethod(req.Method) {
+		csrfToken := ""
+		if csrfCookie, err := req.Cookie(csrfTokenCookie); err == nil {
+			csrfToken = csrfCookie.Value
+		}
+		if err := auth.ValidateCSRFToken(req.Header, csrfToken); err != nil {
+			return "", true, err
+		}
+	}
+
+	return cookie.Value, true, nil
+}
@@ -0,0 +1,66 @@
+-- name: BlockUser :exec
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: IsBlocked :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE blocker_id = $1 AND blocked_id = $2
+);
+
+-- name: GetBlockedUsers :many
+SELECT * FROM blocks
+WHERE blocker_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = @user_id AND followee_id = @other_user_id)
+    OR (follower_id = @other_user_id AND followee_id = @user_id);
+
+-- name: MuteUser :exec
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutedUsers :many
+SELECT * FROM mutes
+WHERE muter_id = $1
+ORDER BY created_at DESC;
+
+-- name: IsBlockedBetween :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = @user_id AND blocked_id = @other_user_id)
+        OR (blocker_id = @other_user_id AND blocked_id = @user_id)
+);
+
+-- name: GetNotificationRecipients :many
+SELECT recipient_id::UUID FROM unnest(@recipient_ids::UUID[]) AS recipient_id
+WHERE NOT author_hidden_for(@author_id, recipient_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE blocker_id = @author_id AND blocked_id = recipient_id
+    );
+
+-- name: IsBlockedWithAny :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE (blocker_id = @user_id AND blocked_id = ANY(@other_user_ids::UUID[]))
+        OR (blocked_id = @user_id AND blocker_id = ANY(@other_user_ids::UUID[]))
+);
+
+-- name: IsBlockedWithin :one
+SELECT EXISTS (
+    SELECT 1 FROM blocks
+    WHERE blocker_id = ANY(@user_ids::UUID[]) AND blocked_id = ANY(@user_ids::UUID[])
+);
@@ -0,0 +1,53 @@
+-- name: CreateBookmark :exec
+INSERT INTO bookmarks (user_id, chirp_id, folder_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (user_id, chirp_id) DO UPDATE
+SET folder_id = EXCLUDED.folder_id;
+
+-- name: DeleteBookmark :exec
+DELETE FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetBookmarks :many
+SELECT sqlc.embed(chirps), bookmarks.folder_id, bookmarks.created_at AS bookmarked_at FROM bookmarks
+INNER JOIN chirps ON chirps.id = bookmarks.chirp_id
+INNER JOIN users ON users.id = chirps.user_id
+WHERE bookmarks.user_id = @user_id
+    AND users.deleted_at IS NULL
+    AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, @user_id)
+    AND (sqlc.narg('folder_id')::UUID IS NULL OR bookmarks.folder_id = sqlc.narg('folder_id'))
+    AND (
+        sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.narg('cursor_time')::TIMESTAMP, sqlc.narg('cursor_id')::UUID)
+    )
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT @page_limit;
+
+-- name: GetBookmarkedChirpIDs :many
+SELECT chirp_id FROM bookmarks
+WHERE user_id = @user_id AND chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: CreateBookmarkFolder :one
+INSERT INTO bookmark_folders (id, created_at, updated_at, user_id, name)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+ON CONFLICT (user_id, name) DO NOTHING
+RETURNING *;
+
+-- name: GetBookmarkFolder :one
+SELECT * FROM bookmark_folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetBookmarkFolders :many
+SELECT * FROM bookmark_folders
+WHERE user_id = $1
+ORDER BY name ASC;
+
+-- name: DeleteBookmarkFolder :execrows
+DELETE FROM bookmark_folders
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetUserBookmarks :many
+SELECT * FROM bookmarks
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -1,19 +1,32 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, visibility, content_warning, sensitive)
 VALUES (
-    gen_random_uuid(), NOW(), NOW(), $1, $2
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5
 )
 RETURNING *;
 
 -- name: GetChirps :many
-SELECT * FROM chirps
+SELECT chirps.* FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.narg('viewer_id'))
+    AND NOT author_hidden_for(chirps.user_id, sqlc.narg('viewer_id'))
 ORDER BY
     CASE WHEN @order_by::TEXT = 'ASC' THEN chirps.created_at END ASC,
     CASE WHEN @order_by::TEXT = 'DESC' THEN chirps.created_at END DESC;
 
 -- name: GetChirp :one
-SELECT * FROM chirps
-WHERE id = $1;
+SELECT chirps.* FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = @id AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.narg('viewer_id'));
+
+-- name: GetChirpForUpdate :one
+SELECT chirps.* FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE chirps.id = @id AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.narg('viewer_id'))
+FOR UPDATE OF chirps;
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps
@@ -26,8 +39,70 @@ WHERE id = $1
 RETURNING *;
 
 -- name: GetChirpsByAuthor :many
-SELECT * FROM chirps
-WHERE user_id = $1
+SELECT chirps.* FROM chirps
+INNER JOIN users ON users.id = chirps.user_id
+WHERE chirps.user_id = @user_id AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.narg('viewer_id'))
 ORDER BY
     CASE WHEN @order_by::TEXT = 'ASC' THEN chirps.created_at END ASC,
     CASE WHEN @order_by::TEXT = 'DESC' THEN chirps.created_at END DESC;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2,
+    edited_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CreateScheduledChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, status, publish_at, visibility, content_warning, sensitive)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, 'scheduled', $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetScheduledChirp :one
+SELECT * FROM chirps
+WHERE id = $1 AND user_id = $2 AND status = 'scheduled';
+
+-- name: GetScheduledChirpsByAuthor :many
+SELECT * FROM chirps
+WHERE user_id = $1 AND status = 'scheduled'
+ORDER BY publish_at ASC;
+
+-- name: UpdateScheduledChirp :one
+UPDATE chirps
+SET body = $3,
+    publish_at = $4,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $2 AND status = 'scheduled'
+RETURNING *;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM chirps
+WHERE id = $1 AND user_id = $2 AND status = 'scheduled';
+
+-- name: PublishDueChirps :many
+UPDATE chirps
+SET status = 'published',
+    created_at = NOW(),
+    updated_at = NOW()
+WHERE status = 'scheduled' AND publish_at <= NOW()
+RETURNING *;
+
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING;
+
+-- name: GetChirpMentions :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: SetChirpSensitive :one
+UPDATE chirps
+SET sensitive = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,126 @@
+-- name: CreateDirectConversation :one
+INSERT INTO conversations (id, created_at, updated_at, direct_key)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1)
+ON CONFLICT (direct_key) DO NOTHING
+RETURNING *;
+
+-- name: GetDirectConversation :one
+SELECT * FROM conversations
+WHERE direct_key = $1;
+
+-- name: AddConversationMember :exec
+INSERT INTO conversation_members (conversation_id, user_id, joined_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: GetConversationForMember :one
+SELECT conversations.* FROM conversations
+INNER JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversations.id = @id AND conversation_members.user_id = @user_id;
+
+-- name: GetConversationsForUser :many
+SELECT conversations.* FROM conversations
+INNER JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = $1
+ORDER BY conversations.updated_at DESC;
+
+-- name: CreateGroupConversation :one
+INSERT INTO conversations (id, created_at, updated_at, kind, name, owner_id)
+VALUES (gen_random_uuid(), NOW(), NOW(), 'group', $1, $2)
+RETURNING *;
+
+-- name: GetConversationForUpdate :one
+SELECT * FROM conversations
+WHERE id = $1
+FOR UPDATE;
+
+-- name: GetOwnedGroupsForUpdate :many
+SELECT * FROM conversations
+WHERE owner_id = $1 AND kind = 'group'
+FOR UPDATE;
+
+-- name: RenameConversation :one
+UPDATE conversations
+SET name = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetConversationOwner :exec
+UPDATE conversations
+SET owner_id = $2
+WHERE id = $1;
+
+-- name: DeleteConversation :exec
+DELETE FROM conversations
+WHERE id = $1;
+
+-- name: RemoveConversationMember :execrows
+DELETE FROM conversation_members
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: GetConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_id = ANY(@conversation_ids::UUID[])
+ORDER BY joined_at ASC;
+
+-- name: GetLastMessages :many
+SELECT DISTINCT ON (conversation_id) * FROM messages
+WHERE conversation_id = ANY(@conversation_ids::UUID[])
+ORDER BY conversation_id, created_at DESC, id DESC;
+
+-- name: GetUnreadCounts :many
+SELECT messages.conversation_id, COUNT(*) AS unread FROM messages
+INNER JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id
+WHERE conversation_members.user_id = @user_id
+    AND (messages.sender_id IS NULL OR messages.sender_id <> @user_id)
+    AND (conversation_members.last_read_at IS NULL OR messages.created_at > conversation_members.last_read_at)
+GROUP BY messages.conversation_id;
+
+-- name: CreateMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+RETURNING *;
+
+-- name: CreateSystemMessage :one
+INSERT INTO messages (id, created_at, conversation_id, sender_id, body, event, user_id)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetMessage :one
+SELECT * FROM messages
+WHERE id = $1 AND conversation_id = $2;
+
+-- name: TouchConversation :exec
+UPDATE conversations
+SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetMessages :many
+SELECT * FROM messages
+WHERE conversation_id = @conversation_id
+    AND (
+        sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (created_at, id) < (sqlc.narg('cursor_time')::TIMESTAMP, sqlc.narg('cursor_id')::UUID)
+    )
+ORDER BY created_at DESC, id DESC
+LIMIT @page_limit;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_members
+SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2;
+
+-- name: MoveReadCursor :exec
+UPDATE conversation_members
+SET last_read_at = GREATEST(last_read_at, @read_at::TIMESTAMP)
+WHERE conversation_id = @conversation_id AND user_id = @user_id;
+
+-- name: GetMessagesBySender :many
+SELECT * FROM messages
+WHERE sender_id = $1 AND event IS NULL
+ORDER BY created_at ASC;
+
+-- name: GetConversationPartners :many
+SELECT DISTINCT other.user_id FROM conversation_members own
+INNER JOIN conversation_members other ON other.conversation_id = own.conversation_id
+WHERE own.user_id = $1 AND other.user_id <> $1;
@@ -0,0 +1,57 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (id, created_at, updated_at, user_id, status)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, 'pending'
+)
+RETURNING *;
+
+-- name: GetDataExport :one
+SELECT * FROM data_exports
+WHERE id = $1;
+
+-- name: GetActiveDataExport :one
+SELECT * FROM data_exports
+WHERE user_id = $1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ClaimDataExport :one
+UPDATE data_exports
+SET status = 'processing',
+    updated_at = NOW()
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+        OR (status = 'processing' AND updated_at < @stale_before::TIMESTAMP)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'ready',
+    expires_at = $2,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: CreateDataExportArchive :exec
+INSERT INTO data_export_archives (export_id, content)
+VALUES ($1, $2)
+ON CONFLICT (export_id) DO NOTHING;
+
+-- name: GetDataExportArchive :one
+SELECT content FROM data_export_archives
+WHERE export_id = $1;
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed',
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteExpiredDataExports :exec
+DELETE FROM data_exports
+WHERE expires_at < NOW()
+    OR (status = 'failed' AND updated_at < @failed_before::TIMESTAMP);
@@ -0,0 +1,23 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowing :many
+SELECT * FROM follows
+WHERE follower_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetFollowedAmong :many
+SELECT followee_id FROM follows
+WHERE follower_id = @follower_id AND followee_id = ANY(@user_ids::UUID[]);
+
+-- name: IsFollowing :one
+SELECT EXISTS (
+    SELECT 1 FROM follows
+    WHERE follower_id = $1 AND followee_id = $2
+);
@@ -0,0 +1,50 @@
+-- name: CreateLinkPreview :exec
+INSERT INTO link_previews (url, created_at, updated_at, status)
+VALUES ($1, NOW(), NOW(), 'pending')
+ON CONFLICT (url) DO NOTHING;
+
+-- name: DeleteChirpLinks :exec
+DELETE FROM chirp_links
+WHERE chirp_id = $1;
+
+-- name: CreateChirpLink :exec
+INSERT INTO chirp_links (chirp_id, url, position)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: ClaimLinkPreview :one
+UPDATE link_previews
+SET status = 'processing',
+    updated_at = NOW()
+WHERE url = (
+    SELECT url FROM link_previews
+    WHERE status = 'pending'
+        OR (status = 'processing' AND updated_at < @stale_before::TIMESTAMP)
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteLinkPreview :exec
+UPDATE link_previews
+SET status = 'ready',
+    title = $2,
+    description = $3,
+    image_url = $4,
+    site_name = $5,
+    canonical_url = $6,
+    updated_at = NOW()
+WHERE url = $1;
+
+-- name: FailLinkPreview :exec
+UPDATE link_previews
+SET status = 'failed',
+    updated_at = NOW()
+WHERE url = $1;
+
+-- name: GetChirpsLinkPreviews :many
+SELECT chirp_links.chirp_id, sqlc.embed(link_previews) FROM chirp_links
+INNER JOIN link_previews ON link_previews.url = chirp_links.url
+WHERE chirp_links.chirp_id = ANY(@chirp_ids::UUID[]) AND link_previews.status = 'ready'
+ORDER BY chirp_links.chirp_id, chirp_links.position;
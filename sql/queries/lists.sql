@@ -0,0 +1,97 @@
+-- name: CreateList :one
+INSERT INTO lists (id, created_at, updated_at, owner_id, name, description, visibility)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4)
+ON CONFLICT (owner_id, name) DO NOTHING
+RETURNING *;
+
+-- name: CountLists :one
+SELECT COUNT(*) FROM lists
+WHERE owner_id = $1;
+
+-- name: GetListsByOwner :many
+SELECT * FROM lists
+WHERE owner_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetVisibleList :one
+SELECT lists.* FROM lists
+INNER JOIN users ON users.id = lists.owner_id
+WHERE lists.id = @id AND users.deleted_at IS NULL
+    AND (lists.visibility = 'public' OR lists.owner_id = sqlc.narg('viewer_id'));
+
+-- name: GetListForUpdate :one
+SELECT * FROM lists
+WHERE id = $1
+FOR UPDATE;
+
+-- name: UpdateList :one
+UPDATE lists
+SET name = $3, description = $4, visibility = $5, updated_at = NOW()
+WHERE lists.id = $1 AND lists.owner_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM lists other
+        WHERE other.owner_id = $2 AND other.name = $3 AND other.id <> $1
+    )
+RETURNING *;
+
+-- name: DeleteList :execrows
+DELETE FROM lists
+WHERE id = $1 AND owner_id = $2;
+
+-- name: AddListMember :execrows
+INSERT INTO list_members (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: RemoveListMember :execrows
+DELETE FROM list_members
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: CountListMembers :one
+SELECT COUNT(*) FROM list_members
+WHERE list_id = $1;
+
+-- name: GetListMembers :many
+SELECT list_members.* FROM list_members
+INNER JOIN users ON users.id = list_members.user_id
+WHERE list_members.list_id = $1 AND users.deleted_at IS NULL
+ORDER BY list_members.created_at ASC;
+
+-- name: GetListChirps :many
+SELECT chirps.* FROM chirps
+INNER JOIN list_members ON list_members.user_id = chirps.user_id
+INNER JOIN users ON users.id = chirps.user_id
+WHERE list_members.list_id = @list_id AND users.deleted_at IS NULL AND chirps.status = 'published'
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.narg('viewer_id'))
+    AND NOT author_hidden_for(chirps.user_id, sqlc.narg('viewer_id'))
+    AND (
+        sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_time')::TIMESTAMP, sqlc.narg('cursor_id')::UUID)
+    )
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT @page_limit;
+
+-- name: SubscribeToList :exec
+INSERT INTO list_subscriptions (list_id, user_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnsubscribeFromList :exec
+DELETE FROM list_subscriptions
+WHERE list_id = $1 AND user_id = $2;
+
+-- name: GetSubscribedLists :many
+SELECT lists.* FROM lists
+INNER JOIN list_subscriptions ON list_subscriptions.list_id = lists.id
+INNER JOIN users ON users.id = lists.owner_id
+WHERE list_subscriptions.user_id = $1 AND lists.visibility = 'public' AND users.deleted_at IS NULL
+ORDER BY list_subscriptions.created_at ASC;
+
+-- name: CountListSubscribers :many
+SELECT list_id, COUNT(*) AS subscribers FROM list_subscriptions
+WHERE list_id = ANY(@list_ids::UUID[])
+GROUP BY list_id;
+
+-- name: DeleteListSubscriptions :exec
+DELETE FROM list_subscriptions
+WHERE list_id = $1;
@@ -0,0 +1,30 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE key = $1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (key, created_at, updated_at, failed_attempts, locked_until)
+VALUES ($1, NOW(), NOW(), 1, NULL)
+ON CONFLICT (key) DO UPDATE
+SET failed_attempts = CASE
+        WHEN login_throttles.updated_at < @reset_before::TIMESTAMP THEN 1
+        ELSE login_throttles.failed_attempts + 1
+    END,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: LockLoginThrottle :exec
+UPDATE login_throttles
+SET locked_until = $2,
+    updated_at = NOW()
+WHERE key = $1;
+
+-- name: ClearLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE key = $1;
+
+-- name: GetLockedLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE key LIKE @key_prefix::TEXT || '%'
+    AND locked_until > NOW()
+ORDER BY locked_until DESC;
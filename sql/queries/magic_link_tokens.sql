@@ -0,0 +1,12 @@
+-- name: CreateMagicLinkToken :exec
+INSERT INTO magic_link_tokens (token_hash, created_at, updated_at, user_id, expires_at, used_at)
+VALUES ($1, NOW(), NOW(), $2, $3, NULL);
+
+-- name: ConsumeMagicLinkToken :one
+UPDATE magic_link_tokens
+SET used_at = NOW(),
+    updated_at = NOW()
+WHERE token_hash = $1
+    AND used_at IS NULL
+    AND expires_at > NOW()
+RETURNING *;
@@ -0,0 +1,36 @@
+-- name: CreateMuteFilter :one
+INSERT INTO mute_filters (id, created_at, updated_at, user_id, phrase, whole_word, only_non_followed, action, expires_at)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4, $5, $6)
+ON CONFLICT (user_id, phrase) DO NOTHING
+RETURNING *;
+
+-- name: GetMuteFilters :many
+SELECT * FROM mute_filters
+WHERE user_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetActiveMuteFilters :many
+SELECT * FROM mute_filters
+WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW());
+
+-- name: CountMuteFilters :one
+SELECT COUNT(*) FROM mute_filters
+WHERE user_id = $1;
+
+-- name: UpdateMuteFilter :one
+UPDATE mute_filters
+SET phrase = $3, whole_word = $4, only_non_followed = $5, action = $6, expires_at = $7, updated_at = NOW()
+WHERE mute_filters.id = $1 AND mute_filters.user_id = $2
+    AND NOT EXISTS (
+        SELECT 1 FROM mute_filters other
+        WHERE other.user_id = $2 AND other.phrase = $3 AND other.id <> $1
+    )
+RETURNING *;
+
+-- name: GetMuteFilter :one
+SELECT * FROM mute_filters
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteMuteFilter :execrows
+DELETE FROM mute_filters
+WHERE id = $1 AND user_id = $2;
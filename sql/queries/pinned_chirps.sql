@@ -0,0 +1,26 @@
+-- name: PinChirp :exec
+INSERT INTO pinned_chirps (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnpinChirp :exec
+DELETE FROM pinned_chirps
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: CountPinnedChirps :one
+SELECT COUNT(*) FROM pinned_chirps
+WHERE user_id = $1;
+
+-- name: IsChirpPinned :one
+SELECT EXISTS (
+    SELECT 1 FROM pinned_chirps
+    WHERE user_id = $1 AND chirp_id = $2
+);
+
+-- name: GetPinnedChirps :many
+SELECT chirps.* FROM chirps
+INNER JOIN pinned_chirps ON pinned_chirps.chirp_id = chirps.id
+INNER JOIN users ON users.id = chirps.user_id
+WHERE pinned_chirps.user_id = @user_id AND users.deleted_at IS NULL
+    AND chirp_visible_to(chirps.id, chirps.user_id, chirps.visibility, sqlc.narg('viewer_id'))
+ORDER BY pinned_chirps.created_at DESC;
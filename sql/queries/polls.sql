@@ -0,0 +1,40 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, created_at, closes_at)
+VALUES ($1, NOW(), $2);
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (id, chirp_id, position, text)
+VALUES (gen_random_uuid(), $1, $2, $3);
+
+-- name: GetPoll :one
+SELECT * FROM polls
+WHERE chirp_id = $1;
+
+-- name: GetPolls :many
+SELECT * FROM polls
+WHERE chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: GetPollOptionsWithVotes :many
+SELECT poll_options.*, COUNT(poll_votes.user_id) AS votes FROM poll_options
+LEFT JOIN poll_votes ON poll_votes.option_id = poll_options.id
+WHERE poll_options.chirp_id = ANY(@chirp_ids::UUID[])
+GROUP BY poll_options.id
+ORDER BY poll_options.chirp_id, poll_options.position;
+
+-- name: GetPollOption :one
+SELECT * FROM poll_options
+WHERE id = $1 AND chirp_id = $2;
+
+-- name: CreatePollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, option_id, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: GetUserPollVotes :many
+SELECT * FROM poll_votes
+WHERE user_id = @user_id AND chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: GetAllUserPollVotes :many
+SELECT * FROM poll_votes
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,19 @@
+-- name: HitRateLimit :one
+-- Counts a hit in the current fixed window of the key, a new window starts
+-- with the first hit after the previous one is over.
+INSERT INTO rate_limits (key, window_start, hits)
+VALUES ($1, NOW(), 1)
+ON CONFLICT (key) DO UPDATE
+SET hits = CASE
+        WHEN rate_limits.window_start <= @window_over_before::TIMESTAMP THEN 1
+        ELSE rate_limits.hits + 1
+    END,
+    window_start = CASE
+        WHEN rate_limits.window_start <= @window_over_before::TIMESTAMP THEN NOW()
+        ELSE rate_limits.window_start
+    END
+RETURNING *;
+
+-- name: ClearRateLimit :exec
+DELETE FROM rate_limits
+WHERE key = $1;
@@ -0,0 +1,43 @@
+-- name: CreateReaction :one
+INSERT INTO reactions (id, created_at, chirp_id, user_id, emoji)
+VALUES (gen_random_uuid(), NOW(), $1, $2, $3)
+ON CONFLICT (chirp_id, user_id, emoji) DO NOTHING
+RETURNING *;
+
+-- name: GetReaction :one
+SELECT * FROM reactions
+WHERE id = $1 AND chirp_id = $2;
+
+-- name: DeleteReaction :execrows
+DELETE FROM reactions
+WHERE id = $1 AND chirp_id = $2 AND user_id = $3;
+
+-- name: GetReactionCounts :many
+SELECT reactions.chirp_id, reactions.emoji, COUNT(*) AS reactions FROM reactions
+INNER JOIN users ON users.id = reactions.user_id
+WHERE reactions.chirp_id = ANY(@chirp_ids::UUID[])
+    AND reactions.emoji = ANY(@emojis::TEXT[])
+    AND users.deleted_at IS NULL
+GROUP BY reactions.chirp_id, reactions.emoji;
+
+-- name: GetViewerReactions :many
+SELECT * FROM reactions
+WHERE user_id = @user_id AND chirp_id = ANY(@chirp_ids::UUID[]);
+
+-- name: GetReactions :many
+SELECT reactions.* FROM reactions
+INNER JOIN users ON users.id = reactions.user_id
+WHERE reactions.chirp_id = @chirp_id
+    AND reactions.emoji = ANY(@emojis::TEXT[])
+    AND users.deleted_at IS NULL
+    AND (
+        sqlc.narg('cursor_time')::TIMESTAMP IS NULL
+        OR (reactions.created_at, reactions.id) < (sqlc.narg('cursor_time')::TIMESTAMP, sqlc.narg('cursor_id')::UUID)
+    )
+ORDER BY reactions.created_at DESC, reactions.id DESC
+LIMIT @page_limit;
+
+-- name: GetUserReactions :many
+SELECT * FROM reactions
+WHERE user_id = $1
+ORDER BY created_at ASC;
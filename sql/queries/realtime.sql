@@ -0,0 +1,2 @@
+-- name: NotifyRealtime :exec
+SELECT pg_notify('realtime', @payload::TEXT);
@@ -1,6 +1,6 @@
 -- name: CreateRefreshToken :exec
-INSERT INTO refresh_tokens (token, created_at, updated_at, user_id, expires_at, revoked_at)
-VALUES ($1, NOW(), NOW(), $2, $3, NULL);
+INSERT INTO refresh_tokens (token, session_id, created_at, updated_at, user_id, expires_at, revoked_at)
+VALUES ($1, $2, NOW(), NOW(), $3, $4, NULL);
 
 -- name: GetUserFromRefreshToken :one
 SELECT u.* FROM users u
@@ -11,8 +11,25 @@ WHERE token = $1;
 SELECT * FROM refresh_tokens
 WHERE token = $1;
 
+-- name: GetActiveSession :one
+SELECT refresh_tokens.* FROM refresh_tokens
+INNER JOIN users ON users.id = refresh_tokens.user_id
+WHERE refresh_tokens.session_id = $1 AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW() AND users.deleted_at IS NULL;
+
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens
 SET revoked_at = NOW(),
     updated_at = NOW()
 WHERE token = $1;
+
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(),
+    updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: GetUserRefreshTokens :many
+SELECT * FROM refresh_tokens
+WHERE user_id = $1
+ORDER BY created_at DESC;
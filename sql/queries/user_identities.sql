@@ -0,0 +1,22 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (id, created_at, updated_at, user_id, issuer, subject, email)
+VALUES (
+    gen_random_uuid(), NOW(), NOW(), $1, $2, $3, $4
+)
+RETURNING *;
+
+-- name: GetUserByIdentity :one
+SELECT u.* FROM users u
+INNER JOIN user_identities ui ON u.id = ui.user_id
+WHERE ui.issuer = $1 AND ui.subject = $2;
+
+-- name: UpdateUserIdentityEmail :exec
+UPDATE user_identities
+SET email = $3,
+    updated_at = NOW()
+WHERE issuer = $1 AND subject = $2;
+
+-- name: GetUserIdentities :many
+SELECT * FROM user_identities
+WHERE user_id = $1
+ORDER BY created_at;
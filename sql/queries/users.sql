@@ -20,3 +20,61 @@ SET email = $2,
 WHERE
     id = $1
 RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2
+WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SoftDeleteUser :one
+UPDATE users
+SET deleted_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RestoreUser :exec
+UPDATE users
+SET deleted_at = NULL,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: GetUsersToPurge :many
+SELECT * FROM users
+WHERE deleted_at IS NOT NULL
+    AND deleted_at < @deleted_before::TIMESTAMP
+LIMIT $1;
+
+-- name: PurgeUser :execrows
+DELETE FROM users
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: CreateAccountDeletion :exec
+INSERT INTO account_deletions (id, created_at, requested_at, was_chirpy_red)
+VALUES (
+    gen_random_uuid(), NOW(), $1, $2
+);
+
+-- name: LockUser :one
+SELECT * FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: SetUserModerator :one
+UPDATE users
+SET is_moderator = $2,
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserPreferences :one
+UPDATE users
+SET sensitive_content = COALESCE(sqlc.narg('sensitive_content'), sensitive_content),
+    dm_privacy = COALESCE(sqlc.narg('dm_privacy'), dm_privacy),
+    updated_at = NOW()
+WHERE id = @id
+RETURNING *;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE TABLE account_deletions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    requested_at TIMESTAMP NOT NULL,
+    was_chirpy_red BOOL NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE account_deletions;
+
+ALTER TABLE users
+    DROP COLUMN deleted_at;
+-- +goose StatementEnd
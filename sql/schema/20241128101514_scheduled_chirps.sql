@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+        CHECK (status IN ('published', 'scheduled')),
+    ADD COLUMN publish_at TIMESTAMP;
+
+CREATE INDEX idx_chirps_scheduled ON chirps (publish_at)
+    WHERE status = 'scheduled';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX idx_chirps_scheduled;
+
+ALTER TABLE chirps
+    DROP COLUMN publish_at,
+    DROP COLUMN status;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE bookmark_folders (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    UNIQUE (user_id, name),
+    CONSTRAINT fk_bookmark_folder_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE bookmarks (
+    user_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    folder_id UUID,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, chirp_id),
+    CONSTRAINT fk_bookmark_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_chirp FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_bookmark_folder FOREIGN KEY (folder_id)
+        REFERENCES bookmark_folders(id) ON DELETE SET NULL
+);
+
+CREATE INDEX bookmarks_user_created_at_idx ON bookmarks (user_id, created_at DESC, chirp_id DESC);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE bookmarks;
+DROP TABLE bookmark_folders;
+-- +goose StatementEnd
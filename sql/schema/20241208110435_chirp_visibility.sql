@@ -0,0 +1,62 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE follows (
+    follower_id UUID NOT NULL,
+    followee_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (follower_id, followee_id),
+    CHECK (follower_id <> followee_id),
+    CONSTRAINT fk_follow_follower FOREIGN KEY (follower_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_follow_followee FOREIGN KEY (followee_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX follows_followee_id_idx ON follows (followee_id);
+
+CREATE TABLE chirp_mentions (
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    PRIMARY KEY (chirp_id, user_id),
+    CONSTRAINT fk_mention_chirp FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_mention_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX chirp_mentions_user_id_idx ON chirp_mentions (user_id);
+
+ALTER TABLE chirps
+ADD COLUMN visibility TEXT NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'followers', 'mentioned'));
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Single definition of who can read a chirp, used by every read query.
+-- The author and mentioned users always can, followers-only chirps are
+-- also visible to the author's followers. viewer_id is NULL for anonymous
+-- callers.
+CREATE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT visibility = 'public'
+        OR author_id = viewer_id
+        OR (visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer_id AND follows.followee_id = author_id
+        ))
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp_visible_to.chirp_id AND chirp_mentions.user_id = viewer_id
+        );
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION chirp_visible_to;
+ALTER TABLE chirps DROP COLUMN visibility;
+DROP TABLE chirp_mentions;
+DROP TABLE follows;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE chirps
+ADD COLUMN content_warning TEXT,
+ADD COLUMN sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE users
+ADD COLUMN is_moderator BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN sensitive_content TEXT NOT NULL DEFAULT 'hide'
+    CHECK (sensitive_content IN ('hide', 'expand'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+DROP COLUMN sensitive_content,
+DROP COLUMN is_moderator;
+
+ALTER TABLE chirps
+DROP COLUMN sensitive,
+DROP COLUMN content_warning;
+-- +goose StatementEnd
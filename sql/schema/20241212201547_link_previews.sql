@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE link_previews (
+    url TEXT PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    title TEXT NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    image_url TEXT NOT NULL DEFAULT '',
+    site_name TEXT NOT NULL DEFAULT '',
+    canonical_url TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX link_previews_pending_idx ON link_previews (created_at)
+WHERE status IN ('pending', 'processing');
+
+CREATE TABLE chirp_links (
+    chirp_id UUID NOT NULL,
+    url TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (chirp_id, url),
+    CONSTRAINT fk_chirp_link_chirp FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_chirp_link_preview FOREIGN KEY (url)
+        REFERENCES link_previews(url) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE chirp_links;
+DROP TABLE link_previews;
+-- +goose StatementEnd
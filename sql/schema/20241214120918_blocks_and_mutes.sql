@@ -0,0 +1,95 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE blocks (
+    blocker_id UUID NOT NULL,
+    blocked_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id),
+    CONSTRAINT fk_block_blocker FOREIGN KEY (blocker_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_block_blocked FOREIGN KEY (blocked_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX blocks_blocked_id_idx ON blocks (blocked_id);
+
+CREATE TABLE mutes (
+    muter_id UUID NOT NULL,
+    muted_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (muter_id, muted_id),
+    CHECK (muter_id <> muted_id),
+    CONSTRAINT fk_mute_muter FOREIGN KEY (muter_id)
+        REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_mute_muted FOREIGN KEY (muted_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Blocked users can't read any chirp of the users that blocked them
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT NOT EXISTS (
+            SELECT 1 FROM blocks
+            WHERE blocks.blocker_id = author_id AND blocks.blocked_id = viewer_id
+        )
+        AND (
+            visibility = 'public'
+            OR author_id = viewer_id
+            OR (visibility = 'followers' AND EXISTS (
+                SELECT 1 FROM follows
+                WHERE follows.follower_id = viewer_id AND follows.followee_id = author_id
+            ))
+            OR EXISTS (
+                SELECT 1 FROM chirp_mentions
+                WHERE chirp_mentions.chirp_id = chirp_visible_to.chirp_id AND chirp_mentions.user_id = viewer_id
+            )
+        );
+$$;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+-- Chirps of users the viewer muted or blocked are left out of listings,
+-- they can still be opened directly.
+CREATE FUNCTION author_hidden_for(author_id UUID, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT EXISTS (
+            SELECT 1 FROM mutes
+            WHERE mutes.muter_id = viewer_id AND mutes.muted_id = author_id
+        )
+        OR EXISTS (
+            SELECT 1 FROM blocks
+            WHERE blocks.blocker_id = viewer_id AND blocks.blocked_id = author_id
+        );
+$$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP FUNCTION author_hidden_for;
+
+CREATE OR REPLACE FUNCTION chirp_visible_to(chirp_id UUID, author_id UUID, visibility TEXT, viewer_id UUID)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT visibility = 'public'
+        OR author_id = viewer_id
+        OR (visibility = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer_id AND follows.followee_id = author_id
+        ))
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp_visible_to.chirp_id AND chirp_mentions.user_id = viewer_id
+        );
+$$;
+
+DROP TABLE mutes;
+DROP TABLE blocks;
+-- +goose StatementEnd
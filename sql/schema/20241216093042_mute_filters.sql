@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE mute_filters (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    phrase TEXT NOT NULL,
+    whole_word BOOLEAN NOT NULL DEFAULT TRUE,
+    only_non_followed BOOLEAN NOT NULL DEFAULT FALSE,
+    action TEXT NOT NULL DEFAULT 'hide' CHECK (action IN ('hide', 'warn')),
+    expires_at TIMESTAMP,
+    UNIQUE (user_id, phrase),
+    CONSTRAINT fk_mute_filter_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mute_filters;
+-- +goose StatementEnd
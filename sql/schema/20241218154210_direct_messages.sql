@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE conversations (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    -- Both members' IDs in order, so each pair of users has a single
+    -- direct conversation
+    direct_key TEXT UNIQUE
+);
+
+CREATE TABLE conversation_members (
+    conversation_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    joined_at TIMESTAMP NOT NULL,
+    last_read_at TIMESTAMP,
+    PRIMARY KEY (conversation_id, user_id),
+    CONSTRAINT fk_conversation_member_conversation FOREIGN KEY (conversation_id)
+        REFERENCES conversations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_conversation_member_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX conversation_members_user_id_idx ON conversation_members (user_id);
+
+CREATE TABLE messages (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    conversation_id UUID NOT NULL,
+    sender_id UUID,
+    body TEXT NOT NULL,
+    CONSTRAINT fk_message_conversation FOREIGN KEY (conversation_id)
+        REFERENCES conversations(id) ON DELETE CASCADE,
+    CONSTRAINT fk_message_sender FOREIGN KEY (sender_id)
+        REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX messages_conversation_created_at_idx ON messages (conversation_id, created_at DESC, id DESC);
+
+ALTER TABLE users
+ADD COLUMN dm_privacy TEXT NOT NULL DEFAULT 'everyone'
+    CHECK (dm_privacy IN ('everyone', 'following', 'nobody'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+DROP COLUMN dm_privacy;
+
+DROP TABLE messages;
+DROP TABLE conversation_members;
+DROP TABLE conversations;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE conversations
+ADD COLUMN kind TEXT NOT NULL DEFAULT 'direct' CHECK (kind IN ('direct', 'group')),
+ADD COLUMN name TEXT,
+ADD COLUMN owner_id UUID,
+ADD CONSTRAINT fk_conversation_owner FOREIGN KEY (owner_id)
+    REFERENCES users(id) ON DELETE SET NULL;
+
+-- System messages record membership changes, sender_id is who made the
+-- change and user_id the member it affects
+ALTER TABLE messages
+ADD COLUMN event TEXT CHECK (event IN ('created', 'renamed', 'member_added', 'member_removed', 'member_left')),
+ADD COLUMN user_id UUID,
+ADD CONSTRAINT fk_message_user FOREIGN KEY (user_id)
+    REFERENCES users(id) ON DELETE SET NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE messages
+DROP COLUMN user_id,
+DROP COLUMN event;
+
+ALTER TABLE conversations
+DROP COLUMN owner_id,
+DROP COLUMN name,
+DROP COLUMN kind;
+-- +goose StatementEnd
@@ -0,0 +1,48 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE lists (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    owner_id UUID NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    visibility TEXT NOT NULL DEFAULT 'private' CHECK (visibility IN ('public', 'private')),
+    UNIQUE (owner_id, name),
+    CONSTRAINT fk_list_owner FOREIGN KEY (owner_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE list_members (
+    list_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (list_id, user_id),
+    CONSTRAINT fk_list_member_list FOREIGN KEY (list_id)
+        REFERENCES lists(id) ON DELETE CASCADE,
+    CONSTRAINT fk_list_member_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX list_members_user_id_idx ON list_members (user_id);
+
+CREATE TABLE list_subscriptions (
+    list_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (list_id, user_id),
+    CONSTRAINT fk_list_subscription_list FOREIGN KEY (list_id)
+        REFERENCES lists(id) ON DELETE CASCADE,
+    CONSTRAINT fk_list_subscription_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX list_subscriptions_user_id_idx ON list_subscriptions (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE list_subscriptions;
+DROP TABLE list_members;
+DROP TABLE lists;
+-- +goose StatementEnd
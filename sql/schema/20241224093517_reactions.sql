@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE reactions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    emoji TEXT NOT NULL,
+    UNIQUE (chirp_id, user_id, emoji),
+    CONSTRAINT fk_reaction_chirp FOREIGN KEY (chirp_id)
+        REFERENCES chirps(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reaction_user FOREIGN KEY (user_id)
+        REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX reactions_chirp_id_emoji_idx ON reactions (chirp_id, emoji, created_at DESC, id DESC);
+CREATE INDEX reactions_user_id_idx ON reactions (user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE reactions;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Archives were written to the disk of the replica building them, those
+-- exports can't be downloaded from every replica and are dropped
+DELETE FROM data_exports WHERE status = 'ready';
+ALTER TABLE data_exports DROP COLUMN file_path;
+
+CREATE TABLE data_export_archives (
+    export_id UUID PRIMARY KEY,
+    content BYTEA NOT NULL,
+    CONSTRAINT fk_archive_export FOREIGN KEY (export_id)
+        REFERENCES data_exports(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE data_export_archives;
+DELETE FROM data_exports WHERE status = 'ready';
+ALTER TABLE data_exports ADD COLUMN file_path TEXT;
+-- +goose StatementEnd
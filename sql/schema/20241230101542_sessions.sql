@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Access tokens carry the session ID of the refresh token they were issued
+-- with, so revoking the refresh token stops them too
+ALTER TABLE refresh_tokens
+ADD COLUMN session_id UUID NOT NULL UNIQUE DEFAULT gen_random_uuid();
+ALTER TABLE refresh_tokens ALTER COLUMN session_id DROP DEFAULT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE refresh_tokens
+DROP COLUMN session_id;
+-- +goose StatementEnd
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -20,6 +21,11 @@ type User struct {
 	Token        string    `json:"token,omitempty"`
 	RefreshToken string    `json:"refresh_token,omitempty"`
 	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	IsModerator  bool      `json:"is_moderator"`
+	// Whether chirps with content warnings are expanded or hidden
+	SensitiveContent string `json:"sensitive_content"`
+	// Who can start a direct conversation with the user
+	DMPrivacy string `json:"dm_privacy"`
 }
 
 type CreateUserReq struct {
@@ -33,14 +39,40 @@ type UpdateUserReq struct {
 
 func ToResponseUser(dbUser database.User) User {
 	return User{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+		ID:               dbUser.ID,
+		CreatedAt:        dbUser.CreatedAt,
+		UpdatedAt:        dbUser.UpdatedAt,
+		Email:            dbUser.Email,
+		IsChirpyRed:      dbUser.IsChirpyRed,
+		IsModerator:      dbUser.IsModerator,
+		SensitiveContent: dbUser.SensitiveContent,
+		DMPrivacy:        dbUser.DmPrivacy,
 	}
 }
 
+// validatePassword responds with the policy violations and returns false
+// when the password can't be used.
+func (cfg *apiConfig) validatePassword(res http.ResponseWriter, password, email string) bool {
+	err := cfg.passwordPolicy.Validate(password, email)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		fmt.Printf("Error validating password: %v\n", err)
+		respondWithError(res, http.StatusInternalServerError, "Error validating password")
+		return false
+	}
+
+	details := make([]ValidationError, len(policyErr.Violations))
+	for i, violation := range policyErr.Violations {
+		details[i] = ValidationError{Field: "password", Code: violation.Code, Message: violation.Message}
+	}
+	respondWithValidationError(res, "Invalid password", details)
+	return false
+}
+
 func createUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request) {
 	return func(res http.ResponseWriter, req *http.Request) {
 		defer req.Body.Close()
@@ -51,7 +83,11 @@ func createUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request)
 			return
 		}
 
-		pwd, err := auth.HashPassword(body.Password)
+		if !cfg.validatePassword(res, body.Password, body.Email) {
+			return
+		}
+
+		pwd, err := cfg.passwordHasher.Hash(body.Password)
 		if err != nil {
 			fmt.Printf("Error generating password hash: %v\n", err)
 			respondWithError(res, http.StatusBadRequest, "Error generating password hash")
@@ -77,8 +113,12 @@ func updateUserHandler(cfg *apiConfig) func(http.ResponseWriter, *http.Request)
 			return
 		}
 
+		if !cfg.validatePassword(res, body.Password, body.Email) {
+			return
+		}
+
 		userID := uuid.MustParse(req.Context().Value("user_id").(string))
-		pwd, err := auth.HashPassword(body.Password)
+		pwd, err := cfg.passwordHasher.Hash(body.Password)
 		if err != nil {
 			fmt.Printf("Error generating password hash: %v\n", err)
 			respondWithError(res, http.StatusBadRequest, "Error generating password hash")